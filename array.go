@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// bindValues dereferences pointer values, nil ones are bound as NULL, wraps
+// slice values, except []byte, with pq.Array so they are bound as arrays and
+// binds map[string]string values as hstore and network values as text.
+func bindValues(l []interface{}) []interface{} {
+	w := make([]interface{}, len(l))
+	for i, v := range l {
+		v = derefValue(v)
+		w[i] = v
+		if m, ok := v.(map[string]string); ok {
+			w[i] = Hstore(&m)
+		} else if s, ok := bindNetwork(v); ok {
+			w[i] = s
+		} else if isArray(reflect.TypeOf(v)) {
+			w[i] = pq.Array(v)
+		}
+	}
+	return w
+}
+
+// scanPointers wraps pointers to slices, except []byte, with pq.Array so arrays can be scanned into them,
+// pointers to pointers to slices with nullArray, pointers to map[string]string with Hstore
+// and pointers to network types with networkPointer.
+func scanPointers(l []interface{}) []interface{} {
+	w := make([]interface{}, len(l))
+	for i, p := range l {
+		w[i] = p
+		if m, ok := p.(*map[string]string); ok {
+			w[i] = Hstore(m)
+			continue
+		}
+		if n, ok := scanNetwork(p); ok {
+			w[i] = n
+			continue
+		}
+		t := reflect.TypeOf(p)
+		if t == nil || t.Kind() != reflect.Ptr {
+			continue
+		}
+		if isArray(t.Elem()) {
+			w[i] = pq.Array(p)
+		} else if t.Elem().Kind() == reflect.Ptr && isArray(t.Elem().Elem()) {
+			w[i] = nullArray{reflect.ValueOf(p).Elem()}
+		}
+	}
+	return w
+}
+
+func isArray(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
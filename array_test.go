@@ -0,0 +1,66 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type post struct {
+	ID     int64
+	Tags   []string
+	Scores *[]int64
+}
+
+var postStore = skyorm.NewStore("posts", 0, func() skyorm.Model {
+	return &post{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("tags", "[]string", false),
+	skyorm.NewProp("scores", "*[]int64", false),
+)
+
+func (m *post) OrmStore() skyorm.Store     { return postStore }
+func (m *post) OrmPk() interface{}         { return m.ID }
+func (m *post) OrmPkProp() skyorm.Prop     { return postStore.Pk() }
+func (m *post) OrmPkPointer() interface{}  { return &m.ID }
+func (m *post) OrmProps() []skyorm.Prop    { return postStore.Props() }
+func (m *post) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Tags, &m.Scores} }
+func (m *post) OrmVals() []interface{}     { return []interface{}{m.ID, m.Tags, m.Scores} }
+
+func TestArrays(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	tags := postStore.Props()[1]
+	mock.ExpectQuery(`^INSERT INTO posts \(id, tags, scores\) VALUES \(\$1, \$2, \$3\) RETURNING id$`).
+		WithArgs(int64(1), `{"a","b"}`, nil).WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(ctx, &post{ID: 1, Tags: []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, tags, scores FROM posts WHERE tags @> \$1$`).WithArgs(`{"a"}`).
+		WillReturnRows([]string{"id", "tags", "scores"}, []interface{}{1, "{a,b}", "{1,2}"}, []interface{}{2, "{}", nil})
+	l, err := p.Find(ctx, postStore, postgres.Contains(tags, []string{"a"}), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 {
+		t.Fatalf("found %d posts", len(l))
+	}
+	if m := l[0].(*post); !reflect.DeepEqual(m.Tags, []string{"a", "b"}) || m.Scores == nil || !reflect.DeepEqual(*m.Scores, []int64{1, 2}) {
+		t.Fatalf("scanned %+v", m)
+	}
+	if m := l[1].(*post); len(m.Tags) != 0 || m.Scores != nil {
+		t.Fatalf("scanned %+v", m)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
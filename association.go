@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Association is a many-to-many relation of two stores through a join table.
+type Association struct {
+	// Table is the join table.
+	Table  string
+	Store  skyorm.Store
+	Target skyorm.Store
+	// StoreKey is the join table column referencing Store pk.
+	StoreKey string
+	// TargetKey is the join table column referencing Target pk.
+	TargetKey string
+}
+
+// Associate links the model with pk to target models, existing links are kept.
+// Join table must have a unique constraint on both key columns.
+func (p *provider) Associate(ctx context.Context, a Association, pk interface{}, targetPks ...interface{}) error {
+	if len(targetPks) == 0 {
+		return nil
+	}
+	rows := make([]string, len(targetPks))
+	args := make([]interface{}, 0, len(targetPks)*2)
+	n := newN()
+	for i, t := range targetPks {
+		rows[i] = "(" + placeholder(n) + ", " + placeholder(n) + ")"
+		args = append(args, pk, t)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES %s ON CONFLICT DO NOTHING",
+		p.table(ctx, a.Table), quoteColumn(a.StoreKey), quoteColumn(a.TargetKey), strings.Join(rows, ", "))
+	ctx = withOp(ctx, OpAssociate)
+	_, err := p.exec(ctx, query, args...)
+	return err
+}
+
+// Dissociate unlinks the model with pk from target models, or from all of them
+// when no target pks are given.
+func (p *provider) Dissociate(ctx context.Context, a Association, pk interface{}, targetPks ...interface{}) error {
+	where := quoteColumn(a.StoreKey) + " = $1"
+	args := []interface{}{pk}
+	if len(targetPks) > 0 {
+		n := 2
+		in, v := parseCond(In(skyorm.NewProp(a.TargetKey, "", false), targetPks...), &n)
+		where += " AND " + in
+		args = append(args, v...)
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", p.table(ctx, a.Table), where)
+	ctx = withOp(ctx, OpDissociate)
+	_, err := p.exec(ctx, query, args...)
+	return err
+}
+
+// FindRelated returns target models linked to the model with pk.
+func (p *provider) FindRelated(ctx context.Context, a Association, pk interface{}, limit, offset int) ([]skyorm.Model, error) {
+	// tables are referenced by the aliases of tableAs.
+	target, join := quoteIdent(unqualified(a.Target.Name())), quoteIdent(unqualified(a.Table))
+	columns, computed := joinedColumns(a.Target, selectedProps(ctx, a.Target), target)
+	query := fmt.Sprintf("SELECT %s FROM %s%s INNER JOIN %s ON %s.%s = %s.%s WHERE %s.%s = $1",
+		columns,
+		p.tableAs(ctx, a.Target.Name()), computed,
+		p.tableAs(ctx, a.Table),
+		join, quoteColumn(a.TargetKey),
+		target, quoteColumn(a.Target.Pk().Name()),
+		join, quoteColumn(a.StoreKey),
+	)
+	query += p.limit(limit, offset)
+	ctx = withOp(ctx, OpFindRelated)
+	return p.findQuery(ctx, a.Target, query, pk)
+}
@@ -0,0 +1,33 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestFindRelated(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := postgres.Association{Table: "billing.user_grants", Store: userStore, Target: grantStore, StoreKey: "user_id", TargetKey: "grant_id"}
+	// schema-qualified tables are referenced by the aliases of their resolved tables.
+	mock.ExpectQuery(`^SELECT "order"\.id, "order"\."user" FROM Billing\.Order_2024 AS "order" `+
+		`INNER JOIN billing\.user_grants_2024 AS user_grants ON user_grants\.grant_id = "order"\.id `+
+		`WHERE user_grants\.user_id = \$1 LIMIT 10 OFFSET 0$`).
+		WithArgs(int64(1)).WillReturnRows([]string{"id", "user"}, []interface{}{int64(2), "a"})
+	ctx := postgres.WithTableResolver(context.Background(), postgres.TableSuffix("_2024"))
+	l, err := p.FindRelated(ctx, a, int64(1), 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0].(*grant).ID != 2 {
+		t.Fatalf("FindRelated() = %v, want grant 2", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
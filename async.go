@@ -0,0 +1,209 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// BufferPolicy defines what async Put does when the buffer is full.
+type BufferPolicy int
+
+const (
+	// BufferDrop drops models which don't fit into the buffer.
+	BufferDrop BufferPolicy = iota
+	// BufferBlock blocks Put until there is space in the buffer or context is done.
+	BufferBlock
+)
+
+// AsyncConfig is a configuration of asynchronous writes of non critical stores.
+type AsyncConfig struct {
+	// Stores are the stores which models are put asynchronously.
+	Stores []skyorm.Store
+	// Size is the maximum number of buffered models.
+	Size int
+	// BatchSize is the number of models flushed with one COPY.
+	BatchSize int
+	// FlushInterval is the maximum time models stay in the buffer.
+	FlushInterval time.Duration
+	Policy        BufferPolicy
+}
+
+// ErrClosed is returned by async Put after provider is closed.
+var ErrClosed = errors.New("postgres: provider is closed")
+
+// WithAsyncStores makes Put of models of the designated stores enqueue them into
+// a bounded in-memory buffer flushed by a background worker with COPY. Such Put
+// doesn't assign serial pks and write errors are only logged, so it suits telemetry
+// like metrics and page views. BeforeInsert hooks run on Put, AfterInsert hooks
+// run after models are flushed, with background context, and their errors are
+// only logged. Buffer is flushed on Close.
+func WithAsyncStores(cfg AsyncConfig) Option {
+	return func(o *options) {
+		o.async = &cfg
+	}
+}
+
+type asyncWriter struct {
+	p      *provider
+	cfg    AsyncConfig
+	stores map[string]bool
+	ch     chan skyorm.Model
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newAsyncWriter(p *provider, cfg AsyncConfig) *asyncWriter {
+	if cfg.Size <= 0 {
+		cfg.Size = 10000
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	w := &asyncWriter{
+		p:      p,
+		cfg:    cfg,
+		stores: make(map[string]bool, len(cfg.Stores)),
+		ch:     make(chan skyorm.Model, cfg.Size),
+	}
+	for _, s := range cfg.Stores {
+		w.stores[s.Name()] = true
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+func (w *asyncWriter) accepts(m skyorm.Model) bool {
+	return w.stores[m.OrmStore().Name()]
+}
+
+func (w *asyncWriter) enqueue(ctx context.Context, m skyorm.Model) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrClosed
+	}
+	if w.cfg.Policy == BufferBlock {
+		select {
+		case w.ch <- m:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case w.ch <- m:
+	default:
+		w.p.logf(LevelWarn, "ASYNC BUFFER FULL: dropped model of %s", m.OrmStore().Name())
+	}
+	return nil
+}
+
+func (w *asyncWriter) run() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]skyorm.Model, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case m, ok := <-w.ch:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, m)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// close stops accepting models and waits until buffered ones are flushed.
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.ch)
+	w.mu.Unlock()
+	w.wg.Wait()
+}
+
+func (w *asyncWriter) flush(batch []skyorm.Model) {
+	if len(batch) == 0 {
+		return
+	}
+	// models inserted without pks by their pk strategy are copied without pk column, so they are grouped separately.
+	groups := make(map[string][]skyorm.Model)
+	for _, m := range batch {
+		key := m.OrmStore().Name()
+		if pkOmitted(context.Background(), m) {
+			key += " (serial)"
+		}
+		groups[key] = append(groups[key], m)
+	}
+	for name, l := range groups {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := w.p.copyModels(ctx, l)
+		cancel()
+		if err != nil {
+			w.p.logf(LevelError, "ASYNC FLUSH ERROR: %d models of %s lost: %v", len(l), name, err)
+			continue
+		}
+		w.p.invalidateCache(l[0].OrmStore())
+		for _, m := range l {
+			if err = afterInsert(context.Background(), m); err != nil {
+				w.p.logf(LevelError, "ASYNC AFTER INSERT ERROR: model of %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// copyModels inserts models of the same store with COPY, skipping pks omitted by pk strategy.
+func (p *provider) copyModels(ctx context.Context, l []skyorm.Model) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	serial := pkOmitted(ctx, l[0])
+	props, _ := storedVals(l[0], serial)
+	columns := make([]string, len(props))
+	for i, prop := range props {
+		columns[i] = prop.Name()
+	}
+	stmt, err := tx.PrepareContext(ctx, copyIn(l[0].OrmStore().Name(), columns...))
+	if err != nil {
+		return err
+	}
+	for _, m := range l {
+		_, values := storedVals(m, serial)
+		if _, err = stmt.ExecContext(ctx, p.bindValues(values)...); err != nil {
+			return err
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+	if err = stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,251 @@
+package postgres
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// unnestArgs returns placeholders of arrays of values of the props of models,
+// cast to arrays of column types, and the arrays. Props of array types can't
+// be unnested, as unnest flattens multidimensional arrays.
+func (p *provider) unnestArgs(ctx context.Context, table string, props []skyorm.Prop, models []skyorm.Model, all []skyorm.Prop) ([]string, []interface{}, error) {
+	types, err := p.tableColumnTypes(ctx, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	index := make(map[string]int, len(all))
+	for i, prop := range all {
+		index[prop.Name()] = i
+	}
+	phs := make([]string, len(props))
+	args := make([]interface{}, len(props))
+	n := 1
+	for i, prop := range props {
+		typ, ok := types[prop.Name()]
+		if !ok {
+			return nil, nil, fmt.Errorf("column %s of %s not found", prop.Name(), table)
+		}
+		if strings.HasSuffix(typ, "]") {
+			return nil, nil, fmt.Errorf("column %s of %s is an array, which can't be unnested", prop.Name(), table)
+		}
+		values := make([]interface{}, len(models))
+		for j, m := range models {
+			values[j] = m.OrmVals()[index[prop.Name()]]
+		}
+		values = p.bindValues(values)
+		if typ == "bytea" {
+			// elements of arrays are quoted as they are, bytea ones have to be hex encoded.
+			for j, v := range values {
+				if b, ok := v.([]byte); ok {
+					values[j] = `\x` + hex.EncodeToString(b)
+				}
+			}
+		}
+		args[i] = bindColumn(prop, pq.Array(values))
+		phs[i] = placeholder(&n) + "::" + typ + "[]"
+	}
+	return phs, args, nil
+}
+
+// PutBatch inserts models of the same store with a single INSERT ... SELECT
+// FROM unnest statement, which has a parameter per prop regardless of number
+// of models. Pks omitted by pk strategy are allocated from the sequence of the
+// pk beforehand and mapped to models by ordinality, as rows returned by inserts
+// aren't ordered, they are reset when models aren't inserted. Models have to
+// omit their pks alike. Writes are never
+// buffered by WithAsyncStores.
+func (p *provider) PutBatch(ctx context.Context, models ...skyorm.Model) error {
+	if len(models) == 0 {
+		return nil
+	}
+	store := models[0].OrmStore()
+	serial := pkOmitted(ctx, models[0])
+	for _, m := range models {
+		if m.OrmStore().Name() != store.Name() {
+			return fmt.Errorf("batch of %s has model of %s", store.Name(), m.OrmStore().Name())
+		}
+		if err := checkWritable(m.OrmStore()); err != nil {
+			return err
+		}
+		if err := beforeInsert(ctx, m); err != nil {
+			return err
+		}
+		if err := checkEnumModel(m); err != nil {
+			return err
+		}
+		if pkOmitted(ctx, m) != serial {
+			return fmt.Errorf("batch of %s mixes models with and without pks", store.Name())
+		}
+	}
+	if serial && !dryRun(ctx) {
+		// retries of failed inserts would take allocated pks for assigned ones.
+		saved := savePks(models)
+		if err := p.allocatePks(ctx, store, models); err != nil {
+			restorePks(models, saved)
+			return err
+		}
+		if err := p.insertBatch(ctx, store, models); err != nil {
+			restorePks(models, saved)
+			return err
+		}
+	} else if err := p.insertBatch(ctx, store, models); err != nil {
+		return err
+	}
+	if dryRun(ctx) {
+		return nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	for _, m := range models {
+		identify(ctx, m)
+		if err := afterInsert(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertBatch inserts models of the store with INSERT ... SELECT FROM unnest statement.
+func (p *provider) insertBatch(ctx context.Context, store skyorm.Store, models []skyorm.Model) error {
+	props := storedProps(store, store.Props())
+	table := p.table(ctx, store.Name())
+	phs, args, err := p.unnestArgs(ctx, table, props, models, store.Props())
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s)%s SELECT * FROM unnest(%s)",
+		table, buildQueryProperties(props, false), pkOverriding(models[0], false), strings.Join(phs, ", "))
+	_, err = p.exec(withOp(ctx, OpPut), query, args...)
+	return err
+}
+
+// savePks returns copies of pks of models.
+func savePks(models []skyorm.Model) []reflect.Value {
+	l := make([]reflect.Value, len(models))
+	for i, m := range models {
+		pk := reflect.ValueOf(m.OrmPkPointer()).Elem()
+		l[i] = reflect.New(pk.Type()).Elem()
+		l[i].Set(pk)
+	}
+	return l
+}
+
+// restorePks sets pks of models to the saved ones.
+func restorePks(models []skyorm.Model, saved []reflect.Value) {
+	for i, m := range models {
+		reflect.ValueOf(m.OrmPkPointer()).Elem().Set(saved[i])
+	}
+}
+
+// allocatePks sets pks of models to next values of the sequence of pk of the
+// store, the value of nth row of the series is the pk of nth model.
+func (p *provider) allocatePks(ctx context.Context, store skyorm.Store, models []skyorm.Model) error {
+	seq, err := p.sequence(ctx, store)
+	if err != nil {
+		return err
+	}
+	res, err := p.query(ctx, "SELECT g.n, nextval($1::regclass) FROM generate_series(1, $2) WITH ORDINALITY AS g (v, n) ORDER BY g.n",
+		seq, len(models))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	i := 0
+	for ; res.Next(); i++ {
+		var n int
+		if i == len(models) {
+			return fmt.Errorf("sequence %s returned more than %d values", seq, len(models))
+		}
+		if err = res.Scan(p.scanPointers([]interface{}{&n, models[i].OrmPkPointer()})...); err != nil {
+			return err
+		}
+		if n != i+1 {
+			return fmt.Errorf("sequence %s returned value %d as %d", seq, n, i+1)
+		}
+	}
+	if err = res.Err(); err != nil {
+		return err
+	}
+	if i != len(models) {
+		return fmt.Errorf("sequence %s returned %d of %d values", seq, i, len(models))
+	}
+	return nil
+}
+
+// UpdateBatch sets the props of rows of the store to values of models with
+// the same pks with a single UPDATE ... FROM unnest statement, and returns
+// number of updated rows. Models of stores with update hooks are updated by
+// UpdateMany instead, so values the hooks return are applied.
+func (p *provider) UpdateBatch(ctx context.Context, store skyorm.Store, props []skyorm.Prop, models ...skyorm.Model) (int64, error) {
+	if err := checkWritable(store); err != nil {
+		return 0, err
+	}
+	if err := checkStored(store, props...); err != nil {
+		return 0, err
+	}
+	if len(models) == 0 || len(props) == 0 {
+		return 0, nil
+	}
+	for _, m := range models {
+		if err := checkEnumModel(m); err != nil {
+			return 0, err
+		}
+	}
+	if h := storeHooks(store); h.BeforeUpdate != nil || h.AfterUpdate != nil {
+		return p.updateMany(ctx, store, modelValueSets(props, models))
+	}
+	pk := quoteColumn(store.Pk().Name())
+	columns := append([]skyorm.Prop{store.Pk()}, props...)
+	table := p.table(ctx, store.Name())
+	phs, args, err := p.unnestArgs(ctx, table, columns, models, store.Props())
+	if err != nil {
+		return 0, err
+	}
+	sets := make([]string, len(props))
+	for i, prop := range props {
+		sets[i] = quoteColumn(prop.Name()) + " = u." + quoteColumn(prop.Name())
+	}
+	query := fmt.Sprintf("UPDATE %s AS t SET %s FROM unnest(%s) AS u (%s) WHERE t.%s = u.%s",
+		table, strings.Join(sets, ", "), strings.Join(phs, ", "), buildQueryProperties(columns, false), pk, pk)
+	res, err := p.exec(withOp(ctx, OpUpdateMany), query, args...)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun(ctx) {
+		return 0, nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	return res.RowsAffected()
+}
+
+// modelValueSets returns sets of values of the props of models by their pks.
+func modelValueSets(props []skyorm.Prop, models []skyorm.Model) []PkValueSet {
+	l := make([]PkValueSet, len(models))
+	for i, m := range models {
+		l[i] = PkValueSet{Pk: m.OrmPk(), Values: modelVals(m, props)}
+	}
+	return l
+}
+
+// modelVals returns values of the props of the model.
+func modelVals(m skyorm.Model, props []skyorm.Prop) []skyorm.Val {
+	index := make(map[string]int, len(m.OrmProps()))
+	for i, prop := range m.OrmProps() {
+		index[prop.Name()] = i
+	}
+	vals := m.OrmVals()
+	l := make([]skyorm.Val, len(props))
+	for i, prop := range props {
+		l[i] = skyorm.NewVal(prop, vals[index[prop.Name()]])
+	}
+	return l
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestPutBatchAllocatesPks(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT pg_get_serial_sequence\(\$1, \$2\)$`).WithArgs("users", "id").
+		WillReturnRows([]string{"pg_get_serial_sequence"}, []interface{}{"users_id_seq"})
+	mock.ExpectQuery(`^SELECT g\.n, nextval\(\$1::regclass\) FROM generate_series\(1, \$2\) WITH ORDINALITY`).
+		WithArgs("users_id_seq", 2).
+		WillReturnRows([]string{"n", "nextval"}, []interface{}{1, 7}, []interface{}{2, 8})
+	mock.ExpectQuery(`FROM pg_attribute`).
+		WillReturnRows([]string{"attname", "format_type"}, []interface{}{"id", "bigint"}, []interface{}{"name", "text"})
+	mock.ExpectExec(`^INSERT INTO users \(id, name\) SELECT \* FROM unnest\(\$1::bigint\[\], \$2::text\[\]\)$`).
+		WillReturnResult(2)
+	a, b := &user{Name: "a"}, &user{Name: "b"}
+	if err = p.PutBatch(context.Background(), a, b); err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 7 || b.ID != 8 {
+		t.Fatalf("pks %d, %d, want 7, 8", a.ID, b.ID)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPutBatchResetsPksOnError(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT pg_get_serial_sequence\(\$1, \$2\)$`).WithArgs("users", "id").
+		WillReturnRows([]string{"pg_get_serial_sequence"}, []interface{}{"users_id_seq"})
+	mock.ExpectQuery(`^SELECT g\.n, nextval\(\$1::regclass\)`).
+		WillReturnRows([]string{"n", "nextval"}, []interface{}{1, 7}, []interface{}{2, 8})
+	mock.ExpectQuery(`FROM pg_attribute`).
+		WillReturnRows([]string{"attname", "format_type"}, []interface{}{"id", "bigint"}, []interface{}{"name", "text"})
+	mock.ExpectExec(`^INSERT INTO users`).WillReturnError(errors.New("unique violation"))
+	a, b := &user{Name: "a"}, &user{Name: "b"}
+	if err = p.PutBatch(context.Background(), a, b); err == nil {
+		t.Fatal("expected error")
+	}
+	// models keep no pks, so a retry allocates them again rather than inserting them as assigned.
+	if a.ID != 0 || b.ID != 0 {
+		t.Fatalf("pks %d, %d after failed insert", a.ID, b.ID)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPutBatchRejectsMixedModels(t *testing.T) {
+	p, _, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, models := range [][]skyorm.Model{
+		{&user{Name: "a"}, &event{Kind: "b"}},
+		{&user{Name: "a"}, &user{ID: 2, Name: "b"}},
+	} {
+		if err = p.PutBatch(context.Background(), models...); err == nil {
+			t.Fatalf("put batch of %T, %T succeeded", models[0], models[1])
+		}
+	}
+}
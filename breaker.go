@@ -0,0 +1,161 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without querying the database while circuit breaker is open.
+var ErrCircuitOpen = errors.New("postgres: circuit breaker is open")
+
+// BreakerConfig configures circuit breaker on database queries.
+type BreakerConfig struct {
+	// Window is the period over which failure rates are measured, 10s by default.
+	Window time.Duration
+	// MinQueries is the number of queries in a window required to trip, 20 by default.
+	MinQueries int
+	// ErrorRate trips the breaker when that fraction of queries in a window fails,
+	// 0.5 by default.
+	ErrorRate float64
+	// SlowThreshold makes queries taking longer slow, zero disables latency tracking.
+	SlowThreshold time.Duration
+	// SlowRate trips the breaker when that fraction of queries in a window is slow.
+	SlowRate float64
+	// OpenTimeout is the time breaker stays open before letting probe queries
+	// through, 5s by default.
+	OpenTimeout time.Duration
+	// Probes is the number of successful probe queries closing the breaker, 3 by default.
+	Probes int
+	// Failure reports whether query error counts as failure, by default transient
+	// errors and timeouts do, while e.g. constraint violations don't.
+	Failure func(err error) bool
+}
+
+// WithCircuitBreaker makes provider fast-fail queries with ErrCircuitOpen after
+// error rate or latency of queries exceeded thresholds of cfg, protecting both
+// the application and the database during incidents.
+func WithCircuitBreaker(cfg BreakerConfig) Option {
+	return func(o *options) {
+		if cfg.Window <= 0 {
+			cfg.Window = 10 * time.Second
+		}
+		if cfg.MinQueries <= 0 {
+			cfg.MinQueries = 20
+		}
+		if cfg.ErrorRate <= 0 {
+			cfg.ErrorRate = 0.5
+		}
+		if cfg.OpenTimeout <= 0 {
+			cfg.OpenTimeout = 5 * time.Second
+		}
+		if cfg.Probes <= 0 {
+			cfg.Probes = 3
+		}
+		if cfg.Failure == nil {
+			cfg.Failure = func(err error) bool {
+				return IsTransient(err) || errors.Is(err, context.DeadlineExceeded)
+			}
+		}
+		o.breaker = &cfg
+	}
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	cfg BreakerConfig
+
+	mu          sync.Mutex
+	state       breakerState
+	windowStart time.Time
+	queries     int
+	failures    int
+	slow        int
+	openedAt    time.Time
+	probes      int
+	successes   int
+}
+
+// allow reports whether a query may run.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return ErrCircuitOpen
+		}
+		b.state, b.probes, b.successes = breakerHalfOpen, 0, 0
+		fallthrough
+	case breakerHalfOpen:
+		if b.probes >= b.cfg.Probes {
+			return ErrCircuitOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// record accounts query result and reports whether the breaker changed state.
+func (b *breaker) record(d time.Duration, err error) (breakerState, bool) {
+	failed := err != nil && b.cfg.Failure(err)
+	slow := b.cfg.SlowThreshold > 0 && d >= b.cfg.SlowThreshold
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case breakerHalfOpen:
+		if failed || slow {
+			b.state, b.openedAt = breakerOpen, now
+			return b.state, true
+		}
+		if b.successes++; b.successes >= b.cfg.Probes {
+			b.state, b.windowStart, b.queries, b.failures, b.slow = breakerClosed, now, 0, 0, 0
+			return b.state, true
+		}
+	case breakerClosed:
+		if now.Sub(b.windowStart) >= b.cfg.Window {
+			b.windowStart, b.queries, b.failures, b.slow = now, 0, 0, 0
+		}
+		b.queries++
+		if failed {
+			b.failures++
+		}
+		if slow {
+			b.slow++
+		}
+		if b.queries < b.cfg.MinQueries {
+			return b.state, false
+		}
+		q := float64(b.queries)
+		if float64(b.failures)/q >= b.cfg.ErrorRate || b.cfg.SlowRate > 0 && float64(b.slow)/q >= b.cfg.SlowRate {
+			b.state, b.openedAt = breakerOpen, now
+			return b.state, true
+		}
+	}
+	return b.state, false
+}
+
+// recordBreaker accounts query result in circuit breaker of provider.
+func (p *provider) recordBreaker(d time.Duration, err error) {
+	if p.breaker == nil {
+		return
+	}
+	state, changed := p.breaker.record(d, err)
+	if !changed {
+		return
+	}
+	if state == breakerOpen {
+		p.logf(LevelError, "CIRCUIT BREAKER OPEN: %v", err)
+	} else {
+		p.logf(LevelInfo, "CIRCUIT BREAKER CLOSED")
+	}
+}
@@ -0,0 +1,29 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestBreakerStopsRetries(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(
+		postgres.WithCircuitBreaker(postgres.BreakerConfig{MinQueries: 1, Failure: func(err error) bool { return true }}),
+		postgres.WithRetry(postgres.RetryPolicy{BaseBackoff: time.Millisecond, Retryable: func(err error) bool { return true }}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, name FROM users`).WillReturnError(errors.New("reset"))
+	// the failure opens the breaker, so the query isn't retried.
+	if _, err = p.Find(context.Background(), userStore, nil, 0, 0); !errors.Is(err, postgres.ErrCircuitOpen) {
+		t.Fatalf("error %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget limits queries issued by the provider within a context.
+type Budget struct {
+	// MaxQueries is the maximum number of queries, zero means no limit.
+	MaxQueries int
+	// MaxDuration is the maximum cumulative database time, zero means no limit.
+	MaxDuration time.Duration
+	// WarnOnly makes provider log exceeded budget instead of failing queries.
+	WarnOnly bool
+}
+
+// BudgetError is returned when query budget of the context is exceeded.
+type BudgetError struct {
+	Budget   Budget
+	Queries  int
+	Duration time.Duration
+	// CallSites are the call sites of queries issued within the context.
+	CallSites []string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("query budget exceeded: %d queries, %s of database time (budget %d queries, %s)",
+		e.Queries, e.Duration, e.Budget.MaxQueries, e.Budget.MaxDuration)
+}
+
+// maxCallSites limits call sites kept by a budget.
+const maxCallSites = 32
+
+type budgetKey struct{}
+
+type budgetState struct {
+	mu        sync.Mutex
+	budget    Budget
+	queries   int
+	duration  time.Duration
+	callSites []string
+	warned    bool
+}
+
+// WithBudget returns context which queries are tracked against budget b.
+func WithBudget(ctx context.Context, b Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &budgetState{budget: b})
+}
+
+// checkBudget counts the query and fails it when budget of ctx is exceeded.
+func (p *provider) checkBudget(ctx context.Context) error {
+	st, ok := ctx.Value(budgetKey{}).(*budgetState)
+	if !ok {
+		return nil
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.queries++
+	if len(st.callSites) < maxCallSites {
+		st.callSites = append(st.callSites, callSite())
+	}
+	b := st.budget
+	if (b.MaxQueries == 0 || st.queries <= b.MaxQueries) && (b.MaxDuration == 0 || st.duration <= b.MaxDuration) {
+		return nil
+	}
+	err := &BudgetError{
+		Budget:    b,
+		Queries:   st.queries,
+		Duration:  st.duration,
+		CallSites: append([]string(nil), st.callSites...),
+	}
+	if !b.WarnOnly {
+		return err
+	}
+	if !st.warned {
+		st.warned = true
+		p.logf(LevelWarn, "QUERY BUDGET: %v, call sites:\n\t%s", err, strings.Join(err.CallSites, "\n\t"))
+	}
+	return nil
+}
+
+func spendBudget(ctx context.Context, d time.Duration) {
+	st, ok := ctx.Value(budgetKey{}).(*budgetState)
+	if !ok {
+		return
+	}
+	st.mu.Lock()
+	st.duration += d
+	st.mu.Unlock()
+}
+
+const packagePath = "github.com/skyorm/postgres."
+
+// callSite returns file:line of the first caller outside of this package.
+func callSite() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, packagePath) {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
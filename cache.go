@@ -0,0 +1,185 @@
+package postgres
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Cache is a backend of query result cache. Cached values are shared by readers
+// and must not be modified.
+type Cache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{})
+}
+
+// NewLRUCache returns in-memory cache holding size most recently used results.
+func NewLRUCache(size int) Cache {
+	return &lruCache{size: size, l: list.New(), m: make(map[string]*list.Element)}
+}
+
+type lruCache struct {
+	mu   sync.Mutex
+	size int
+	l    *list.List
+	m    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+}
+
+func (c *lruCache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.m[key]
+	if !ok {
+		return nil, false
+	}
+	c.l.MoveToFront(e)
+	return e.Value.(*lruEntry).value, true
+}
+
+func (c *lruCache) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.m[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.l.MoveToFront(e)
+		return
+	}
+	c.m[key] = c.l.PushFront(&lruEntry{key, value})
+	for c.l.Len() > c.size {
+		e := c.l.Back()
+		c.l.Remove(e)
+		delete(c.m, e.Value.(*lruEntry).key)
+	}
+}
+
+// WithCache makes Find, FindOne and Count of the stores, or of every store when
+// none are given, read through cache c. Results are keyed by query and arguments
+// and invalidated for a store by its Put, Update, UpdateMany and Delete, also the
+// ones of committed transactions, and by writes to other stores read by their
+// conditions, e.g. by Exists or InSubquery. Reads with CTEs bypass cache. Writes bypassing provider, e.g. Exec or writes
+// of other processes, are not seen, so c should expire entries when they matter.
+// Reads in transactions and with session variables bypass cache.
+func WithCache(c Cache, stores ...skyorm.Store) Option {
+	return func(o *options) {
+		o.cache = &resultCache{backend: c, gens: make(map[string]uint64)}
+		if len(stores) > 0 {
+			o.cache.stores = make(map[string]bool, len(stores))
+			for _, s := range stores {
+				o.cache.stores[s.Name()] = true
+			}
+		}
+	}
+}
+
+type noCacheKey struct{}
+
+// NoCache returns context which reads bypass result cache.
+func NoCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// resultCache invalidates cached results of a store by advancing its generation,
+// which is a part of keys, so stale results are never read and age out of backend.
+type resultCache struct {
+	backend Cache
+	stores  map[string]bool
+	mu      sync.Mutex
+	gens    map[string]uint64
+}
+
+// key returns key of query results of the stores, which generations are a part of it.
+func (c *resultCache) key(stores []string, query string, args []interface{}) string {
+	var b strings.Builder
+	c.mu.Lock()
+	for _, store := range stores {
+		b.WriteString(store + "\x00" + strconv.FormatUint(c.gens[store], 10) + "\x00")
+	}
+	c.mu.Unlock()
+	b.WriteString(strings.Join(strings.Fields(query), " "))
+	for _, arg := range args {
+		arg = derefValue(argValue(arg))
+		if v, ok := arg.(driver.Valuer); ok {
+			arg, _ = v.Value()
+		}
+		_, _ = fmt.Fprintf(&b, "\x00%T:%v", arg, arg)
+	}
+	return b.String()
+}
+
+func (c *resultCache) invalidate(store string) {
+	c.mu.Lock()
+	c.gens[store]++
+	c.mu.Unlock()
+}
+
+// readThrough returns cached result of query of the store with condition, or
+// loads and caches it. Results are keyed by generations of the store and of
+// other stores read by the condition, e.g. by Exists, so writes to any of them
+// invalidate the result. Queries with CTEs bypass cache, as their tables are unknown.
+func (p *provider) readThrough(ctx context.Context, store skyorm.Store, condition skyorm.Cond, query string, args []interface{},
+	load func() (interface{}, error)) (interface{}, error) {
+	c := p.cache
+	if c == nil || c.stores != nil && !c.stores[store.Name()] || p.conn != conn(p.db) ||
+		len(SessionVars(ctx)) > 0 || ctx.Value(noCacheKey{}) != nil || len(contextCTEs(ctx)) > 0 {
+		return load()
+	}
+	key := c.key(append([]string{store.Name()}, condStores(condition)...), query, args)
+	if v, ok := c.backend.Get(key); ok {
+		return copyResult(v), nil
+	}
+	v, err := load()
+	if err != nil {
+		return nil, err
+	}
+	c.backend.Set(key, copyResult(v))
+	return v, nil
+}
+
+// invalidateCache invalidates cached results of the store, again on commit of
+// transaction, so results read meanwhile are not kept.
+func (p *provider) invalidateCache(store skyorm.Store) {
+	if p.cache == nil {
+		return
+	}
+	p.cache.invalidate(store.Name())
+	if p.txWrites != nil {
+		*p.txWrites = append(*p.txWrites, store.Name())
+	}
+}
+
+// copyResult returns copy of cached result, models are copied shallowly.
+func copyResult(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []skyorm.Model:
+		l := make([]skyorm.Model, len(v))
+		for i, m := range v {
+			l[i] = copyModel(m)
+		}
+		return l
+	case skyorm.Model:
+		return copyModel(v)
+	}
+	return v
+}
+
+func copyModel(m skyorm.Model) skyorm.Model {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return m
+	}
+	cp := reflect.New(v.Elem().Type())
+	cp.Elem().Set(v.Elem())
+	return cp.Interface().(skyorm.Model)
+}
@@ -0,0 +1,140 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+const findUsers = `^SELECT id, name FROM users WHERE name = \$1$`
+
+// expectReads runs find, expecting it to query the database when queried is set
+// and to be served from cache otherwise.
+func expectReads(t *testing.T, mock *postgrestest.Mock, queried bool, query string, find func() error) {
+	t.Helper()
+	if queried {
+		mock.ExpectQuery(query).WillReturnRows([]string{"id", "name"}, []interface{}{int64(1), "a"})
+	}
+	if err := find(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithCache(postgres.NewLRUCache(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	name := userStore.Props()[1]
+	find := func() error {
+		l, err := p.Find(ctx, userStore, skyorm.Eq(name, "a"), 0, 0)
+		if err == nil && len(l) != 1 {
+			t.Fatalf("found %d users, want 1", len(l))
+		}
+		return err
+	}
+	expectReads(t, mock, true, findUsers, find)
+	expectReads(t, mock, false, findUsers, find)
+
+	mock.ExpectExec(`^UPDATE users SET name = \$1 WHERE name = \$2$`).WillReturnResult(0)
+	if err = p.Update(ctx, userStore, skyorm.Eq(name, "a"), skyorm.NewVal(name, "b")); err != nil {
+		t.Fatal(err)
+	}
+	expectReads(t, mock, true, findUsers, find)
+	expectReads(t, mock, false, findUsers, find)
+
+	// writes of a transaction invalidate results again on commit.
+	tx, err := p.Begin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^DELETE FROM users WHERE id = \$1$`).WillReturnResult(0)
+	if err = tx.Delete(ctx, userStore, skyorm.Eq(userStore.Pk(), 2)); err != nil {
+		t.Fatal(err)
+	}
+	expectReads(t, mock, true, findUsers, find)
+	expectReads(t, mock, false, findUsers, find)
+	// reads of the transaction bypass cache.
+	expectReads(t, mock, true, findUsers, func() error {
+		_, err := tx.Find(ctx, userStore, skyorm.Eq(name, "a"), 0, 0)
+		return err
+	})
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	expectReads(t, mock, true, findUsers, find)
+	expectReads(t, mock, false, findUsers, find)
+}
+
+func TestCacheOfSubqueries(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithCache(postgres.NewLRUCache(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	orders := postgres.Relation{Name: "orders", Kind: postgres.HasMany, Store: userStore, Target: orderStore, Prop: orderStore.Props()[1]}
+	query := `^SELECT COUNT\(id\) AS cnt FROM users WHERE EXISTS \(SELECT 1 FROM orders AS orm_sub`
+	count := func() error {
+		_, err := p.Count(ctx, userStore, postgres.Exists(orders, nil))
+		return err
+	}
+	expectCount := func(queried bool) {
+		t.Helper()
+		if queried {
+			mock.ExpectQuery(query).WillReturnRows([]string{"cnt"}, []interface{}{int64(1)})
+		}
+		if err := count(); err != nil {
+			t.Fatal(err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectCount(true)
+	expectCount(false)
+	// writes to the store of the subquery invalidate the count of users.
+	mock.ExpectExec(`^DELETE FROM orders WHERE id = \$1$`).WillReturnResult(1)
+	if err = p.Delete(ctx, orderStore, skyorm.Eq(orderStore.Pk(), 1)); err != nil {
+		t.Fatal(err)
+	}
+	expectCount(true)
+	expectCount(false)
+}
+
+func TestCacheBypass(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithCache(postgres.NewLRUCache(10), orderStore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := userStore.Props()[1]
+	findIn := func(ctx context.Context) func() error {
+		return func() error {
+			_, err := p.Find(ctx, userStore, skyorm.Eq(name, "a"), 0, 0)
+			return err
+		}
+	}
+	// users aren't cached by the cache of orders.
+	expectReads(t, mock, true, findUsers, findIn(context.Background()))
+	expectReads(t, mock, true, findUsers, findIn(context.Background()))
+
+	p, mock, err = postgrestest.NewMock(postgres.WithCache(postgres.NewLRUCache(10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	expectReads(t, mock, true, findUsers, findIn(ctx))
+	expectReads(t, mock, true, findUsers, findIn(postgres.NoCache(ctx)))
+	// tables of CTEs aren't known, so their writes couldn't invalidate results.
+	cte := postgres.WithCTE(ctx, postgres.CTE{Name: "recent", Query: "SELECT id FROM orders"})
+	expectReads(t, mock, true, `^WITH recent AS`, findIn(cte))
+	expectReads(t, mock, true, `^WITH recent AS`, findIn(cte))
+	expectReads(t, mock, false, findUsers, findIn(ctx))
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+)
+
+// Capabilities describes server version and features available to provider.
+type Capabilities struct {
+	// Version is the server version string, e.g. "15.4".
+	Version string
+	// VersionNum is server_version_num, e.g. 150004.
+	VersionNum int
+	// Extensions maps names of installed extensions to their versions.
+	Extensions map[string]string
+	// Merge reports MERGE statement support, PostgreSQL 15+.
+	Merge bool
+	// IdentityColumns reports GENERATED AS IDENTITY support, PostgreSQL 10+.
+	IdentityColumns bool
+	// NullsNotDistinct reports UNIQUE NULLS NOT DISTINCT support, PostgreSQL 15+.
+	NullsNotDistinct bool
+}
+
+// Known extension names of Capabilities.Extensions.
+const (
+	ExtPgcrypto    = "pgcrypto"
+	ExtPgTrgm      = "pg_trgm"
+	ExtPgvector    = "vector"
+	ExtPostGIS     = "postgis"
+	ExtTimescaleDB = "timescaledb"
+)
+
+// HasExtension reports whether the extension is installed.
+func (c *Capabilities) HasExtension(name string) bool {
+	_, ok := c.Extensions[name]
+	return ok
+}
+
+// Capabilities queries server version and installed extensions, so features can
+// degrade gracefully instead of failing at query time. The result isn't cached.
+func (p *provider) Capabilities(ctx context.Context) (*Capabilities, error) {
+	c := &Capabilities{Extensions: make(map[string]string)}
+	err := p.queryRow(ctx, "SELECT current_setting('server_version'), current_setting('server_version_num')::int", nil,
+		&c.Version, &c.VersionNum)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.query(ctx, "SELECT extname, extversion FROM pg_extension")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	for res.Next() {
+		var name, version string
+		if err = res.Scan(&name, &version); err != nil {
+			return nil, err
+		}
+		c.Extensions[name] = version
+	}
+	if err = res.Err(); err != nil {
+		return nil, err
+	}
+	c.Merge = c.VersionNum >= 150000
+	c.IdentityColumns = c.VersionNum >= 100000
+	c.NullsNotDistinct = c.VersionNum >= 150000
+	return c, nil
+}
+
+// serverVersion caches server_version_num of the database of provider.
+type serverVersion struct {
+	mu  sync.Mutex
+	num int
+}
+
+// serverVersionNum returns server_version_num, queried once per provider.
+func (p *provider) serverVersionNum(ctx context.Context) (int, error) {
+	p.version.mu.Lock()
+	defer p.version.mu.Unlock()
+	// version isn't known in dry-run mode, which captures the query, so zero isn't cached.
+	if p.version.num > 0 {
+		return p.version.num, nil
+	}
+	var num int
+	if err := p.queryRow(ctx, "SELECT current_setting('server_version_num')::int", nil, &num); err != nil {
+		return 0, err
+	}
+	p.version.num = num
+	return num, nil
+}
@@ -0,0 +1,48 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestCapabilities(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, tt := range []struct {
+		version    string
+		num        int
+		extensions [][]interface{}
+		merge      bool
+		identity   bool
+	}{
+		{"15.4", 150004, [][]interface{}{{"plpgsql", "1.0"}, {"vector", "0.5.1"}}, true, true},
+		{"9.6.24", 90624, nil, false, false},
+	} {
+		mock.ExpectQuery(`^SELECT current_setting\('server_version'\), current_setting\('server_version_num'\)::int$`).
+			WillReturnRows([]string{"server_version", "server_version_num"}, []interface{}{tt.version, tt.num})
+		mock.ExpectQuery(`^SELECT extname, extversion FROM pg_extension$`).
+			WillReturnRows([]string{"extname", "extversion"}, tt.extensions...)
+		c, err := p.Capabilities(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Version != tt.version || c.VersionNum != tt.num {
+			t.Errorf("version %s (%d), want %s (%d)", c.Version, c.VersionNum, tt.version, tt.num)
+		}
+		if c.Merge != tt.merge || c.NullsNotDistinct != tt.merge || c.IdentityColumns != tt.identity {
+			t.Errorf("%s: merge %t, nulls not distinct %t, identity columns %t", tt.version, c.Merge, c.NullsNotDistinct, c.IdentityColumns)
+		}
+		if c.HasExtension(postgres.ExtPgvector) != (len(tt.extensions) > 0) || c.HasExtension(postgres.ExtPostGIS) {
+			t.Errorf("%s: extensions %v", tt.version, c.Extensions)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
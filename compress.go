@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sync"
+)
+
+// Codec compresses values of compressed properties.
+type Codec interface {
+	// ID identifies codec in the stored values, it must be unique among registered codecs.
+	ID() byte
+	Compress(b []byte) ([]byte, error)
+	Decompress(b []byte) ([]byte, error)
+}
+
+// compressMagic prefixes compressed values and is followed by codec id.
+var compressMagic = []byte("\x00skz")
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[byte]Codec{GzipCodec.ID(): GzipCodec}
+)
+
+// RegisterCodec registers codec, so values can be compressed by it and values
+// compressed by it can be decompressed, e.g. a codec of a third-party library.
+// It fails when codec is nil or another codec has its id.
+func RegisterCodec(c Codec) error {
+	if c == nil {
+		return errors.New("nil compression codec")
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	// codecs may not be comparable, so the ones of the same type are the same.
+	if registered, ok := codecs[c.ID()]; ok && reflect.TypeOf(registered) != reflect.TypeOf(c) {
+		return fmt.Errorf("compression codec %d is already registered", c.ID())
+	}
+	codecs[c.ID()] = c
+	return nil
+}
+
+// checkCodec returns error unless codec is registered, so values it compresses
+// can be read back.
+func checkCodec(c Codec) error {
+	if c == nil {
+		return errors.New("nil compression codec")
+	}
+	codecsMu.RLock()
+	registered, ok := codecs[c.ID()]
+	codecsMu.RUnlock()
+	if !ok || reflect.TypeOf(registered) != reflect.TypeOf(c) {
+		return fmt.Errorf("compression codec %d isn't registered", c.ID())
+	}
+	return nil
+}
+
+// GzipCodec is the codec based on compress/gzip.
+var GzipCodec Codec = gzipCodec{}
+
+type gzipCodec struct{}
+
+func (gzipCodec) ID() byte {
+	return 1
+}
+
+func (gzipCodec) Compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+	return ioutil.ReadAll(r)
+}
+
+// Compressed wraps a pointer to string or []byte property stored compressed in bytea column.
+// Values shorter than minSize are stored as is, values without compression prefix are
+// read as is, so existing uncompressed data stays readable. Codec must be GzipCodec or
+// registered with RegisterCodec, values fail to bind otherwise.
+func Compressed(v interface{}, codec Codec, minSize int) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	return &compressed{v, codec, minSize}
+}
+
+type compressed struct {
+	v       interface{}
+	codec   Codec
+	minSize int
+}
+
+func (c *compressed) Value() (driver.Value, error) {
+	var b []byte
+	switch v := c.v.(type) {
+	case *string:
+		b = []byte(*v)
+	case *[]byte:
+		if *v == nil {
+			return nil, nil
+		}
+		b = *v
+	default:
+		return nil, fmt.Errorf("can't compress %T", c.v)
+	}
+	if len(b) < c.minSize {
+		return b, nil
+	}
+	if err := checkCodec(c.codec); err != nil {
+		return nil, err
+	}
+	cb, err := c.codec.Compress(b)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(compressMagic)+1+len(cb))
+	out = append(out, compressMagic...)
+	out = append(out, c.codec.ID())
+	return append(out, cb...), nil
+}
+
+func (c *compressed) Scan(src interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case nil:
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("can't decompress %T", src)
+	}
+	if len(b) > len(compressMagic) && bytes.HasPrefix(b, compressMagic) {
+		codecsMu.RLock()
+		codec, ok := codecs[b[len(compressMagic)]]
+		codecsMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown compression codec %d", b[len(compressMagic)])
+		}
+		var err error
+		if b, err = codec.Decompress(b[len(compressMagic)+1:]); err != nil {
+			return err
+		}
+	}
+	switch v := c.v.(type) {
+	case *string:
+		*v = string(b)
+	case *[]byte:
+		*v = append([]byte(nil), b...)
+	default:
+		return fmt.Errorf("can't decompress into %T", c.v)
+	}
+	return nil
+}
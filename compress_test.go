@@ -0,0 +1,49 @@
+package postgres_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/skyorm/postgres"
+)
+
+type rawCodec struct {
+	id byte
+}
+
+func (c rawCodec) ID() byte                          { return c.id }
+func (rawCodec) Compress(b []byte) ([]byte, error)   { return b, nil }
+func (rawCodec) Decompress(b []byte) ([]byte, error) { return b, nil }
+
+func TestCompressedCodecs(t *testing.T) {
+	s := "aaaa"
+	if _, err := postgres.Compressed(&s, nil, 0).Value(); err == nil {
+		t.Fatal("compressed with nil codec")
+	}
+	if _, err := postgres.Compressed(&s, rawCodec{200}, 0).Value(); err == nil {
+		t.Fatal("compressed with unregistered codec")
+	}
+	if err := postgres.RegisterCodec(nil); err == nil {
+		t.Fatal("registered nil codec")
+	}
+	if err := postgres.RegisterCodec(rawCodec{postgres.GzipCodec.ID()}); err == nil {
+		t.Fatal("registered codec with id of gzip")
+	}
+	if err := postgres.RegisterCodec(rawCodec{200}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := postgres.Compressed(&s, rawCodec{200}, 0).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasSuffix(v.([]byte), []byte("\xc8aaaa")) {
+		t.Fatalf("value %q", v)
+	}
+	var out string
+	if err = postgres.Compressed(&out, rawCodec{200}, 0).Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if out != s {
+		t.Fatalf("scanned %q", out)
+	}
+}
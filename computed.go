@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// ErrComputedProp is returned by writes setting values of computed props.
+var ErrComputedProp = errors.New("postgres: computed prop can't be written")
+
+var (
+	computedMu sync.RWMutex
+	computed   = make(map[string]map[string]string)
+)
+
+// RegisterComputed backs prop of the store by SQL expression over columns of
+// its table, e.g. first_name || ' ' || last_name or extract(epoch from
+// created_at), so Populate, Find and other finds select the expression as the
+// prop. Computed props are left out of inserts and upserts, updates of them fail
+// with ErrComputedProp, and GenerateDDL creates no column for them. Conditions
+// and orders refer to columns, so they can't use computed props. Expressions
+// of finds joining other tables are evaluated over columns of the store only.
+func RegisterComputed(store skyorm.Store, prop skyorm.Prop, expr string) {
+	computedMu.Lock()
+	defer computedMu.Unlock()
+	if computed[store.Name()] == nil {
+		computed[store.Name()] = make(map[string]string)
+	}
+	computed[store.Name()][prop.Name()] = expr
+}
+
+func computedExpr(store skyorm.Store, prop skyorm.Prop) (string, bool) {
+	computedMu.RLock()
+	defer computedMu.RUnlock()
+	expr, ok := computed[store.Name()][prop.Name()]
+	return expr, ok
+}
+
+// selectColumns returns select list of the props of the store, computed props
+// are their expressions aliased by prop names.
+func selectColumns(store skyorm.Store, props []skyorm.Prop) string {
+	l := make([]string, len(props))
+	for i, prop := range props {
+		l[i] = quoteColumn(prop.Name())
+		if expr, ok := computedExpr(store, prop); ok {
+			l[i] = "(" + expr + ") AS " + quoteColumn(prop.Name())
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
+// computedAlias is the alias of LATERAL subquery of computed props in joins.
+const computedAlias = "orm_computed"
+
+// joinedColumns returns select list of props of the store in queries joining
+// it with other tables, columns qualified with table and computed props with
+// computedAlias, and the LATERAL join evaluating expressions of computed props
+// to follow the table, so they refer to its columns only.
+func joinedColumns(store skyorm.Store, props []skyorm.Prop, table string) (string, string) {
+	l := make([]string, len(props))
+	exprs := make([]string, 0)
+	for i, prop := range props {
+		l[i] = table + "." + quoteColumn(prop.Name())
+		if expr, ok := computedExpr(store, prop); ok {
+			l[i] = computedAlias + "." + quoteColumn(prop.Name())
+			exprs = append(exprs, "("+expr+") AS "+quoteColumn(prop.Name()))
+		}
+	}
+	if len(exprs) == 0 {
+		return strings.Join(l, ", "), ""
+	}
+	return strings.Join(l, ", "), " CROSS JOIN LATERAL (SELECT " + strings.Join(exprs, ", ") + ") AS " + computedAlias
+}
+
+// storedProps returns props of the store which aren't computed.
+func storedProps(store skyorm.Store, props []skyorm.Prop) []skyorm.Prop {
+	l := make([]skyorm.Prop, 0, len(props))
+	for _, prop := range props {
+		if _, ok := computedExpr(store, prop); !ok {
+			l = append(l, prop)
+		}
+	}
+	return l
+}
+
+// storedVals returns props of the model stored in columns with their values,
+// without pk when omitPk is set.
+func storedVals(m skyorm.Model, omitPk bool) ([]skyorm.Prop, []interface{}) {
+	props := m.OrmProps()
+	vals := m.OrmVals()
+	lp := make([]skyorm.Prop, 0, len(props))
+	lv := make([]interface{}, 0, len(vals))
+	for i, prop := range props {
+		if omitPk && prop.IsPk() {
+			continue
+		}
+		if _, ok := computedExpr(m.OrmStore(), prop); ok {
+			continue
+		}
+		lp = append(lp, prop)
+		lv = append(lv, vals[i])
+	}
+	return lp, lv
+}
+
+// checkStored returns ErrComputedProp when any of the props of the store is computed.
+func checkStored(store skyorm.Store, props ...skyorm.Prop) error {
+	for _, prop := range props {
+		if _, ok := computedExpr(store, prop); ok {
+			return fmt.Errorf("%w: %s of %s", ErrComputedProp, prop.Name(), store.Name())
+		}
+	}
+	return nil
+}
+
+// checkStoredVals returns ErrComputedProp when any of the values sets computed prop of the store.
+func checkStoredVals(store skyorm.Store, values []skyorm.Val) error {
+	for _, v := range values {
+		if err := checkStored(store, v.Prop()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type account struct {
+	ID     int64
+	UserID int64
+	Name   string
+	Label  string
+}
+
+var accountStore = skyorm.NewStore("accounts", 0, func() skyorm.Model {
+	return &account{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("user_id", "int64", false),
+	skyorm.NewProp("name", "string", false),
+	skyorm.NewProp("label", "string", false),
+)
+
+func (m *account) OrmStore() skyorm.Store    { return accountStore }
+func (m *account) OrmPk() interface{}        { return m.ID }
+func (m *account) OrmPkProp() skyorm.Prop    { return accountStore.Pk() }
+func (m *account) OrmPkPointer() interface{} { return &m.ID }
+func (m *account) OrmProps() []skyorm.Prop   { return accountStore.Props() }
+func (m *account) OrmPointers() []interface{} {
+	return []interface{}{&m.ID, &m.UserID, &m.Name, &m.Label}
+}
+func (m *account) OrmVals() []interface{} { return []interface{}{m.ID, m.UserID, m.Name, m.Label} }
+
+func init() {
+	postgres.RegisterComputed(accountStore, accountStore.Props()[3], "upper(name)")
+}
+
+func TestFindJoinComputed(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT accounts\.id, accounts\.user_id, accounts\.name, orm_computed\.label FROM accounts `+
+		`CROSS JOIN LATERAL \(SELECT \(upper\(name\)\) AS label\) AS orm_computed `+
+		`INNER JOIN users ON accounts\.user_id = users\.id$`).
+		WillReturnRows([]string{"id", "user_id", "name", "label"}, []interface{}{1, 2, "a", "A"})
+	on := postgres.EqProp(postgres.Qualify(accountStore, accountStore.Props()[1]), postgres.Qualify(userStore, userStore.Pk()))
+	l, err := p.FindJoin(context.Background(), accountStore, []postgres.Join{postgres.InnerJoin(userStore, on)}, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0].(*account).Label != "A" {
+		t.Fatalf("FindJoin() = %v, want account labelled A", l)
+	}
+	mock.ExpectQuery(`^SELECT id, user_id, name, \(upper\(name\)\) AS label FROM accounts_of\(\$1\)$`).WithArgs(2).
+		WillReturnRows([]string{"id", "user_id", "name", "label"}, []interface{}{1, 2, "a", "A"})
+	if l, err = p.CallFunction(context.Background(), "accounts_of", 2).Models(accountStore); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0].(*account).Label != "A" {
+		t.Fatalf("Models() = %v, want account labelled A", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
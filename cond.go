@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// exprCond is a condition rendered by this provider rather than by skyorm
+// condition types. It embeds skyorm condition only to satisfy skyorm.Cond.
+type exprCond struct {
+	skyorm.Cond
+	build func(n *int) (string, []interface{})
+}
+
+// exprProp is a property backed by SQL expression over another property.
+type exprProp struct {
+	skyorm.Prop
+	expr string
+}
+
+func (p *exprProp) Name() string {
+	return p.expr
+}
+
+func (p *exprProp) IsPk() bool {
+	return false
+}
+
+// Not returns condition negating c.
+func Not(c skyorm.Cond) skyorm.Cond {
+	e := &exprCond{c, func(n *int) (string, []interface{}) {
+		s, v := parseCond(c, n)
+		if s == "" {
+			return "", v
+		}
+		return "NOT (" + s + ")", v
+	}}
+	if stores := condStores(c); len(stores) > 0 {
+		return &tableCond{e, stores, func(table func(name string) string) skyorm.Cond {
+			c, _ := bindTables(c, table)
+			return Not(c)
+		}}
+	}
+	return e
+}
+
+// placeholder returns the next query placeholder.
+func placeholder(n *int) string {
+	*n++
+	return "$" + strconv.Itoa(*n-1)
+}
+
+// Between returns condition matching property values in inclusive range [from, to].
+func Between(prop skyorm.Prop, from, to interface{}) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, from), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " BETWEEN " + placeholder(n) + " AND " + placeholder(n),
+			[]interface{}{bindColumn(prop, from), bindColumn(prop, to)}
+	}}
+}
+
+// Overlaps returns condition matching range or array property overlapping with val (&&).
+func Overlaps(prop skyorm.Prop, val interface{}) skyorm.Cond {
+	return binaryCond(prop, "&&", val)
+}
+
+// Contains returns condition matching range or array property containing val (@>).
+// For ranges val may be either a range or a single element, for arrays it is a slice.
+func Contains(prop skyorm.Prop, val interface{}) skyorm.Cond {
+	return binaryCond(prop, "@>", val)
+}
+
+// Matches returns condition matching property values matching POSIX regular
+// expression pattern (~), which may use trigram indexes of the column.
+func Matches(prop skyorm.Prop, pattern string) skyorm.Cond {
+	return binaryCond(prop, "~", pattern)
+}
+
+// MatchesFold returns condition matching property values matching POSIX regular
+// expression pattern case insensitively (~*).
+func MatchesFold(prop skyorm.Prop, pattern string) skyorm.Cond {
+	return binaryCond(prop, "~*", pattern)
+}
+
+func binaryCond(prop skyorm.Prop, op string, val interface{}) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, val), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " " + op + " " + placeholder(n), []interface{}{bindColumn(prop, val)}
+	}}
+}
+
+// valueExpr is a bound value which placeholder is wrapped into SQL expression.
+type valueExpr interface {
+	expr(ph string) string
+}
+
+// exprVal is an update value which is set through SQL expression over its placeholder.
+type exprVal struct {
+	prop skyorm.Prop
+	val  interface{}
+	expr func(ph string) string
+	// noArg tells that expression has no placeholder and val is not bound.
+	noArg bool
+}
+
+func (v *exprVal) Prop() skyorm.Prop {
+	return v.prop
+}
+
+func (v *exprVal) Val() interface{} {
+	return v.val
+}
+
+// Incr returns update value atomically adding delta to numeric property: prop = prop + delta.
+func Incr(prop skyorm.Prop, delta interface{}) skyorm.Val {
+	return &exprVal{prop: prop, val: delta, expr: func(ph string) string {
+		return quoteColumn(prop.Name()) + " + " + ph
+	}}
+}
+
+// Now returns update value setting timestamp property to the transaction time: prop = now().
+func Now(prop skyorm.Prop) skyorm.Val {
+	return Raw(prop, "now()")
+}
+
+// Raw returns update value setting property to SQL expression without parameters,
+// e.g. Raw(prop, "DEFAULT") or Raw(prop, "coalesce(other, 0)"). The expression is
+// used as is, so it must never contain user input.
+func Raw(prop skyorm.Prop, expr string) skyorm.Val {
+	return &exprVal{prop: prop, noArg: true, expr: func(string) string {
+		return expr
+	}}
+}
+
+// In returns condition matching property values equal to one of vals.
+func In(prop skyorm.Prop, vals ...interface{}) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, nil), func(n *int) (string, []interface{}) {
+		if len(vals) == 0 {
+			return "FALSE", nil
+		}
+		l := make([]string, len(vals))
+		args := make([]interface{}, len(vals))
+		for i, v := range vals {
+			l[i] = placeholder(n)
+			args[i] = bindColumn(prop, v)
+		}
+		return quoteColumn(prop.Name()) + " IN (" + strings.Join(l, ", ") + ")", args
+	}}
+}
@@ -0,0 +1,58 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+// expectFind finds users matching cond and expects query with args.
+func expectFind(t *testing.T, cond skyorm.Cond, query string, args ...interface{}) {
+	t.Helper()
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(query).WithArgs(args...).WillReturnRows([]string{"id", "name"})
+	if _, err = p.Find(context.Background(), userStore, cond, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNot(t *testing.T) {
+	name := userStore.Props()[1]
+	expectFind(t, postgres.Not(skyorm.Eq(name, "a")), `^SELECT id, name FROM users WHERE NOT \(name = \$1\)$`, "a")
+	expectFind(t, skyorm.And(skyorm.Gt(userStore.Pk(), 1), postgres.Not(skyorm.Or(skyorm.Eq(name, "a"), skyorm.Eq(name, "b")))),
+		`^SELECT id, name FROM users WHERE \(id > \$1 AND NOT \(\(name = \$2 OR name = \$3\)\)\)$`, 1, "a", "b")
+}
+
+func TestBetweenAndOverlaps(t *testing.T) {
+	during := skyorm.NewProp("during", "postgres.IntRange", false)
+	expectFind(t, postgres.Between(userStore.Pk(), 1, 5), `^SELECT id, name FROM users WHERE id BETWEEN \$1 AND \$2$`, 1, 5)
+	expectFind(t, postgres.Overlaps(during, postgres.NewIntRange(1, 5)), `^SELECT id, name FROM users WHERE during && \$1$`, `["1","5")`)
+	expectFind(t, postgres.Contains(during, 3), `^SELECT id, name FROM users WHERE during @> \$1$`, 3)
+}
+
+func TestMatches(t *testing.T) {
+	name := userStore.Props()[1]
+	expectFind(t, postgres.Matches(name, "^a.*z$"), `^SELECT id, name FROM users WHERE name ~ \$1$`, "^a.*z$")
+	expectFind(t, skyorm.And(skyorm.Gt(userStore.Pk(), 1), postgres.MatchesFold(name, "^A")),
+		`^SELECT id, name FROM users WHERE \(id > \$1 AND name ~\* \$2\)$`, 1, "^A")
+}
+
+func TestEqFold(t *testing.T) {
+	name := userStore.Props()[1]
+	expectFind(t, postgres.EqFold(name, "Ann"), `^SELECT id, name FROM users WHERE lower\(name\) = lower\(\$1\)$`, "Ann")
+	expectFind(t, skyorm.And(skyorm.Gt(userStore.Pk(), 1), postgres.EqFold(name, "Ann")),
+		`^SELECT id, name FROM users WHERE \(id > \$1 AND lower\(name\) = lower\(\$2\)\)$`, 1, "Ann")
+	// citext columns compare case insensitively themselves.
+	email := skyorm.NewProp("email", "citext", false)
+	expectFind(t, postgres.EqFold(email, "Ann@Example.com"), `^SELECT id, name FROM users WHERE email = \$1$`, "Ann@Example.com")
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Config is a structured configuration of provider connection and pool.
+type Config struct {
+	Host            string
+	Port            int
+	Database        string
+	User            string
+	Password        string
+	SSLMode         string
+	SSLRootCert     string
+	SSLCert         string
+	SSLKey          string
+	ApplicationName string
+	// ConnectTimeout is rounded up to whole seconds.
+	ConnectTimeout time.Duration
+	// PingTimeout makes NewFromConfig verify connectivity, see WithPing.
+	PingTimeout     time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	Logger          skyorm.Logger
+}
+
+// NewFromConfig returns new postgres provider configured by cfg.
+func NewFromConfig(cfg Config, opts ...Option) (Provider, error) {
+	opts = append([]Option{WithPool(cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime, cfg.ConnMaxIdleTime)}, opts...)
+	if cfg.PingTimeout > 0 {
+		opts = append(opts, WithPing(cfg.PingTimeout))
+	}
+	return New(cfg.DSN(), cfg.Logger, opts...)
+}
+
+// DSN returns key=value connection string with properly quoted values.
+func (c Config) DSN() string {
+	params := map[string]string{
+		"host":             c.Host,
+		"dbname":           c.Database,
+		"user":             c.User,
+		"password":         c.Password,
+		"sslmode":          c.SSLMode,
+		"sslrootcert":      c.SSLRootCert,
+		"sslcert":          c.SSLCert,
+		"sslkey":           c.SSLKey,
+		"application_name": c.ApplicationName,
+	}
+	if c.Port > 0 {
+		params["port"] = strconv.Itoa(c.Port)
+	}
+	if c.ConnectTimeout > 0 {
+		// connect_timeout is in whole seconds, zero disables it.
+		params["connect_timeout"] = strconv.Itoa(int((c.ConnectTimeout + time.Second - 1) / time.Second))
+	}
+	l := make([]string, 0, len(params))
+	for k, v := range params {
+		if v != "" {
+			l = append(l, k+"="+quoteDSNValue(v))
+		}
+	}
+	sort.Strings(l)
+	return strings.Join(l, " ")
+}
+
+// quoteDSNValue quotes value of key=value connection string, so spaces, quotes
+// and backslashes in passwords are passed as is.
+func quoteDSNValue(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// ConfigFromEnv returns config read from environment variables named by prefix:
+// HOST, PORT, DATABASE, USER, PASSWORD, SSLMODE, SSLROOTCERT, SSLCERT, SSLKEY,
+// APPNAME, CONNECT_TIMEOUT, PING_TIMEOUT, MAX_OPEN_CONNS, MAX_IDLE_CONNS,
+// CONN_MAX_LIFETIME and CONN_MAX_IDLE_TIME. Durations use time.ParseDuration format.
+func ConfigFromEnv(prefix string) (Config, error) {
+	return configFromLookup(prefix, os.LookupEnv)
+}
+
+// ConfigFromFile returns config read from file of KEY=VALUE lines, with the same
+// keys as ConfigFromEnv. Empty lines and lines starting with # are ignored.
+func ConfigFromFile(path, prefix string) (Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	values := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return Config{}, fmt.Errorf("%s: invalid line %q", path, line)
+		}
+		values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+	}
+	if err = sc.Err(); err != nil {
+		return Config{}, err
+	}
+	return configFromLookup(prefix, func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	})
+}
+
+func configFromLookup(prefix string, lookup func(key string) (string, bool)) (Config, error) {
+	var (
+		c   Config
+		err error
+	)
+	str := func(key string, dst *string) {
+		if v, ok := lookup(prefix + key); ok {
+			*dst = v
+		}
+	}
+	num := func(key string, dst *int) {
+		if v, ok := lookup(prefix + key); ok && err == nil {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("%s%s: %w", prefix, key, err)
+			}
+		}
+	}
+	dur := func(key string, dst *time.Duration) {
+		if v, ok := lookup(prefix + key); ok && err == nil {
+			if *dst, err = time.ParseDuration(v); err != nil {
+				err = fmt.Errorf("%s%s: %w", prefix, key, err)
+			}
+		}
+	}
+	str("HOST", &c.Host)
+	num("PORT", &c.Port)
+	str("DATABASE", &c.Database)
+	str("USER", &c.User)
+	str("PASSWORD", &c.Password)
+	str("SSLMODE", &c.SSLMode)
+	str("SSLROOTCERT", &c.SSLRootCert)
+	str("SSLCERT", &c.SSLCert)
+	str("SSLKEY", &c.SSLKey)
+	str("APPNAME", &c.ApplicationName)
+	dur("CONNECT_TIMEOUT", &c.ConnectTimeout)
+	dur("PING_TIMEOUT", &c.PingTimeout)
+	num("MAX_OPEN_CONNS", &c.MaxOpenConns)
+	num("MAX_IDLE_CONNS", &c.MaxIdleConns)
+	dur("CONN_MAX_LIFETIME", &c.ConnMaxLifetime)
+	dur("CONN_MAX_IDLE_TIME", &c.ConnMaxIdleTime)
+	return c, err
+}
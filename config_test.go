@@ -0,0 +1,20 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+)
+
+func TestConfigConnectTimeout(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		500 * time.Millisecond:  "connect_timeout='1'",
+		time.Second:             "connect_timeout='1'",
+		1500 * time.Millisecond: "connect_timeout='2'",
+	} {
+		if dsn := (postgres.Config{ConnectTimeout: d}).DSN(); dsn != want {
+			t.Errorf("DSN of %s timeout: %s, want %s", d, dsn, want)
+		}
+	}
+}
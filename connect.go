@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ConnectErrorKind is a cause of connection failure.
+type ConnectErrorKind int
+
+const (
+	ConnectErrorUnknown ConnectErrorKind = iota
+	ConnectErrorDNS
+	ConnectErrorRefused
+	ConnectErrorTimeout
+	ConnectErrorAuth
+	ConnectErrorSSL
+	ConnectErrorDatabase
+)
+
+var connectErrorKinds = map[ConnectErrorKind]string{
+	ConnectErrorUnknown:  "unknown",
+	ConnectErrorDNS:      "dns",
+	ConnectErrorRefused:  "connection refused",
+	ConnectErrorTimeout:  "timeout",
+	ConnectErrorAuth:     "authentication",
+	ConnectErrorSSL:      "ssl",
+	ConnectErrorDatabase: "database does not exist",
+}
+
+func (k ConnectErrorKind) String() string {
+	return connectErrorKinds[k]
+}
+
+// ConnectError is returned when provider can't connect to the database.
+type ConnectError struct {
+	Kind ConnectErrorKind
+	Err  error
+}
+
+func (e *ConnectError) Error() string {
+	return fmt.Sprintf("postgres connect failed (%s): %v", e.Kind, e.Err)
+}
+
+func (e *ConnectError) Unwrap() error {
+	return e.Err
+}
+
+func (p *provider) Ping(ctx context.Context) error {
+	if err := p.db.PingContext(ctx); err != nil {
+		return &ConnectError{classifyConnectError(err), err}
+	}
+	return nil
+}
+
+func classifyConnectError(err error) ConnectErrorKind {
+	var (
+		dnsErr *net.DNSError
+		opErr  *net.OpError
+		pqErr  *pq.Error
+	)
+	switch {
+	case errors.As(err, &dnsErr):
+		return ConnectErrorDNS
+	case errors.As(err, &pqErr):
+		switch pqErr.Code {
+		case "28000", "28P01":
+			return ConnectErrorAuth
+		case "3D000":
+			return ConnectErrorDatabase
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		return ConnectErrorTimeout
+	case errors.As(err, &opErr):
+		if opErr.Timeout() {
+			return ConnectErrorTimeout
+		}
+		return ConnectErrorRefused
+	case strings.Contains(err.Error(), "SSL"):
+		// lib/pq reports ssl negotiation failures as plain errors.
+		return ConnectErrorSSL
+	}
+	return ConnectErrorUnknown
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// FKAction is a referential action of foreign key constraint.
+type FKAction string
+
+const (
+	NoAction   FKAction = ""
+	Cascade    FKAction = "CASCADE"
+	SetNull    FKAction = "SET NULL"
+	SetDefault FKAction = "SET DEFAULT"
+	Restrict   FKAction = "RESTRICT"
+)
+
+// Constraint is a unique or check constraint of a store.
+type Constraint struct {
+	// Name is the constraint name, required for check constraints. Unique
+	// constraints are named by table and prop names with _key suffix by default.
+	Name string
+	// Unique are props of unique constraint.
+	Unique []skyorm.Prop
+	// Check is the expression of check constraint, e.g. "price >= 0".
+	Check string
+}
+
+func (c Constraint) name(store skyorm.Store) string {
+	if c.Name != "" || c.Check != "" {
+		return c.Name
+	}
+	_, table := splitTable(store.Name())
+	parts := []string{table}
+	for _, prop := range c.Unique {
+		parts = append(parts, prop.Name())
+	}
+	return strings.Join(parts, "_") + "_key"
+}
+
+// definition returns constraint definition of table constraint clause.
+func (c Constraint) definition(store skyorm.Store) (string, error) {
+	name := c.name(store)
+	switch {
+	case name == "":
+		return "", fmt.Errorf("check constraint of %s has no name", store.Name())
+	case c.Check != "" && len(c.Unique) > 0:
+		return "", fmt.Errorf("constraint %s of %s is both unique and check", name, store.Name())
+	case c.Check != "":
+		return fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteIdent(name), c.Check), nil
+	case len(c.Unique) > 0:
+		columns := make([]string, len(c.Unique))
+		for i, prop := range c.Unique {
+			columns[i] = quoteColumn(prop.Name())
+		}
+		return fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", quoteIdent(name), strings.Join(columns, ", ")), nil
+	}
+	return "", fmt.Errorf("constraint %s of %s has neither unique props nor check", name, store.Name())
+}
+
+var (
+	constraintsMu sync.RWMutex
+	constraints   = make(map[string][]Constraint)
+)
+
+// RegisterConstraint declares constraint of the store, so it's created by
+// EnsureConstraints and included in GenerateDDL.
+func RegisterConstraint(store skyorm.Store, c Constraint) {
+	constraintsMu.Lock()
+	defer constraintsMu.Unlock()
+	constraints[store.Name()] = append(constraints[store.Name()], c)
+}
+
+func storeConstraints(store skyorm.Store) []Constraint {
+	constraintsMu.RLock()
+	defer constraintsMu.RUnlock()
+	return append([]Constraint(nil), constraints[store.Name()]...)
+}
+
+// foreignKey is a foreign key constraint derived from relation.
+type foreignKey struct {
+	name     string
+	store    skyorm.Store
+	prop     skyorm.Prop
+	target   skyorm.Store
+	onDelete FKAction
+	onUpdate FKAction
+}
+
+// relationForeignKey returns foreign key of the relation, which is of Target
+// for HasMany relations.
+func relationForeignKey(r Relation) foreignKey {
+	fk := foreignKey{store: r.Store, prop: r.Prop, target: r.Target, onDelete: r.OnDelete, onUpdate: r.OnUpdate}
+	if r.Kind == HasMany {
+		fk.store, fk.target = r.Target, r.Store
+	}
+	_, table := splitTable(fk.store.Name())
+	fk.name = table + "_" + fk.prop.Name() + "_fkey"
+	return fk
+}
+
+// foreignKeys returns foreign keys of the store derived from all registered
+// relations, ordered by name.
+func foreignKeys(store skyorm.Store) []foreignKey {
+	relationsMu.RLock()
+	defer relationsMu.RUnlock()
+	seen := make(map[string]bool)
+	l := make([]foreignKey, 0)
+	for _, rs := range relations {
+		for _, r := range rs {
+			fk := relationForeignKey(r)
+			if fk.store.Name() != store.Name() || seen[fk.name] {
+				continue
+			}
+			seen[fk.name] = true
+			l = append(l, fk)
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].name < l[j].name
+	})
+	return l
+}
+
+func (fk foreignKey) definition(target string) string {
+	s := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+		quoteIdent(fk.name), quoteColumn(fk.prop.Name()), target, quoteColumn(fk.target.Pk().Name()))
+	if fk.onDelete != NoAction {
+		s += " ON DELETE " + string(fk.onDelete)
+	}
+	if fk.onUpdate != NoAction {
+		s += " ON UPDATE " + string(fk.onUpdate)
+	}
+	return s
+}
+
+// EnsureConstraints adds constraints registered for the store and its foreign
+// keys of registered relations which don't exist, by name.
+func (p *provider) EnsureConstraints(ctx context.Context, store skyorm.Store) error {
+	table := p.table(ctx, store.Name())
+	type named struct{ name, definition string }
+	l := make([]named, 0)
+	for _, c := range storeConstraints(store) {
+		definition, err := c.definition(store)
+		if err != nil {
+			return err
+		}
+		l = append(l, named{c.name(store), definition})
+	}
+	for _, fk := range foreignKeys(store) {
+		l = append(l, named{fk.name, fk.definition(p.table(ctx, fk.target.Name()))})
+	}
+	for _, c := range l {
+		var exists bool
+		err := p.queryRow(ctx, "SELECT EXISTS(SELECT 1 FROM pg_constraint WHERE conrelid = to_regclass($1) AND conname = $2)",
+			[]interface{}{table, c.name}, &exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err = p.exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD %s", table, c.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
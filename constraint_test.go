@@ -0,0 +1,38 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestEnsureConstraints(t *testing.T) {
+	postgres.RegisterConstraint(commentStore, postgres.Constraint{Unique: commentStore.Props()[1:]})
+	postgres.RegisterConstraint(commentStore, postgres.Constraint{Name: "comments_id_check", Check: "id > 0"})
+	postgres.RegisterRelation(postgres.Relation{Name: "user", Kind: postgres.BelongsTo, Store: commentStore,
+		Target: userStore, Prop: commentStore.Props()[1], OnDelete: postgres.Cascade})
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exists := `^SELECT EXISTS\(SELECT 1 FROM pg_constraint WHERE conrelid = to_regclass\(\$1\) AND conname = \$2\)$`
+	// existing constraints are skipped.
+	mock.ExpectQuery(exists).WithArgs("acme.comments", "comments_user_id_key").
+		WillReturnRows([]string{"exists"}, []interface{}{true})
+	mock.ExpectQuery(exists).WithArgs("acme.comments", "comments_id_check").
+		WillReturnRows([]string{"exists"}, []interface{}{false})
+	mock.ExpectExec(`^ALTER TABLE acme\.comments ADD CONSTRAINT comments_id_check CHECK \(id > 0\)$`).WillReturnResult(0)
+	// foreign keys reference tables of the tenant too.
+	mock.ExpectQuery(exists).WithArgs("acme.comments", "comments_user_id_fkey").
+		WillReturnRows([]string{"exists"}, []interface{}{false})
+	mock.ExpectExec(`^ALTER TABLE acme\.comments ADD CONSTRAINT comments_user_id_fkey FOREIGN KEY \(user_id\) ` +
+		`REFERENCES acme\.users \(id\) ON DELETE CASCADE$`).WillReturnResult(0)
+	if err = p.EnsureConstraints(postgres.WithTenant(context.Background(), "acme"), commentStore); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Converter encodes values of a Go type to column values and decodes column
+// values into them, e.g. for types which don't implement driver.Valuer and
+// sql.Scanner themselves.
+type Converter struct {
+	// Encode returns column value of v, which is of the converted type.
+	Encode func(v interface{}) (driver.Value, error)
+	// Decode decodes column value src, which is never nil, into dest, which is
+	// a pointer to the converted type.
+	Decode func(src interface{}, dest interface{}) error
+}
+
+// WithConverter registers converter of Go type of sample, e.g. time.Duration(0).
+// Values and pointers of the type are encoded when bound to queries and decoded
+// when scanned into models, nil pointers are NULL.
+func WithConverter(sample interface{}, c Converter) Option {
+	return func(o *options) {
+		if o.converters == nil {
+			o.converters = make(map[reflect.Type]Converter)
+		}
+		o.converters[reflect.TypeOf(sample)] = c
+	}
+}
+
+// WithIntervals makes provider bind time.Duration props as intervals and scan
+// intervals into them with DurationConverter. Without it durations are bound as
+// numbers of nanoseconds, e.g. into bigint columns.
+func WithIntervals() Option {
+	return WithConverter(time.Duration(0), DurationConverter)
+}
+
+// DurationConverter converts time.Duration to interval, intervals of months
+// and years can't be decoded as their duration varies.
+var DurationConverter = Converter{
+	Encode: func(v interface{}) (driver.Value, error) {
+		return strconv.FormatInt(v.(time.Duration).Microseconds(), 10) + " microseconds", nil
+	},
+	Decode: func(src interface{}, dest interface{}) error {
+		var s string
+		switch src := src.(type) {
+		case []byte:
+			s = string(src)
+		case string:
+			s = src
+		default:
+			return fmt.Errorf("can't decode %T into time.Duration", src)
+		}
+		d, err := parseInterval(s)
+		if err != nil {
+			return err
+		}
+		*dest.(*time.Duration) = d
+		return nil
+	},
+}
+
+// parseInterval parses interval in postgres output style, e.g. "1 day -02:03:04.5".
+func parseInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Contains(f, ":") {
+			neg := strings.HasPrefix(f, "-")
+			parts := strings.Split(strings.TrimLeft(f, "+-"), ":")
+			if len(parts) != 3 {
+				return 0, fmt.Errorf("invalid interval %q", s)
+			}
+			h, herr := strconv.ParseInt(parts[0], 10, 64)
+			m, merr := strconv.ParseInt(parts[1], 10, 64)
+			sec, serr := parseSeconds(parts[2])
+			if herr != nil || merr != nil || serr != nil {
+				return 0, fmt.Errorf("invalid interval %q", s)
+			}
+			t := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + sec
+			if neg {
+				t = -t
+			}
+			d += t
+			continue
+		}
+		if i+1 == len(fields) {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval %q", s)
+		}
+		i++
+		switch strings.TrimSuffix(fields[i], "s") {
+		case "day":
+			d += time.Duration(n) * 24 * time.Hour
+		default:
+			return 0, fmt.Errorf("interval %q has no fixed duration", s)
+		}
+	}
+	return d, nil
+}
+
+// parseSeconds parses seconds with up to 6 fractional digits, e.g. "04.5", as
+// whole microseconds, so intervals encoded from durations decode unchanged.
+func parseSeconds(s string) (time.Duration, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	if len(frac) > 6 || strings.Trim(frac, "0123456789") != "" {
+		return 0, fmt.Errorf("invalid seconds %q", s)
+	}
+	sec, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	var us int64
+	if frac != "" {
+		if us, err = strconv.ParseInt(frac+strings.Repeat("0", 6-len(frac)), 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	return time.Duration(sec)*time.Second + time.Duration(us)*time.Microsecond, nil
+}
+
+// convertedValue is a value bound with its converter.
+type convertedValue struct {
+	v interface{}
+	c Converter
+}
+
+func (v convertedValue) Value() (driver.Value, error) {
+	return v.c.Encode(v.v)
+}
+
+// convertedPointer is a pointer to converted type, or a pointer to pointer to
+// it when nullable, scanned with its converter.
+type convertedPointer struct {
+	p        reflect.Value
+	c        Converter
+	nullable bool
+}
+
+func (p convertedPointer) Scan(src interface{}) error {
+	if !p.nullable {
+		if src == nil {
+			return fmt.Errorf("can't scan NULL into %s", p.p.Type().Elem())
+		}
+		return p.c.Decode(src, p.p.Interface())
+	}
+	if src == nil {
+		p.p.Elem().Set(reflect.Zero(p.p.Type().Elem()))
+		return nil
+	}
+	v := reflect.New(p.p.Type().Elem().Elem())
+	if err := p.c.Decode(src, v.Interface()); err != nil {
+		return err
+	}
+	p.p.Elem().Set(v)
+	return nil
+}
+
+// bindValues encodes values of types with converters and binds the rest with bindValues.
+func (p *provider) bindValues(l []interface{}) []interface{} {
+	if len(p.converters) == 0 {
+		return bindValues(l)
+	}
+	w := make([]interface{}, len(l))
+	for i, v := range l {
+		w[i] = v
+		t := reflect.TypeOf(v)
+		if t == nil {
+			continue
+		}
+		if c, ok := p.converters[t]; ok {
+			w[i] = convertedValue{v, c}
+			continue
+		}
+		if t.Kind() != reflect.Ptr {
+			continue
+		}
+		if c, ok := p.converters[t.Elem()]; ok {
+			w[i] = nil
+			if rv := reflect.ValueOf(v); !rv.IsNil() {
+				w[i] = convertedValue{rv.Elem().Interface(), c}
+			}
+		}
+	}
+	// converted values are valuers, so they are bound as they are.
+	return bindValues(w)
+}
+
+// scanPointers wraps pointers to types with converters, times with zonedTime
+// when provider has time zone and the rest with scanPointers.
+func (p *provider) scanPointers(l []interface{}) []interface{} {
+	w := scanPointers(l)
+	if p.location != nil {
+		w = zonePointers(w, p.location)
+	}
+	if len(p.converters) == 0 {
+		return w
+	}
+	for i, ptr := range l {
+		t := reflect.TypeOf(ptr)
+		if t == nil || t.Kind() != reflect.Ptr {
+			continue
+		}
+		if c, ok := p.converters[t.Elem()]; ok {
+			w[i] = convertedPointer{p: reflect.ValueOf(ptr), c: c}
+			continue
+		}
+		if t.Elem().Kind() != reflect.Ptr {
+			continue
+		}
+		if c, ok := p.converters[t.Elem().Elem()]; ok {
+			w[i] = convertedPointer{p: reflect.ValueOf(ptr), c: c, nullable: true}
+		}
+	}
+	return w
+}
@@ -0,0 +1,46 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestIntervalsRoundTrip(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithIntervals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ttls := []time.Duration{
+		1000001 * time.Microsecond,
+		-(2*time.Hour + 3*time.Minute + 4500*time.Millisecond),
+		24*time.Hour + 290*time.Millisecond,
+	}
+	mock.ExpectQuery(`^SELECT id, ttl FROM sessions$`).WillReturnRows([]string{"id", "ttl"},
+		[]interface{}{1, "00:00:01.000001"},
+		[]interface{}{2, "-02:03:04.5"},
+		[]interface{}{3, "1 day 00:00:00.29"},
+	)
+	l, err := p.Find(context.Background(), sessionStore, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != len(ttls) {
+		t.Fatalf("found %d sessions", len(l))
+	}
+	for i, m := range l {
+		if ttl := m.(*session).TTL; ttl != ttls[i] {
+			t.Errorf("ttl %d: %s, want %s", i, ttl, ttls[i])
+		}
+	}
+	mock.ExpectQuery(`^SELECT id, ttl FROM sessions$`).WillReturnRows([]string{"id", "ttl"}, []interface{}{1, "00:00:01.0000001"})
+	if _, err = p.Find(context.Background(), sessionStore, nil, 0, 0); err == nil {
+		t.Fatal("scanned interval with nanoseconds")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// CTE is a named common table expression of WITH clause.
+type CTE struct {
+	Name string
+	// Query is the statement of the expression, its args are numbered from $1.
+	Query string
+	Args  []interface{}
+	// Modifying marks data-modifying statements, e.g. DELETE ... RETURNING, so
+	// finds using them run on the primary and bypass the result cache.
+	Modifying bool
+}
+
+type ctesKey struct{}
+
+// WithCTE returns context which Find, FindOne, Count, Update and Delete run
+// with the common table expressions added to CTEs of parent context, e.g. for
+// dedupe or ranked selection referenced by InCTE conditions.
+func WithCTE(ctx context.Context, ctes ...CTE) context.Context {
+	l := append(append([]CTE(nil), contextCTEs(ctx)...), ctes...)
+	ctx = context.WithValue(ctx, ctesKey{}, l)
+	for _, c := range ctes {
+		if c.Modifying {
+			ctx = NoCache(WithPrimary(ctx))
+			break
+		}
+	}
+	return ctx
+}
+
+func contextCTEs(ctx context.Context) []CTE {
+	l, _ := ctx.Value(ctesKey{}).([]CTE)
+	return l
+}
+
+var ctePlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// withCTEs prepends WITH clause of CTEs of ctx to the query, numbering their
+// args after args of the query.
+func withCTEs(ctx context.Context, query string, args []interface{}) (string, []interface{}) {
+	ctes := contextCTEs(ctx)
+	if len(ctes) == 0 {
+		return query, args
+	}
+	args = append([]interface{}(nil), args...)
+	l := make([]string, len(ctes))
+	for i, c := range ctes {
+		offset := len(args)
+		q := ctePlaceholder.ReplaceAllStringFunc(c.Query, func(ph string) string {
+			n, _ := strconv.Atoi(ph[1:])
+			return "$" + strconv.Itoa(n+offset)
+		})
+		l[i] = quoteIdent(c.Name) + " AS (" + q + ")"
+		args = append(args, c.Args...)
+	}
+	return "WITH " + strings.Join(l, ", ") + " " + query, args
+}
+
+// InCTE returns condition matching property values in column of the CTE.
+func InCTE(prop skyorm.Prop, cte, column string) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, nil), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " IN (SELECT " + quoteColumn(column) + " FROM " + quoteIdent(cte) + ")", nil
+	}}
+}
@@ -0,0 +1,37 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestDeleteInCTE(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^WITH dupes AS \(SELECT max\(id\) AS id FROM users GROUP BY name HAVING count\(\*\) > \$1\) ` +
+		`DELETE FROM users WHERE \(id IN \(SELECT id FROM dupes\)\)$`).WithArgs(1).WillReturnResult(2)
+	ctx := postgres.WithCTE(context.Background(), postgres.CTE{
+		Name:  "dupes",
+		Query: "SELECT max(id) AS id FROM users GROUP BY name HAVING count(*) > $1",
+		Args:  []interface{}{1},
+	})
+	cond := skyorm.And(postgres.InCTE(userStore.Pk(), "dupes", "id"))
+	if err = p.Delete(ctx, userStore, cond); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInCTE(t *testing.T) {
+	expectFind(t, postgres.InCTE(userStore.Pk(), "recent", "order"),
+		`^SELECT id, name FROM users WHERE id IN \(SELECT "order" FROM recent\)$`)
+}
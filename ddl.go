@@ -0,0 +1,179 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// columnTypes maps Go types of props to column types. int8 is left out, as
+// it's the column type of bigint in props of generated models.
+var columnTypes = map[string]string{
+	"string":             "TEXT",
+	"int":                "BIGINT",
+	"int64":              "BIGINT",
+	"int32":              "INTEGER",
+	"int16":              "SMALLINT",
+	"uint":               "BIGINT",
+	"uint64":             "NUMERIC(20)",
+	"uint32":             "BIGINT",
+	"uint16":             "INTEGER",
+	"float64":            "DOUBLE PRECISION",
+	"float32":            "REAL",
+	"bool":               "BOOLEAN",
+	"time.Time":          "TIMESTAMPTZ",
+	"[]byte":             "BYTEA",
+	"json.RawMessage":    "JSONB",
+	"[]string":           "TEXT[]",
+	"[]int":              "BIGINT[]",
+	"[]int64":            "BIGINT[]",
+	"[]int32":            "INTEGER[]",
+	"[]float64":          "DOUBLE PRECISION[]",
+	"[]float32":          "REAL[]",
+	"[]bool":             "BOOLEAN[]",
+	"map[string]string":  "HSTORE",
+	"postgres.Numeric":   "NUMERIC",
+	"net.IP":             "INET",
+	"net.IPNet":          "CIDR",
+	"net.HardwareAddr":   "MACADDR",
+	"postgres.IntRange":  "INT8RANGE",
+	"postgres.TimeRange": "TSTZRANGE",
+	"postgres.DateRange": "DATERANGE",
+	"sql.NullString":     "TEXT",
+	"sql.NullInt64":      "BIGINT",
+	"sql.NullInt32":      "INTEGER",
+	"sql.NullFloat64":    "DOUBLE PRECISION",
+	"sql.NullBool":       "BOOLEAN",
+	"sql.NullTime":       "TIMESTAMPTZ",
+}
+
+// serialTypes maps pk column types to their auto-incremented counterparts.
+var serialTypes = map[string]string{
+	"BIGINT":   "BIGSERIAL",
+	"INTEGER":  "SERIAL",
+	"SMALLINT": "SMALLSERIAL",
+	"int8":     "BIGSERIAL",
+	"int4":     "SERIAL",
+	"int2":     "SMALLSERIAL",
+}
+
+// columnType returns column type of the prop and whether the column is nullable.
+// Props of Go types are NOT NULL unless they are pointers or sql.Null types,
+// other types, e.g. of generated models, are column types of nullable columns.
+func columnType(prop skyorm.Prop) (string, bool, error) {
+	typ := prop.Type()
+	nullable := strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "sql.Null")
+	if t, ok := columnTypes[strings.TrimPrefix(typ, "*")]; ok {
+		return t, nullable, nil
+	}
+	if typ == "" || strings.ContainsAny(typ, "*.") || strings.ToLower(typ) != typ && strings.ToUpper(typ) != typ {
+		return "", false, fmt.Errorf("prop %s: unsupported type %q", prop.Name(), typ)
+	}
+	return typ, true, nil
+}
+
+// GenerateDDL returns CREATE TYPE statements of registered enums, CREATE TABLE
+// statements of the stores, or CREATE VIEW of the ones with registered views,
+// with their registered constraints, foreign key constraints of registered
+// relations and CREATE INDEX statements of registered indexes and foreign keys
+// without executing them, e.g. for external migration tools. Column types are
+// derived from types of props, integer pks are serial or identity by pk strategy
+// of the store.
+func GenerateDDL(stores ...skyorm.Store) (string, error) {
+	statements := make([]string, 0, len(stores))
+	foreign := make([]string, 0)
+	indexes := make([]string, 0)
+	indexed := make(map[string]bool)
+	types := make([]string, 0)
+	views := make([]string, 0)
+	typed := make(map[string]bool)
+	for _, s := range stores {
+		if v, ok := storeView(s); ok {
+			views = append(views, v.createSQL(quoteTable(s.Name())))
+			if v.Materialized {
+				// e.g. unique index needed by concurrent refresh.
+				for _, spec := range storeIndexes(s) {
+					indexes = append(indexes, spec.createSQL(quoteTable(s.Name())))
+				}
+			}
+			continue
+		}
+		if isMaterializedView(s) {
+			// views of stores without registered view are created elsewhere.
+			continue
+		}
+		columns := make([]string, 0, len(s.Props())+1)
+		enums := storeEnums(s)
+		for _, prop := range storedProps(s, s.Props()) {
+			var (
+				typ      string
+				nullable bool
+				err      error
+			)
+			if e, ok := enums[prop.Name()]; ok {
+				// props of enums may be of custom string types.
+				typ, nullable = quoteTable(e.Name), strings.HasPrefix(prop.Type(), "*")
+				if !typed[e.Name] {
+					typed[e.Name] = true
+					types = append(types, e.createSQL())
+				}
+			} else if typ, nullable, err = columnType(prop); err != nil {
+				return "", fmt.Errorf("store %s: %w", s.Name(), err)
+			}
+			if prop.IsPk() {
+				switch storePkStrategy(s) {
+				case PkIdentity:
+					typ += " GENERATED BY DEFAULT AS IDENTITY"
+				case PkClientAssigned:
+					// pks are assigned by clients, so the column has no default.
+				default:
+					if serial, ok := serialTypes[typ]; ok {
+						typ = serial
+					}
+				}
+				nullable = false
+			}
+			column := quoteColumn(prop.Name()) + " " + typ
+			if !nullable {
+				column += " NOT NULL"
+			}
+			columns = append(columns, column)
+		}
+		columns = append(columns, "PRIMARY KEY ("+quoteColumn(s.Pk().Name())+")")
+		for _, c := range storeConstraints(s) {
+			definition, err := c.definition(s)
+			if err != nil {
+				return "", err
+			}
+			columns = append(columns, definition)
+		}
+		statements = append(statements, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)",
+			quoteTable(s.Name()), strings.Join(columns, ",\n\t")))
+		for _, spec := range storeIndexes(s) {
+			// foreign keys leading registered indexes are covered by them.
+			if len(spec.Props) > 0 {
+				indexed[s.Name()+"."+spec.Props[0].Name()] = true
+			}
+			indexes = append(indexes, spec.createSQL(quoteTable(s.Name())))
+		}
+		for _, fk := range foreignKeys(s) {
+			foreign = append(foreign, fmt.Sprintf("ALTER TABLE %s ADD %s", quoteTable(s.Name()), fk.definition(quoteTable(fk.target.Name()))))
+			if indexed[s.Name()+"."+fk.prop.Name()] {
+				continue
+			}
+			indexed[s.Name()+"."+fk.prop.Name()] = true
+			_, table := splitTable(s.Name())
+			indexes = append(indexes, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s)",
+				quoteIdent(table+"_"+fk.prop.Name()+"_idx"), quoteTable(s.Name()), quoteColumn(fk.prop.Name())))
+		}
+	}
+	// enum types are created before tables using them, foreign keys are added
+	// after all tables are created, as they may reference each other, and views
+	// selecting from tables after them.
+	statements = append(append(append(append(types, statements...), foreign...), views...), indexes...)
+	if len(statements) == 0 {
+		return "", nil
+	}
+	return strings.Join(statements, ";\n\n") + ";\n", nil
+}
@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Numeric is an exact decimal value of numeric or money property, kept as
+// text so it never rounds through float64 in binds and scans. Money values
+// are scanned without currency symbol and group separators, which assumes
+// lc_monetary with '.' decimal point. Use WithDecimal for arithmetic types.
+type Numeric string
+
+func (n Numeric) Value() (driver.Value, error) {
+	if n == "" {
+		return nil, nil
+	}
+	return string(n), nil
+}
+
+func (n *Numeric) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case nil:
+		*n = ""
+		return nil
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	case int64:
+		s = fmt.Sprint(src)
+	default:
+		return fmt.Errorf("can't scan %T into Numeric", src)
+	}
+	*n = Numeric(plainDecimal(s))
+	return nil
+}
+
+// plainDecimal returns numeric text of money output, e.g. 1234.56 of -$1,234.56.
+func plainDecimal(s string) string {
+	if s == "NaN" || strings.HasSuffix(s, "Infinity") {
+		return s
+	}
+	neg := strings.HasPrefix(s, "-") || strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for _, r := range s {
+		if r >= '0' && r <= '9' || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// WithDecimal makes provider bind and scan props of decimal type of sample, e.g.
+// decimal.Decimal of shopspring/decimal, as numeric text with its String method
+// and parse, e.g. decimal.NewFromString, rather than through float64:
+//
+//	postgres.WithDecimal(decimal.Decimal{}, func(s string) (interface{}, error) {
+//		return decimal.NewFromString(s)
+//	})
+func WithDecimal(sample fmt.Stringer, parse func(s string) (interface{}, error)) Option {
+	return WithConverter(sample, Converter{
+		Encode: func(v interface{}) (driver.Value, error) {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				return nil, nil
+			}
+			return v.(fmt.Stringer).String(), nil
+		},
+		Decode: func(src interface{}, dest interface{}) error {
+			var n Numeric
+			if err := n.Scan(src); err != nil {
+				return err
+			}
+			v, err := parse(string(n))
+			if err != nil {
+				return err
+			}
+			return assignValue(dest, v)
+		},
+	})
+}
+
+// assignValue sets value pointed by dest to v or to value pointed by v.
+func assignValue(dest, v interface{}) error {
+	d := reflect.ValueOf(dest).Elem()
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && rv.Type().Elem() == d.Type() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || !rv.Type().AssignableTo(d.Type()) {
+		return fmt.Errorf("can't assign %T to %s", v, d.Type())
+	}
+	d.Set(rv)
+	return nil
+}
@@ -0,0 +1,126 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestNumericScan(t *testing.T) {
+	for src, want := range map[interface{}]postgres.Numeric{
+		nil:                        "",
+		"1234.56":                  "1234.56",
+		"-0.000001":                "-0.000001",
+		"$1,234.56":                "1234.56",
+		"-$1,234.56":               "-1234.56",
+		"($1.00)":                  "-1.00",
+		"NaN":                      "NaN",
+		"-Infinity":                "-Infinity",
+		int64(42):                  "42",
+		"123456789012345678901.50": "123456789012345678901.50",
+	} {
+		n := postgres.Numeric("stale")
+		if err := n.Scan(src); err != nil {
+			t.Fatalf("scan %v: %v", src, err)
+		}
+		if n != want {
+			t.Errorf("scan %v: %q, want %q", src, n, want)
+		}
+	}
+	var n postgres.Numeric
+	if err := n.Scan(1.5); err == nil {
+		t.Fatal("scanned float into Numeric")
+	}
+	if v, err := postgres.Numeric("").Value(); err != nil || v != nil {
+		t.Fatalf("value of empty numeric %v, %v", v, err)
+	}
+	if v, err := postgres.Numeric("1.50").Value(); err != nil || v != "1.50" {
+		t.Fatalf("value %v, %v", v, err)
+	}
+}
+
+// decimalText is a decimal type like shopspring/decimal.Decimal.
+type decimalText struct {
+	s string
+}
+
+func (d decimalText) String() string {
+	return d.s
+}
+
+type price struct {
+	ID       int64
+	Amount   decimalText
+	Discount *decimalText
+}
+
+var priceStore = skyorm.NewStore("prices", 0, func() skyorm.Model {
+	return &price{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("amount", "postgres_test.decimalText", false),
+	skyorm.NewProp("discount", "*postgres_test.decimalText", false),
+)
+
+func (m *price) OrmStore() skyorm.Store     { return priceStore }
+func (m *price) OrmPk() interface{}         { return m.ID }
+func (m *price) OrmPkProp() skyorm.Prop     { return priceStore.Pk() }
+func (m *price) OrmPkPointer() interface{}  { return &m.ID }
+func (m *price) OrmProps() []skyorm.Prop    { return priceStore.Props() }
+func (m *price) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Amount, &m.Discount} }
+func (m *price) OrmVals() []interface{}     { return []interface{}{m.ID, m.Amount, m.Discount} }
+
+func TestWithDecimal(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithDecimal(decimalText{}, func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, errors.New("empty decimal")
+		}
+		return &decimalText{s}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mock.ExpectQuery(`^INSERT INTO prices \(id, amount, discount\) VALUES \(\$1, \$2, \$3\) RETURNING id$`).
+		WithArgs(int64(1), "19.99", nil).WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(ctx, &price{ID: 1, Amount: decimalText{"19.99"}}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, amount, discount FROM prices$`).WillReturnRows([]string{"id", "amount", "discount"},
+		[]interface{}{1, "-$1,234.56", "($0.50)"}, []interface{}{2, "0.1", nil})
+	l, err := p.Find(ctx, priceStore, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := l[0].(*price); m.Amount.s != "-1234.56" || m.Discount == nil || m.Discount.s != "-0.50" {
+		t.Fatalf("scanned %+v", m)
+	}
+	if m := l[1].(*price); m.Amount.s != "0.1" || m.Discount != nil {
+		t.Fatalf("scanned %+v", m)
+	}
+	mock.ExpectQuery(`^SELECT id, amount, discount FROM prices$`).WillReturnRows([]string{"id", "amount", "discount"},
+		[]interface{}{1, "$", nil})
+	if _, err = p.Find(ctx, priceStore, nil, 0, 0); err == nil {
+		t.Fatal("scanned invalid decimal")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	// parsed values have to be of the type of the sample or pointers to it.
+	p, mock, err = postgrestest.NewMock(postgres.WithDecimal(decimalText{}, func(s string) (interface{}, error) {
+		return s, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, amount, discount FROM prices$`).WillReturnRows([]string{"id", "amount", "discount"},
+		[]interface{}{1, "1", nil})
+	if _, err = p.Find(ctx, priceStore, nil, 0, 0); err == nil {
+		t.Fatal("assigned string to decimal")
+	}
+}
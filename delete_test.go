@@ -0,0 +1,78 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestDeleteInBatchesShortBatch(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := `^DELETE FROM events WHERE ctid IN \(SELECT ctid FROM events WHERE kind = \$1 LIMIT 2\)$`
+	mock.ExpectExec(query).WithArgs("a").WillReturnResult(2)
+	mock.ExpectExec(query).WithArgs("a").WillReturnResult(1)
+	mock.ExpectExec(query).WithArgs("a").WillReturnResult(0)
+	n, err := p.DeleteInBatches(context.Background(), eventStore, skyorm.Eq(eventStore.Props()[1], "a"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("deleted %d events, want 3", n)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeleteReturning(t *testing.T) {
+	var deleted int
+	postgres.RegisterHooks(eventStore, postgres.StoreHooks{
+		AfterDelete: func(context.Context, skyorm.Cond) error {
+			deleted++
+			return nil
+		},
+	})
+	defer postgres.RegisterHooks(eventStore, postgres.StoreHooks{})
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := postgres.WithCTE(context.Background(), postgres.CTE{Name: "stale", Query: "SELECT id FROM events WHERE kind = $1", Args: []interface{}{"a"}})
+	cond := skyorm.And(postgres.InCTE(eventStore.Pk(), "stale", "id"))
+	mock.ExpectQuery(`^WITH stale AS \(SELECT id FROM events WHERE kind = \$1\) `+
+		`DELETE FROM events WHERE \(id IN \(SELECT id FROM stale\)\) RETURNING id, kind$`).WithArgs("a").
+		WillReturnRows([]string{"id", "kind"}, []interface{}{int64(1), "a"}, []interface{}{int64(2), "a"})
+	l, err := p.DeleteReturning(ctx, eventStore, cond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[1].(*event).ID != 2 {
+		t.Fatalf("DeleteReturning() = %v, want events 1 and 2", l)
+	}
+	if deleted != 1 {
+		t.Fatalf("AfterDelete ran %d times, want once", deleted)
+	}
+
+	// dry-run deletes are captured without effects.
+	dry, capture := postgres.DryRun(ctx)
+	if l, err = p.DeleteReturning(dry, eventStore, cond); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 0 || deleted != 1 {
+		t.Fatalf("dry-run deleted %v and ran AfterDelete %d times", l, deleted)
+	}
+	if queries := capture.Queries(); len(queries) != 1 || !strings.HasPrefix(queries[0].Query, "WITH stale AS") {
+		t.Fatalf("captured %v, want delete with CTE", queries)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// DiffReport is a result of comparing one read on primary and secondary providers.
+type DiffReport struct {
+	Time          time.Time
+	Op            string
+	Store         string
+	PrimaryRows   int64
+	SecondaryRows int64
+	Mismatches    []PropMismatch
+}
+
+// Match reports whether both providers returned the same result.
+func (r DiffReport) Match() bool {
+	return r.PrimaryRows == r.SecondaryRows && len(r.Mismatches) == 0
+}
+
+// PropMismatch is a difference of property value of the same model in two providers.
+// Primary or Secondary is nil when the model is missing in the corresponding provider.
+type PropMismatch struct {
+	Pk        interface{} `json:"pk"`
+	Prop      string      `json:"prop"`
+	Primary   interface{} `json:"primary"`
+	Secondary interface{} `json:"secondary"`
+}
+
+// DiffReporter receives diff reports of dual write provider.
+type DiffReporter interface {
+	Report(ctx context.Context, r DiffReport) error
+}
+
+// NewSQLDiffReporter returns reporter which persists reports into the table, so
+// data fidelity of migration can be measured over long periods. The table is
+// created by CreateTable method.
+func NewSQLDiffReporter(db *sql.DB, table string) *SQLDiffReporter {
+	return &SQLDiffReporter{db, table}
+}
+
+// SQLDiffReporter persists diff reports into postgres table.
+type SQLDiffReporter struct {
+	db    *sql.DB
+	table string
+}
+
+// CreateTable creates reports table if it does not exist.
+func (r *SQLDiffReporter) CreateTable(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	op TEXT NOT NULL,
+	store TEXT NOT NULL,
+	primary_rows BIGINT NOT NULL,
+	secondary_rows BIGINT NOT NULL,
+	match BOOLEAN NOT NULL,
+	mismatches JSONB
+)`, quoteTable(r.table)))
+	return err
+}
+
+// Report inserts report into the table.
+func (r *SQLDiffReporter) Report(ctx context.Context, report DiffReport) error {
+	var mismatches interface{}
+	if len(report.Mismatches) > 0 {
+		b, err := json.Marshal(report.Mismatches)
+		if err != nil {
+			return err
+		}
+		mismatches = string(b)
+	}
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(
+		"INSERT INTO %s (created_at, op, store, primary_rows, secondary_rows, match, mismatches) VALUES (%s)",
+		quoteTable(r.table),
+		buildInsertPlaceholders(7),
+	), report.Time, report.Op, report.Store, report.PrimaryRows, report.SecondaryRows, report.Match(), mismatches)
+	return err
+}
+
+// diffModels matches models by pk and returns mismatching property values,
+// models only in secondary follow in order of their pks. Pks are matched
+// formatted, so pks of any type, e.g. []byte, can be compared.
+func diffModels(primary, secondary []skyorm.Model) []PropMismatch {
+	l := make([]PropMismatch, 0)
+	sm := make(map[string]skyorm.Model, len(secondary))
+	for _, m := range secondary {
+		sm[fmt.Sprint(m.OrmPk())] = m
+	}
+	for _, pm := range primary {
+		pk := pm.OrmPk()
+		s, ok := sm[fmt.Sprint(pk)]
+		if !ok {
+			l = append(l, PropMismatch{Pk: pk, Prop: pm.OrmPkProp().Name(), Primary: pk})
+			continue
+		}
+		delete(sm, fmt.Sprint(pk))
+		sv := s.OrmVals()
+		for i, v := range pm.OrmVals() {
+			if !valueEqual(v, sv[i]) {
+				l = append(l, PropMismatch{Pk: pk, Prop: pm.OrmProps()[i].Name(), Primary: v, Secondary: sv[i]})
+			}
+		}
+	}
+	keys := make([]string, 0, len(sm))
+	for k := range sm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := sm[k]
+		l = append(l, PropMismatch{Pk: s.OrmPk(), Prop: s.OrmPkProp().Name(), Secondary: s.OrmPk()})
+	}
+	return l
+}
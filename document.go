@@ -0,0 +1,253 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// DocStore stores Go values as jsonb documents identified by string id.
+type DocStore struct {
+	p      *provider
+	table  string
+	schema DocSchema
+}
+
+// DocUpgrade converts document to the next schema version in place.
+type DocUpgrade func(doc map[string]interface{}) error
+
+// DocSchema is a versioned schema of documents. Documents are written with the
+// current version and older documents are upgraded on read, so payloads written
+// by previous versions of Go structs remain readable.
+type DocSchema struct {
+	// Upgrades[i] converts documents of version i+1 to version i+2, so the
+	// current version is len(Upgrades)+1.
+	Upgrades []DocUpgrade
+	// Rewrite makes reads persist upgraded documents, so every document is
+	// upgraded at most once. Otherwise documents are upgraded on every read
+	// until they are written again.
+	Rewrite bool
+}
+
+func (s DocSchema) version() int {
+	return len(s.Upgrades) + 1
+}
+
+// DocStore returns document store persisting documents into the table.
+func (p *provider) DocStore(table string) *DocStore {
+	return &DocStore{p: p, table: table}
+}
+
+// WithSchema returns document store upgrading documents by the schema.
+func (d *DocStore) WithSchema(s DocSchema) *DocStore {
+	return &DocStore{d.p, d.table, s}
+}
+
+// CreateTable creates documents table and GIN index for containment queries if
+// they don't exist.
+func (d *DocStore) CreateTable(ctx context.Context) error {
+	_, table := splitTable(d.table)
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	doc JSONB NOT NULL,
+	version INT NOT NULL DEFAULT 1,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, quoteTable(d.table)),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1", quoteTable(d.table)),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s USING GIN (doc jsonb_path_ops)", quoteIdent(table+"_doc_idx"), quoteTable(d.table)),
+	}
+	for _, query := range queries {
+		if _, err := d.p.exec(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PutDoc inserts or replaces document with id.
+func (d *DocStore) PutDoc(ctx context.Context, id string, v interface{}) error {
+	query := fmt.Sprintf(`INSERT INTO %s (id, doc, version) VALUES ($1, $2::jsonb, $3)
+ON CONFLICT (id) DO UPDATE SET doc = EXCLUDED.doc, version = EXCLUDED.version, updated_at = now()`, quoteTable(d.table))
+	_, err := d.p.exec(ctx, query, id, JSONB(v), d.schema.version())
+	return err
+}
+
+// GetDoc unmarshals document with id into v or returns ErrNotFound error.
+func (d *DocStore) GetDoc(ctx context.Context, id string, v interface{}) error {
+	query := fmt.Sprintf("SELECT doc, version FROM %s WHERE id = $1", quoteTable(d.table))
+	var (
+		raw     []byte
+		version int
+	)
+	if err := d.p.queryRow(ctx, query, []interface{}{id}, &raw, &version); err != nil {
+		return err
+	}
+	raw, err := d.upgrade(ctx, id, raw, version)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// PatchDoc sets value at path of document with id using jsonb_set, without
+// reading the document unless it has to be upgraded first. ErrNotFound error is
+// returned when there is no such document.
+func (d *DocStore) PatchDoc(ctx context.Context, id string, path []string, v interface{}) error {
+	if len(d.schema.Upgrades) > 0 {
+		if err := d.upgradeDoc(ctx, id); err != nil {
+			return err
+		}
+	}
+	query := fmt.Sprintf("UPDATE %s SET doc = jsonb_set(doc, %s, $1::jsonb), updated_at = now() WHERE id = $2",
+		quoteTable(d.table), pq.QuoteLiteral(textArrayLiteral(path)))
+	res, err := d.p.exec(ctx, query, JSONB(v), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err == nil && n == 0 {
+		err = d.p.ErrNotFound()
+	}
+	return err
+}
+
+// DeleteDoc deletes document with id.
+func (d *DocStore) DeleteDoc(ctx context.Context, id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", quoteTable(d.table))
+	_, err := d.p.exec(ctx, query, id)
+	return err
+}
+
+// FindDocs appends documents containing filter (@>, served by the GIN index) to
+// the slice dest points to, ordered by id. Nil filter matches every document.
+//
+//	var users []User
+//	err := docs.FindDocs(ctx, map[string]interface{}{"role": "admin"}, &users, 100, 0)
+func (d *DocStore) FindDocs(ctx context.Context, filter interface{}, dest interface{}, limit, offset int) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("FindDocs dest must be pointer to slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	query := fmt.Sprintf("SELECT id, doc, version FROM %s", quoteTable(d.table))
+	var args []interface{}
+	if filter != nil {
+		query += " WHERE doc @> $1::jsonb"
+		args = append(args, JSONB(filter))
+	}
+	query += " ORDER BY id"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	}
+	docs, err := d.scanDocs(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	// rows are closed before rewrites, which need a connection of their own,
+	// e.g. when the provider is a transaction.
+	elem := slice.Type().Elem()
+	for _, doc := range docs {
+		raw, err := d.upgrade(ctx, doc.id, doc.raw, doc.version)
+		if err != nil {
+			return err
+		}
+		v := reflect.New(elem)
+		if err = json.Unmarshal(raw, v.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, v.Elem()))
+	}
+	return nil
+}
+
+// rawDoc is a stored document before upgrade.
+type rawDoc struct {
+	id      string
+	raw     []byte
+	version int
+}
+
+// scanDocs returns documents of query selecting id, doc and version.
+func (d *DocStore) scanDocs(ctx context.Context, query string, args ...interface{}) ([]rawDoc, error) {
+	res, err := d.p.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	var l []rawDoc
+	for res.Next() {
+		var doc rawDoc
+		if err = res.Scan(&doc.id, &doc.raw, &doc.version); err != nil {
+			return nil, err
+		}
+		l = append(l, doc)
+	}
+	return l, res.Err()
+}
+
+// upgradeDoc persists upgraded document with id if it's outdated.
+func (d *DocStore) upgradeDoc(ctx context.Context, id string) error {
+	query := fmt.Sprintf("SELECT doc, version FROM %s WHERE id = $1", quoteTable(d.table))
+	var (
+		raw     []byte
+		version int
+	)
+	if err := d.p.queryRow(ctx, query, []interface{}{id}, &raw, &version); err != nil {
+		return err
+	}
+	if version >= d.schema.version() {
+		return nil
+	}
+	raw, err := d.convert(id, raw, version)
+	if err != nil {
+		return err
+	}
+	return d.rewrite(ctx, id, raw, version)
+}
+
+// upgrade converts outdated document to the current version and, with Rewrite
+// schema, persists it.
+func (d *DocStore) upgrade(ctx context.Context, id string, raw []byte, version int) ([]byte, error) {
+	if version >= d.schema.version() {
+		return raw, nil
+	}
+	raw, err := d.convert(id, raw, version)
+	if err != nil {
+		return nil, err
+	}
+	if d.schema.Rewrite {
+		if err = d.rewrite(ctx, id, raw, version); err != nil {
+			return nil, err
+		}
+	}
+	return raw, nil
+}
+
+func (d *DocStore) convert(id string, raw []byte, version int) ([]byte, error) {
+	if version < 1 {
+		return nil, fmt.Errorf("document %s has invalid version %d", id, version)
+	}
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	for v := version; v < d.schema.version(); v++ {
+		if err := d.schema.Upgrades[v-1](doc); err != nil {
+			return nil, fmt.Errorf("upgrade document %s from version %d: %w", id, v, err)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// rewrite persists upgraded document unless it was changed since it was read.
+func (d *DocStore) rewrite(ctx context.Context, id string, raw []byte, version int) error {
+	query := fmt.Sprintf("UPDATE %s SET doc = $1::jsonb, version = $2 WHERE id = $3 AND version = $4", quoteTable(d.table))
+	_, err := d.p.exec(ctx, query, string(raw), d.schema.version(), id, version)
+	return err
+}
@@ -0,0 +1,37 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestFindDocsRewrites(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := p.DocStore("docs").WithSchema(postgres.DocSchema{
+		Upgrades: []postgres.DocUpgrade{func(doc map[string]interface{}) error {
+			doc["role"] = "user"
+			return nil
+		}},
+		Rewrite: true,
+	})
+	mock.ExpectQuery(`^SELECT id, doc, version FROM docs ORDER BY id$`).
+		WillReturnRows([]string{"id", "doc", "version"}, []interface{}{"a", []byte(`{}`), 1}, []interface{}{"b", []byte(`{}`), 2})
+	mock.ExpectExec(`^UPDATE docs SET doc = \$1::jsonb, version = \$2 WHERE id = \$3 AND version = \$4$`).
+		WithArgs(`{"role":"user"}`, 2, "a", 1).WillReturnResult(1)
+	var l []map[string]string
+	if err = docs.FindDocs(context.Background(), nil, &l, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[0]["role"] != "user" {
+		t.Fatalf("found %v", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
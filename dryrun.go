@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrDryRun is returned by reads of dry-run context, which queries aren't executed.
+var ErrDryRun = errors.New("postgres: query not executed in dry-run mode")
+
+// CapturedQuery is a query built in dry-run mode.
+type CapturedQuery struct {
+	Op    string
+	Query string
+	Args  []interface{}
+}
+
+// Capture collects queries built in dry-run mode.
+type Capture struct {
+	mu      sync.Mutex
+	queries []CapturedQuery
+}
+
+// Queries returns captured queries in the order they were built.
+func (c *Capture) Queries() []CapturedQuery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]CapturedQuery(nil), c.queries...)
+}
+
+func (c *Capture) add(q CapturedQuery) {
+	c.mu.Lock()
+	c.queries = append(c.queries, q)
+	c.mu.Unlock()
+}
+
+type dryRunKey struct{}
+
+// DryRun returns context which queries are built and captured, but not executed,
+// which is handy to see what conditions produce in tests and audits:
+//
+//	ctx, capture := postgres.DryRun(ctx)
+//	err := p.Update(ctx, store, cond, values...)
+//	fmt.Println(capture.Queries()[0].Query)
+//
+// Writes succeed without effect, reads which results are needed, such as Find,
+// fail with ErrDryRun after their query is captured. Transactional helpers,
+// e.g. queues and rollups, execute their own transaction statements.
+func DryRun(ctx context.Context) (context.Context, *Capture) {
+	c := &Capture{}
+	return context.WithValue(ctx, dryRunKey{}, c), c
+}
+
+// dryRun reports whether ctx is in dry-run mode, so writes skip their effects
+// beyond the query, e.g. after hooks and cache invalidation.
+func dryRun(ctx context.Context) bool {
+	_, ok := ctx.Value(dryRunKey{}).(*Capture)
+	return ok
+}
+
+// captureDryRun captures the query when ctx is in dry-run mode and reports whether it did.
+func captureDryRun(ctx context.Context, q *QueryInfo) bool {
+	c, ok := ctx.Value(dryRunKey{}).(*Capture)
+	if !ok {
+		return false
+	}
+	c.add(CapturedQuery{Op: q.Op, Query: q.Query, Args: append([]interface{}(nil), q.Args...)})
+	return true
+}
@@ -0,0 +1,51 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestDryRunPutAsync(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithAsyncStores(postgres.AsyncConfig{Stores: []skyorm.Store{eventStore}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var after int
+	postgres.RegisterHooks(eventStore, postgres.StoreHooks{
+		AfterInsert: func(context.Context, skyorm.Model) error {
+			after++
+			return nil
+		},
+		AfterDelete: func(context.Context, skyorm.Cond) error {
+			after++
+			return nil
+		},
+	})
+	defer postgres.RegisterHooks(eventStore, postgres.StoreHooks{})
+	ctx, capture := postgres.DryRun(context.Background())
+	if err = p.Put(ctx, &event{Kind: "click"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Delete(ctx, eventStore, skyorm.Eq(eventStore.Props()[1], "click")); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	queries := capture.Queries()
+	if len(queries) != 2 || !strings.HasPrefix(queries[0].Query, "INSERT INTO events") {
+		t.Fatalf("captured %v, want insert and delete of events", queries)
+	}
+	if after != 0 {
+		t.Fatalf("after hooks ran %d times in dry-run", after)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// FailurePolicy defines how dual write provider reacts on secondary provider errors.
+type FailurePolicy int
+
+const (
+	// FailurePolicyLog logs secondary provider errors and reports primary result only.
+	FailurePolicyLog FailurePolicy = iota
+	// FailurePolicyFail returns secondary provider errors to the caller.
+	FailurePolicyFail
+)
+
+// DualWriteConfig is a configuration of dual write provider.
+type DualWriteConfig struct {
+	// Policy is applied when write to secondary provider fails.
+	Policy FailurePolicy
+	// CompareRate is a fraction (0..1) of reads which are repeated on secondary
+	// provider and compared with primary results.
+	CompareRate float64
+	// Logger receives secondary errors and read mismatches.
+	Logger skyorm.Logger
+	// Reporter optionally receives a diff report of every compared read.
+	Reporter DiffReporter
+}
+
+// NewDualWrite returns provider which writes to both primary and secondary
+// providers and reads from primary one. It is intended for gradual migrations
+// where secondary is the new database which has to catch up with the legacy one.
+func NewDualWrite(primary, secondary skyorm.Provider, cfg DualWriteConfig) skyorm.Provider {
+	if cfg.Logger == nil {
+		cfg.Logger = skyorm.DefaultLogger
+	}
+	return &dualWrite{primary, secondary, cfg}
+}
+
+type dualWrite struct {
+	primary   skyorm.Provider
+	secondary skyorm.Provider
+	cfg       DualWriteConfig
+}
+
+func (d *dualWrite) Put(ctx context.Context, models ...skyorm.Model) error {
+	if err := d.primary.Put(ctx, models...); err != nil {
+		return err
+	}
+	// primary has already assigned serial pks, so secondary inserts the same ones.
+	return d.secondaryErr("PUT", d.secondary.Put(withClientPks(ctx), models...))
+}
+
+func (d *dualWrite) Populate(ctx context.Context, model skyorm.Model, pk interface{}) error {
+	if err := d.primary.Populate(ctx, model, pk); err != nil {
+		return err
+	}
+	if d.sample() {
+		m := model.OrmStore().Model()
+		if err := d.secondary.Populate(ctx, m, pk); err != nil {
+			d.logLn("DUAL WRITE POPULATE COMPARE ERROR: %s %v: %v", model.OrmStore().Name(), pk, err)
+			return nil
+		}
+		d.report(ctx, "POPULATE", model.OrmStore(), 1, 1, diffModels(
+			[]skyorm.Model{model},
+			[]skyorm.Model{m},
+		))
+	}
+	return nil
+}
+
+func (d *dualWrite) Find(ctx context.Context, store skyorm.Store, condition skyorm.Cond, limit, offset int) ([]skyorm.Model, error) {
+	l, err := d.primary.Find(ctx, store, condition, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	if d.sample() {
+		// pages of unordered finds may hold different models, so the page of
+		// primary is compared with the same models of secondary.
+		cond := condition
+		if limit > 0 || offset > 0 {
+			pks := make([]interface{}, len(l))
+			for i, m := range l {
+				pks[i] = m.OrmPk()
+			}
+			cond = In(store.Pk(), pks...)
+			if condition != nil {
+				cond = skyorm.And(condition, cond)
+			}
+		}
+		sl, err := d.secondary.Find(ctx, store, cond, 0, 0)
+		if err != nil {
+			d.logLn("DUAL WRITE FIND COMPARE ERROR: %s: %v", store.Name(), err)
+			return l, nil
+		}
+		d.report(ctx, "FIND", store, int64(len(l)), int64(len(sl)), diffModels(l, sl))
+	}
+	return l, nil
+}
+
+func (d *dualWrite) Update(ctx context.Context, store skyorm.Store, condition skyorm.Cond, values ...skyorm.Val) error {
+	if err := d.primary.Update(ctx, store, condition, values...); err != nil {
+		return err
+	}
+	return d.secondaryErr("UPDATE", d.secondary.Update(ctx, store, condition, values...))
+}
+
+func (d *dualWrite) Delete(ctx context.Context, store skyorm.Store, condition skyorm.Cond) error {
+	if err := d.primary.Delete(ctx, store, condition); err != nil {
+		return err
+	}
+	return d.secondaryErr("DELETE", d.secondary.Delete(ctx, store, condition))
+}
+
+func (d *dualWrite) Count(ctx context.Context, store skyorm.Store, condition skyorm.Cond) (int64, error) {
+	cnt, err := d.primary.Count(ctx, store, condition)
+	if err != nil {
+		return 0, err
+	}
+	if d.sample() {
+		scnt, err := d.secondary.Count(ctx, store, condition)
+		if err != nil {
+			d.logLn("DUAL WRITE COUNT COMPARE ERROR: %s: %v", store.Name(), err)
+			return cnt, nil
+		}
+		d.report(ctx, "COUNT", store, cnt, scnt, nil)
+	}
+	return cnt, nil
+}
+
+func (d *dualWrite) ErrNotFound() error {
+	return d.primary.ErrNotFound()
+}
+
+func (d *dualWrite) secondaryErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if d.cfg.Policy == FailurePolicyFail {
+		return err
+	}
+	d.logLn("DUAL WRITE %s SECONDARY ERROR: %v", op, err)
+	return nil
+}
+
+func (d *dualWrite) report(ctx context.Context, op string, store skyorm.Store, primaryRows, secondaryRows int64, mismatches []PropMismatch) {
+	r := DiffReport{
+		Time:          time.Now(),
+		Op:            op,
+		Store:         store.Name(),
+		PrimaryRows:   primaryRows,
+		SecondaryRows: secondaryRows,
+		Mismatches:    mismatches,
+	}
+	if !r.Match() {
+		d.logLn("DUAL WRITE %s MISMATCH: %s: %d primary rows, %d secondary rows, %d prop mismatches",
+			op, r.Store, primaryRows, secondaryRows, len(mismatches))
+	}
+	if d.cfg.Reporter == nil {
+		return
+	}
+	if err := d.cfg.Reporter.Report(ctx, r); err != nil {
+		d.logLn("DUAL WRITE REPORT ERROR: %v", err)
+	}
+}
+
+func (d *dualWrite) sample() bool {
+	return d.cfg.CompareRate > 0 && rand.Float64() < d.cfg.CompareRate
+}
+
+func (d *dualWrite) logLn(format string, v ...interface{}) {
+	d.cfg.Logger.Printf(format+"\n", v...)
+}
+
+func valueEqual(a, b interface{}) bool {
+	a, b = derefValue(a), derefValue(b)
+	// databases may return timestamps in different locations.
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type reports []postgres.DiffReport
+
+func (r *reports) Report(_ context.Context, report postgres.DiffReport) error {
+	*r = append(*r, report)
+	return nil
+}
+
+type token struct {
+	ID   []byte
+	Name string
+}
+
+var tokenStore = skyorm.NewStore("tokens", 0, func() skyorm.Model {
+	return &token{}
+},
+	skyorm.NewProp("id", "[]byte", true),
+	skyorm.NewProp("name", "string", false),
+)
+
+func (m *token) OrmStore() skyorm.Store     { return tokenStore }
+func (m *token) OrmPk() interface{}         { return m.ID }
+func (m *token) OrmPkProp() skyorm.Prop     { return tokenStore.Pk() }
+func (m *token) OrmPkPointer() interface{}  { return &m.ID }
+func (m *token) OrmProps() []skyorm.Prop    { return tokenStore.Props() }
+func (m *token) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Name} }
+func (m *token) OrmVals() []interface{}     { return []interface{}{m.ID, m.Name} }
+
+func newDualWrite(t *testing.T) (skyorm.Provider, *postgrestest.Mock, *postgrestest.Mock, *reports) {
+	primary, pm, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondary, sm, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &reports{}
+	return postgres.NewDualWrite(primary, secondary, postgres.DualWriteConfig{CompareRate: 1, Reporter: r}), pm, sm, r
+}
+
+func TestDualWriteFindComparesPage(t *testing.T) {
+	d, pm, sm, r := newDualWrite(t)
+	pm.ExpectQuery(`^SELECT id, name FROM users LIMIT 1 OFFSET 1$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{2, "b"})
+	sm.ExpectQuery(`^SELECT id, name FROM users WHERE id IN \(\$1\)$`).WithArgs(2).
+		WillReturnRows([]string{"id", "name"}, []interface{}{2, "c"})
+	if _, err := d.Find(context.Background(), userStore, nil, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(*r) != 1 || (*r)[0].SecondaryRows != 1 || len((*r)[0].Mismatches) != 1 || (*r)[0].Mismatches[0].Prop != "name" {
+		t.Fatalf("reports %+v", *r)
+	}
+	for _, m := range []*postgrestest.Mock{pm, sm} {
+		if err := m.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDualWriteUnhashablePk(t *testing.T) {
+	d, pm, sm, r := newDualWrite(t)
+	pm.ExpectQuery(`^SELECT id, name FROM tokens WHERE id = \$1$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{[]byte("a"), "a"})
+	sm.ExpectQuery(`^SELECT id, name FROM tokens WHERE id = \$1$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{[]byte("a"), "a"})
+	if err := d.Populate(context.Background(), &token{}, []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if len(*r) != 1 || !(*r)[0].Match() {
+		t.Fatalf("reports %+v", *r)
+	}
+}
+
+func TestDualWritePutKeepsPrimaryPks(t *testing.T) {
+	postgres.RegisterPkStrategy(eventStore, postgres.PkSerial)
+	defer postgres.RegisterPkStrategy(eventStore, postgres.PkAuto)
+	d, pm, sm, _ := newDualWrite(t)
+	pm.ExpectQuery(`^INSERT INTO events \(kind\) VALUES \(\$1\) RETURNING id$`).WithArgs("a").
+		WillReturnRows([]string{"id"}, []interface{}{5})
+	sm.ExpectQuery(`^INSERT INTO events \(id, kind\) VALUES \(\$1, \$2\) RETURNING id$`).WithArgs(int64(5), "a").
+		WillReturnRows([]string{"id"}, []interface{}{5})
+	if err := d.Put(context.Background(), &event{Kind: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []*postgrestest.Mock{pm, sm} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
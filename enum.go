@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// ErrInvalidEnumValue is returned by writes of values which are not labels of
+// enum type of the prop.
+var ErrInvalidEnumValue = errors.New("invalid enum value")
+
+// Enum is an ENUM type, its values are labels in sort order.
+type Enum struct {
+	Name   string
+	Values []string
+}
+
+// has returns whether v, a string or a value of string kind, e.g. of custom
+// string type, is a label of the enum. NULL is accepted, as it's of any type.
+func (e Enum) has(v interface{}) bool {
+	v = derefValue(v)
+	if vv, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = vv.Value(); err != nil {
+			return false
+		}
+	}
+	if v == nil {
+		return true
+	}
+	var s string
+	switch rv := reflect.ValueOf(v); {
+	case rv.Kind() == reflect.String:
+		s = rv.String()
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		s = string(rv.Bytes())
+	default:
+		return false
+	}
+	for _, label := range e.Values {
+		if label == s {
+			return true
+		}
+	}
+	return false
+}
+
+// createSQL returns statement creating the type unless it exists, CREATE TYPE
+// has no IF NOT EXISTS.
+func (e Enum) createSQL() string {
+	labels := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		labels[i] = pq.QuoteLiteral(v)
+	}
+	return fmt.Sprintf("DO $$ BEGIN CREATE TYPE %s AS ENUM (%s); EXCEPTION WHEN duplicate_object THEN NULL; END $$",
+		quoteTable(e.Name), strings.Join(labels, ", "))
+}
+
+var (
+	enumsMu sync.RWMutex
+	enums   = make(map[string]map[string]Enum)
+)
+
+// RegisterEnum declares the prop of the store backed by the enum type, so the
+// type is created by EnsureEnums and GenerateDDL and values written to the prop
+// are validated. Props of enum types are scanned as strings.
+func RegisterEnum(store skyorm.Store, prop skyorm.Prop, e Enum) {
+	enumsMu.Lock()
+	defer enumsMu.Unlock()
+	if enums[store.Name()] == nil {
+		enums[store.Name()] = make(map[string]Enum)
+	}
+	enums[store.Name()][prop.Name()] = e
+}
+
+// storeEnums returns enums of props of the store by prop names.
+func storeEnums(store skyorm.Store) map[string]Enum {
+	enumsMu.RLock()
+	defer enumsMu.RUnlock()
+	m := make(map[string]Enum, len(enums[store.Name()]))
+	for name, e := range enums[store.Name()] {
+		m[name] = e
+	}
+	return m
+}
+
+// checkEnum returns ErrInvalidEnumValue when v is not a label of enum of the prop.
+func checkEnum(store skyorm.Store, prop skyorm.Prop, v interface{}) error {
+	e, ok := storeEnums(store)[prop.Name()]
+	if !ok || e.has(v) {
+		return nil
+	}
+	return fmt.Errorf("%w: %v of %s.%s is not one of %s", ErrInvalidEnumValue, derefValue(v), store.Name(), prop.Name(), e.Name)
+}
+
+// checkEnumVals validates values of update, expressions are validated by postgres.
+func checkEnumVals(store skyorm.Store, values []skyorm.Val) error {
+	if len(storeEnums(store)) == 0 {
+		return nil
+	}
+	for _, v := range values {
+		if _, ok := v.(*exprVal); ok {
+			continue
+		}
+		if _, ok := v.Val().(valueExpr); ok {
+			continue
+		}
+		if err := checkEnum(store, v.Prop(), v.Val()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEnumModel validates values of enum props of the model.
+func checkEnumModel(m skyorm.Model) error {
+	if len(storeEnums(m.OrmStore())) == 0 {
+		return nil
+	}
+	vals := m.OrmVals()
+	for i, prop := range m.OrmProps() {
+		if err := checkEnum(m.OrmStore(), prop, vals[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnsureEnum creates the enum type if it doesn't exist and adds its missing
+// values after the preceding ones, or before the following ones when they are
+// first, so values are only added in migrations, never removed. Before
+// postgres 12 values can't be added inside of a transaction.
+func (p *provider) EnsureEnum(ctx context.Context, e Enum) error {
+	if len(e.Values) == 0 {
+		return fmt.Errorf("enum %s has no values", e.Name)
+	}
+	res, err := p.query(ctx, "SELECT enumlabel FROM pg_enum WHERE enumtypid = to_regtype($1) ORDER BY enumsortorder",
+		quoteTable(e.Name))
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for res.Next() {
+		var label string
+		if err = res.Scan(&label); err != nil {
+			_ = res.Close()
+			return err
+		}
+		existing[label] = true
+	}
+	if err = res.Err(); err != nil {
+		_ = res.Close()
+		return err
+	}
+	if err = res.Close(); err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		_, err = p.exec(ctx, e.createSQL())
+		return err
+	}
+	for i, v := range e.Values {
+		if existing[v] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s", quoteTable(e.Name), pq.QuoteLiteral(v))
+		if i > 0 {
+			query += " AFTER " + pq.QuoteLiteral(e.Values[i-1])
+		} else {
+			// the first value goes before the first existing one rather than last.
+			for _, next := range e.Values[1:] {
+				if existing[next] {
+					query += " BEFORE " + pq.QuoteLiteral(next)
+					break
+				}
+			}
+		}
+		if _, err = p.exec(ctx, query); err != nil {
+			return err
+		}
+		existing[v] = true
+		p.logf(LevelInfo, "ENUM %s VALUE %s ADDED", e.Name, v)
+	}
+	return nil
+}
+
+// EnsureEnums ensures enum types of props of the store.
+func (p *provider) EnsureEnums(ctx context.Context, store skyorm.Store) error {
+	done := make(map[string]bool)
+	for _, e := range storeEnums(store) {
+		if done[e.Name] {
+			continue
+		}
+		done[e.Name] = true
+		if err := p.EnsureEnum(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
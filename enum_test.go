@@ -0,0 +1,100 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type ticketStatus string
+
+type ticket struct {
+	ID     int64
+	Status ticketStatus
+}
+
+var ticketStore = skyorm.NewStore("tickets", 0, func() skyorm.Model {
+	return &ticket{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("status", "postgres_test.ticketStatus", false),
+)
+
+func (m *ticket) OrmStore() skyorm.Store     { return ticketStore }
+func (m *ticket) OrmPk() interface{}         { return m.ID }
+func (m *ticket) OrmPkProp() skyorm.Prop     { return ticketStore.Pk() }
+func (m *ticket) OrmPkPointer() interface{}  { return &m.ID }
+func (m *ticket) OrmProps() []skyorm.Prop    { return ticketStore.Props() }
+func (m *ticket) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Status} }
+func (m *ticket) OrmVals() []interface{}     { return []interface{}{m.ID, m.Status} }
+
+var ticketStatusEnum = postgres.Enum{Name: "ticket_status", Values: []string{"open", "closed"}}
+
+func init() {
+	postgres.RegisterEnum(ticketStore, ticketStore.Props()[1], ticketStatusEnum)
+}
+
+func TestEnumValidation(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	status := ticketStore.Props()[1]
+	if err = p.Put(ctx, &ticket{ID: 1, Status: "pending"}); !errors.Is(err, postgres.ErrInvalidEnumValue) {
+		t.Fatalf("put of invalid value: %v", err)
+	}
+	mock.ExpectQuery(`^INSERT INTO tickets`).WithArgs(int64(1), "open").WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(ctx, &ticket{ID: 1, Status: "open"}); err != nil {
+		t.Fatal(err)
+	}
+	closed := ticketStatus("closed")
+	var null *ticketStatus
+	for _, v := range []interface{}{"closed", closed, &closed, []byte("closed"), null, nil} {
+		mock.ExpectExec(`^UPDATE tickets SET status = \$1 WHERE id = \$2$`).WillReturnResult(1)
+		if err = p.Update(ctx, ticketStore, skyorm.Eq(ticketStore.Pk(), 1), skyorm.NewVal(status, v)); err != nil {
+			t.Fatalf("update to %#v: %v", v, err)
+		}
+	}
+	for _, v := range []interface{}{"Closed", ticketStatus(""), []byte("pending"), 1} {
+		if err = p.Update(ctx, ticketStore, nil, skyorm.NewVal(status, v)); !errors.Is(err, postgres.ErrInvalidEnumValue) {
+			t.Fatalf("update to %#v: %v", v, err)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnsureEnum(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	labels := `^SELECT enumlabel FROM pg_enum WHERE enumtypid = to_regtype\(\$1\) ORDER BY enumsortorder$`
+	mock.ExpectQuery(labels).WithArgs("ticket_status").WillReturnRows([]string{"enumlabel"})
+	mock.ExpectExec(`^DO \$\$ BEGIN CREATE TYPE ticket_status AS ENUM \('open', 'closed'\); EXCEPTION WHEN duplicate_object THEN NULL; END \$\$$`)
+	if err = p.EnsureEnums(ctx, ticketStore); err != nil {
+		t.Fatal(err)
+	}
+	// values are added in the order of the enum around the existing ones.
+	mock.ExpectQuery(labels).WithArgs("ticket_status").WillReturnRows([]string{"enumlabel"}, []interface{}{"open"}, []interface{}{"closed"})
+	mock.ExpectExec(`^ALTER TYPE ticket_status ADD VALUE IF NOT EXISTS 'new' BEFORE 'open'$`)
+	mock.ExpectExec(`^ALTER TYPE ticket_status ADD VALUE IF NOT EXISTS 'triaged' AFTER 'new'$`)
+	mock.ExpectExec(`^ALTER TYPE ticket_status ADD VALUE IF NOT EXISTS 'won''t fix' AFTER 'closed'$`)
+	if err = p.EnsureEnum(ctx, postgres.Enum{Name: "ticket_status", Values: []string{"new", "triaged", "open", "closed", "won't fix"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.EnsureEnum(ctx, postgres.Enum{Name: "ticket_status"}); err == nil {
+		t.Fatal("ensured enum without values")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// slowExplainTimeout limits EXPLAIN of slow queries run in background.
+const slowExplainTimeout = 10 * time.Second
+
+// Slow queries are explained at most once per slowExplainInterval and by at
+// most slowExplainConcurrency EXPLAINs at a time, so bursts of slow queries
+// don't pile EXPLAINs on an already struggling database.
+const (
+	slowExplainInterval    = time.Second
+	slowExplainConcurrency = 2
+)
+
+// slowExplains limits EXPLAINs of slow queries.
+type slowExplains struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	last time.Time
+}
+
+func newSlowExplains() *slowExplains {
+	return &slowExplains{sem: make(chan struct{}, slowExplainConcurrency)}
+}
+
+// acquire reports whether a slow query may be explained now, release has to
+// be called when it's done.
+func (e *slowExplains) acquire() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if time.Since(e.last) < slowExplainInterval {
+		return false
+	}
+	select {
+	case e.sem <- struct{}{}:
+		e.last = time.Now()
+		return true
+	default:
+		return false
+	}
+}
+
+func (e *slowExplains) release() {
+	<-e.sem
+}
+
+// Explain returns query plan of Find of models matching condition. Analyze runs
+// the query to report actual times and row counts.
+func (p *provider) Explain(ctx context.Context, store skyorm.Store, condition skyorm.Cond, analyze bool) (string, error) {
+	query, args := p.findSQL(ctx, store, condition)
+	if analyze {
+		query = "EXPLAIN ANALYZE " + query
+	} else {
+		query = "EXPLAIN " + query
+	}
+	res, err := p.query(forRead(ctx), query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	return scanPlan(res)
+}
+
+// explainSlowQuery logs plan of slow query run on c. Queries run on databases
+// are explained in background on the same database, the primary or a replica,
+// with session variables of ctx. Queries of transactions are explained in the
+// transaction, so plans see its changes, unless their rows are open. EXPLAINs
+// run without instrumentation, so slow EXPLAINs are not explained in turn.
+func (p *provider) explainSlowQuery(ctx context.Context, c conn, open bool, query string, args []interface{}) {
+	switch sqlOperation(query) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+	default:
+		return
+	}
+	db, ok := c.(*sql.DB)
+	if !ok && open {
+		p.logf(LevelDebug, "SLOW QUERY EXPLAIN SKIPPED: rows of the transaction are open")
+		return
+	}
+	if !p.explains.acquire() {
+		return
+	}
+	if !ok {
+		defer p.explains.release()
+		ctx, cancel := context.WithTimeout(ctx, slowExplainTimeout)
+		defer cancel()
+		p.logPlan(ctx, c, query, args)
+		return
+	}
+	vars := SessionVars(ctx)
+	go func() {
+		defer p.explains.release()
+		ctx, cancel := context.WithTimeout(WithSessionVars(context.Background(), vars), slowExplainTimeout)
+		defer cancel()
+		_, _ = inSession(ctx, db, func(c conn) (int64, error) {
+			p.logPlan(ctx, c, query, args)
+			return 0, nil
+		})
+	}()
+}
+
+// logPlan logs plan of query run on c.
+func (p *provider) logPlan(ctx context.Context, c conn, query string, args []interface{}) {
+	res, err := c.QueryContext(ctx, "EXPLAIN "+query, args...)
+	if err != nil {
+		p.logf(LevelDebug, "SLOW QUERY EXPLAIN ERROR: %v", err)
+		return
+	}
+	plan, err := scanPlan(res)
+	if cerr := res.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		p.logf(LevelDebug, "SLOW QUERY EXPLAIN ERROR: %v", err)
+		return
+	}
+	p.logf(LevelWarn, "SLOW QUERY PLAN: %s\n%s", query, plan)
+}
+
+// scanPlan returns lines of EXPLAIN output joined.
+func scanPlan(res interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}) (string, error) {
+	l := make([]string, 0)
+	for res.Next() {
+		var line string
+		if err := res.Scan(&line); err != nil {
+			return "", err
+		}
+		l = append(l, line)
+	}
+	return strings.Join(l, "\n"), res.Err()
+}
@@ -0,0 +1,42 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestExplainSlowQueryInTx(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithSlowQueryThreshold(time.Nanosecond), postgres.WithSlowQueryExplain())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	tx, err := p.Begin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := userStore.Props()[1]
+	query := `^UPDATE users SET name = \$1 WHERE id = \$2$`
+	mock.ExpectExec(query).WithArgs("b", 1).WillReturnResult(1)
+	mock.ExpectQuery(`^EXPLAIN UPDATE users SET name = \$1 WHERE id = \$2$`).WithArgs("b", 1).
+		WillReturnRows([]string{"QUERY PLAN"}, []interface{}{"Update on users"})
+	// explains are rate limited, so the second update isn't explained.
+	mock.ExpectExec(query).WithArgs("c", 1).WillReturnResult(1)
+	for _, v := range []string{"b", "c"} {
+		if err = tx.Update(ctx, userStore, skyorm.Eq(userStore.Pk(), 1), skyorm.NewVal(name, v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
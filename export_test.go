@@ -0,0 +1,4 @@
+package postgres
+
+// WithDSNParam exports withDSNParam to tests of connection strings.
+var WithDSNParam = withDSNParam
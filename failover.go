@@ -0,0 +1,178 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WithHealthCheck makes provider check the primary and replicas every interval.
+// Unreachable replicas stop receiving reads until they recover. When the primary
+// becomes a standby and one of the replicas is promoted, like target_session_attrs
+// of libpq, new connections of the primary pool go to the promoted host and
+// connections to the demoted one are discarded, so provider survives failovers
+// without a restart.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(o *options) {
+		o.healthInterval = interval
+	}
+}
+
+// failoverConnector connects to the current primary host.
+type failoverConnector struct {
+	connectors []*pq.Connector
+	current    int32
+	// gen is incremented on failover, connections of older generations are invalid.
+	gen uint32
+}
+
+func newFailoverConnector(dsns []string) (*failoverConnector, error) {
+	c := &failoverConnector{}
+	for _, dsn := range dsns {
+		pc, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		c.connectors = append(c.connectors, pc)
+	}
+	return c, nil
+}
+
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	gen := atomic.LoadUint32(&c.gen)
+	cn, err := c.connectors[atomic.LoadInt32(&c.current)].Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &failoverConn{cn, c, gen}, nil
+}
+
+func (c *failoverConnector) Driver() driver.Driver {
+	return c.connectors[0].Driver()
+}
+
+func (c *failoverConnector) switchTo(host int) {
+	atomic.StoreInt32(&c.current, int32(host))
+	atomic.AddUint32(&c.gen, 1)
+}
+
+// failoverConn forwards to lib/pq connection and becomes invalid after failover.
+type failoverConn struct {
+	driver.Conn
+	c   *failoverConnector
+	gen uint32
+}
+
+func (cn *failoverConn) IsValid() bool {
+	return atomic.LoadUint32(&cn.c.gen) == cn.gen
+}
+
+func (cn *failoverConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return cn.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (cn *failoverConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return cn.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (cn *failoverConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return cn.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (cn *failoverConn) Ping(ctx context.Context) error {
+	return cn.Conn.(driver.Pinger).Ping(ctx)
+}
+
+// healthMonitor checks hosts of provider, host 0 is the configured primary and
+// the rest are replicas in order.
+type healthMonitor struct {
+	p         *provider
+	connector *failoverConnector
+	checks    []*sql.DB
+	interval  time.Duration
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func newHealthMonitor(p *provider, connector *failoverConnector, dsns []string, interval time.Duration) (*healthMonitor, error) {
+	m := &healthMonitor{
+		p:         p,
+		connector: connector,
+		interval:  interval,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, dsn := range dsns {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			for _, db := range m.checks {
+				_ = db.Close()
+			}
+			return nil, err
+		}
+		db.SetMaxOpenConns(1)
+		m.checks = append(m.checks, db)
+	}
+	go m.run()
+	return m, nil
+}
+
+func (m *healthMonitor) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+		m.check()
+	}
+}
+
+func (m *healthMonitor) check() {
+	alive := make([]bool, len(m.checks))
+	writable := make([]bool, len(m.checks))
+	for i, db := range m.checks {
+		ctx, cancel := context.WithTimeout(context.Background(), m.interval)
+		var recovery bool
+		err := db.QueryRowContext(ctx, "SELECT pg_is_in_recovery()").Scan(&recovery)
+		cancel()
+		alive[i], writable[i] = err == nil, err == nil && !recovery
+	}
+	if m.p.replicas != nil {
+		for i := range m.p.replicas.dbs {
+			if m.p.replicas.setHealthy(i, alive[i+1]) {
+				if alive[i+1] {
+					m.p.logf(LevelInfo, "REPLICA %d RECOVERED", i)
+				} else {
+					m.p.logf(LevelWarn, "REPLICA %d DEMOTED: health check failed", i)
+				}
+			}
+		}
+	}
+	current := int(atomic.LoadInt32(&m.connector.current))
+	if writable[current] {
+		return
+	}
+	for i, w := range writable {
+		if w {
+			m.p.logf(LevelWarn, "PRIMARY FAILOVER: host %d is not writable, switching to host %d", current, i)
+			m.connector.switchTo(i)
+			return
+		}
+	}
+}
+
+func (m *healthMonitor) close() {
+	close(m.stop)
+	<-m.done
+	for _, db := range m.checks {
+		_ = db.Close()
+	}
+}
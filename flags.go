@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	flagsNamespace = "feature_flags"
+	flagsChannel   = "skyorm_feature_flags"
+)
+
+// Flag is a feature flag. A flag is enabled for a subject listed in Subjects,
+// otherwise for Percentage of subjects, which are selected by a stable hash of
+// flag name and subject.
+type Flag struct {
+	Name    string
+	Enabled bool
+	// Percentage of subjects the flag is enabled for, from 0 to 100.
+	Percentage float64
+	Subjects   []string
+}
+
+// FeatureFlags is a feature flag store kept in key-value store. Flags are cached
+// in process while Listen runs, the cache is invalidated by NOTIFY sent on change.
+type FeatureFlags struct {
+	kv *KV
+	// dsn is used by listener connection.
+	dsn string
+
+	mu        sync.RWMutex
+	listening bool
+	flags     map[string]*Flag
+	// gen is incremented on invalidation, so flags read before it aren't cached.
+	gen uint64
+}
+
+// FeatureFlags returns feature flag store persisting flags into kv.
+func (p *provider) FeatureFlags(kv *KV) *FeatureFlags {
+	return &FeatureFlags{kv: kv, dsn: p.dsn}
+}
+
+// SetFlag creates or updates the flag and notifies listening processes.
+func (f *FeatureFlags) SetFlag(ctx context.Context, flag Flag) error {
+	b, err := json.Marshal(flag)
+	if err != nil {
+		return err
+	}
+	if err = f.kv.Set(ctx, flagsNamespace, flag.Name, b, 0); err != nil {
+		return err
+	}
+	return f.notify(ctx, flag.Name)
+}
+
+// DeleteFlag deletes the flag and notifies listening processes.
+func (f *FeatureFlags) DeleteFlag(ctx context.Context, name string) error {
+	if err := f.kv.Delete(ctx, flagsNamespace, name); err != nil {
+		return err
+	}
+	return f.notify(ctx, name)
+}
+
+// IsEnabled reports whether the flag is enabled for subject. Unknown flags are disabled.
+func (f *FeatureFlags) IsEnabled(ctx context.Context, name, subject string) (bool, error) {
+	flag, err := f.flag(ctx, name)
+	if err != nil || flag == nil || !flag.Enabled {
+		return false, err
+	}
+	for _, s := range flag.Subjects {
+		if s == subject {
+			return true, nil
+		}
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name + ":" + subject))
+	return float64(h.Sum32()%10000) < flag.Percentage*100, nil
+}
+
+// Listen caches flags and invalidates the cache on notifications until ctx is
+// done. Flags aren't cached while listener is disconnected, as notifications
+// may be missed.
+func (f *FeatureFlags) Listen(ctx context.Context) error {
+	l := pq.NewListener(f.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			f.reset(false)
+		case pq.ListenerEventReconnected:
+			f.reset(true)
+		}
+		if err != nil {
+			f.kv.p.logf(LevelWarn, "FEATURE FLAGS LISTENER: %v", err)
+		}
+	})
+	defer func() {
+		f.reset(false)
+		_ = l.Close()
+	}()
+	if err := l.Listen(flagsChannel); err != nil {
+		return err
+	}
+	f.reset(true)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-l.Notify:
+			if n == nil {
+				continue
+			}
+			f.mu.Lock()
+			delete(f.flags, n.Extra)
+			f.gen++
+			f.mu.Unlock()
+		case <-time.After(time.Minute):
+			go func() {
+				_ = l.Ping()
+			}()
+		}
+	}
+}
+
+// reset drops cached flags and sets whether flags are cached.
+func (f *FeatureFlags) reset(listening bool) {
+	f.mu.Lock()
+	f.listening = listening
+	f.flags = make(map[string]*Flag)
+	f.gen++
+	f.mu.Unlock()
+}
+
+// flag returns the flag or nil when it doesn't exist.
+func (f *FeatureFlags) flag(ctx context.Context, name string) (*Flag, error) {
+	f.mu.RLock()
+	flag, cached := f.flags[name]
+	listening, gen := f.listening, f.gen
+	f.mu.RUnlock()
+	if cached {
+		return flag, nil
+	}
+	b, err := f.kv.Get(ctx, flagsNamespace, name)
+	if err != nil && err != f.kv.p.ErrNotFound() {
+		return nil, err
+	}
+	if err == nil {
+		flag = &Flag{}
+		if err = json.Unmarshal(b, flag); err != nil {
+			return nil, err
+		}
+	}
+	if listening {
+		f.mu.Lock()
+		if f.listening && f.gen == gen {
+			f.flags[name] = flag
+		}
+		f.mu.Unlock()
+	}
+	return flag, nil
+}
+
+func (f *FeatureFlags) notify(ctx context.Context, name string) error {
+	_, err := f.kv.p.exec(ctx, "SELECT pg_notify($1, $2)", flagsChannel, name)
+	return err
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"github.com/skyorm/skyorm"
+)
+
+// Lower returns property of lower cased values of prop, e.g. for ordering or
+// expression indexes.
+func Lower(prop skyorm.Prop) skyorm.Prop {
+	return &exprProp{prop, "lower(" + quoteColumn(prop.Name()) + ")"}
+}
+
+// EqFold returns condition matching property values equal to val case
+// insensitively. Props of citext columns are compared as they are, others by
+// lower(prop) = lower($n), which uses index of FoldIndex.
+func EqFold(prop skyorm.Prop, val string) skyorm.Cond {
+	if prop.Type() == "citext" {
+		return skyorm.Eq(prop, val)
+	}
+	return &exprCond{skyorm.Eq(prop, val), func(n *int) (string, []interface{}) {
+		return "lower(" + quoteColumn(prop.Name()) + ") = lower(" + placeholder(n) + ")", []interface{}{val}
+	}}
+}
+
+// FoldIndex returns spec of index of lower(prop) used by EqFold conditions of
+// the prop, registered with RegisterIndex it's created by EnsureIndexes and
+// included in GenerateDDL.
+func FoldIndex(prop skyorm.Prop, unique bool) IndexSpec {
+	return IndexSpec{Props: []skyorm.Prop{Lower(prop)}, Unique: unique}
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// FunctionCall is a call of a stored function, run when its result is scanned.
+type FunctionCall struct {
+	p    *provider
+	ctx  context.Context
+	name string
+	args []interface{}
+}
+
+// CallFunction returns call of stored function name, e.g. "billing.close_period",
+// with args. Calls run on the primary, as functions may write.
+func (p *provider) CallFunction(ctx context.Context, name string, args ...interface{}) *FunctionCall {
+	return &FunctionCall{p, ctx, name, args}
+}
+
+// from returns FROM clause of the call.
+func (c *FunctionCall) from() string {
+	return fmt.Sprintf("FROM %s(%s)", quoteTable(c.name), argPlaceholders(len(c.args)))
+}
+
+// Scan runs the call and scans columns of the first row of its result into
+// dest, e.g. the value of scalar function. It returns sql.ErrNoRows when the
+// result has no rows.
+func (c *FunctionCall) Scan(dest ...interface{}) error {
+	return c.p.queryRow(c.ctx, "SELECT * "+c.from(), c.args, dest...)
+}
+
+// Models runs the call of function returning rows of table of the store, e.g.
+// RETURNS SETOF, and returns them as models. Computed props are evaluated over
+// columns of the rows.
+func (c *FunctionCall) Models(store skyorm.Store) ([]skyorm.Model, error) {
+	res, err := c.p.query(c.ctx, "SELECT "+selectColumns(store, store.Props())+" "+c.from(), c.args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.p.scanModels(store, res)
+}
+
+// CallProcedure calls stored procedure name with args, PostgreSQL 11+. Procedures
+// committing transactions can't be called in a transaction, including the ones
+// wrapping operations with session variables.
+func (p *provider) CallProcedure(ctx context.Context, name string, args ...interface{}) error {
+	_, err := p.exec(ctx, fmt.Sprintf("CALL %s(%s)", quoteTable(name), argPlaceholders(len(args))), args...)
+	return err
+}
+
+// argPlaceholders returns n comma separated placeholders.
+func argPlaceholders(n int) string {
+	l := make([]string, n)
+	for i := range l {
+		l[i] = "$" + strconv.Itoa(i+1)
+	}
+	return strings.Join(l, ", ")
+}
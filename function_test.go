@@ -0,0 +1,60 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestCallFunction(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mock.ExpectQuery(`^SELECT \* FROM billing\.close_period\(\$1, \$2\)$`).WithArgs(2024, 6).
+		WillReturnRows([]string{"close_period"}, []interface{}{int64(12)})
+	var closed int64
+	if err = p.CallFunction(ctx, "billing.close_period", 2024, 6).Scan(&closed); err != nil {
+		t.Fatal(err)
+	}
+	if closed != 12 {
+		t.Fatalf("closed %d, want 12", closed)
+	}
+	mock.ExpectQuery(`^SELECT \* FROM "order"\(\)$`).WillReturnRows([]string{"order"})
+	if err = p.CallFunction(ctx, "order").Scan(&closed); err != sql.ErrNoRows {
+		t.Fatalf("Scan() error = %v, want sql.ErrNoRows", err)
+	}
+	mock.ExpectQuery(`^SELECT id, name FROM active_users\(\$1\)$`).WithArgs("a").
+		WillReturnRows([]string{"id", "name"}, []interface{}{int64(1), "a"}, []interface{}{int64(2), "a"})
+	l, err := p.CallFunction(ctx, "active_users", "a").Models(userStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[1].(*user).ID != 2 {
+		t.Fatalf("Models() = %v, want users 1 and 2", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCallProcedure(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^CALL billing\.archive\(\$1\)$`).WithArgs(30).WillReturnResult(0)
+	if err = p.CallProcedure(context.Background(), "billing.archive", 30); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^CALL vacuum_queue\(\)$`).WillReturnResult(0)
+	if err = p.CallProcedure(context.Background(), "vacuum_queue"); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+package postgres
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+// goTypes maps column types to Go types of model fields.
+var goTypes = map[string]string{
+	"int2":        "int16",
+	"int4":        "int32",
+	"int8":        "int64",
+	"float4":      "float32",
+	"float8":      "float64",
+	"numeric":     "string",
+	"bool":        "bool",
+	"text":        "string",
+	"varchar":     "string",
+	"bpchar":      "string",
+	"citext":      "string",
+	"uuid":        "string",
+	"date":        "time.Time",
+	"timestamp":   "time.Time",
+	"timestamptz": "time.Time",
+	"bytea":       "[]byte",
+	"json":        "[]byte",
+	"jsonb":       "[]byte",
+	"hstore":      "map[string]string",
+}
+
+// nullTypes maps Go types to their nullable counterparts. Nullable int2 is
+// sql.NullInt32, as sql.NullInt16 needs Go 1.17.
+var nullTypes = map[string]string{
+	"int16":     "sql.NullInt32",
+	"int32":     "sql.NullInt32",
+	"int64":     "sql.NullInt64",
+	"float32":   "sql.NullFloat64",
+	"float64":   "sql.NullFloat64",
+	"bool":      "sql.NullBool",
+	"string":    "sql.NullString",
+	"time.Time": "sql.NullTime",
+}
+
+// commonInitialisms are upper cased in Go names.
+var commonInitialisms = map[string]bool{
+	"api": true, "id": true, "ip": true, "json": true, "sql": true, "uid": true,
+	"uri": true, "url": true, "uuid": true, "http": true, "html": true,
+}
+
+// goType returns Go type of the column, arrays are slices and nullable scalars
+// are sql.Null types. Unknown types are strings.
+func goType(c ColumnDef) string {
+	if strings.HasPrefix(c.Type, "_") {
+		if t, ok := goTypes[c.Type[1:]]; ok && t != "[]byte" {
+			return "[]" + t
+		}
+		return "[]string"
+	}
+	t, ok := goTypes[c.Type]
+	if !ok {
+		t = "string"
+	}
+	if c.Nullable && !c.IsPk {
+		if n, ok := nullTypes[t]; ok {
+			return n
+		}
+	}
+	return t
+}
+
+// goName returns exported Go name of snake case SQL name.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		if commonInitialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	s := b.String()
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		s = "T" + s
+	}
+	return s
+}
+
+type genField struct {
+	Name, Type, Column, ColumnType string
+	IsPk                           bool
+}
+
+type genModel struct {
+	Type, Store, Table string
+	PkIndex            int
+	Pk                 genField
+	Fields             []genField
+}
+
+var modelsTemplate = template.Must(template.New("models").Parse(`// Code generated from database schema; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- if .SQL}}
+	"database/sql"
+{{- end}}
+{{- if .Time}}
+	"time"
+{{- end}}
+
+	"github.com/skyorm/skyorm"
+)
+{{range .Models}}
+// {{.Type}} is a model of {{.Table}} table.
+type {{.Type}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}}
+{{- end}}
+}
+
+// {{.Store}} is the store of {{.Type}} models.
+var {{.Store}} = skyorm.NewStore("{{.Table}}", {{.PkIndex}}, func() skyorm.Model {
+	return &{{.Type}}{}
+},
+{{- range .Fields}}
+	skyorm.NewProp("{{.Column}}", "{{.ColumnType}}", {{.IsPk}}),
+{{- end}}
+)
+
+func (m *{{.Type}}) OrmStore() skyorm.Store {
+	return {{.Store}}
+}
+
+func (m *{{.Type}}) OrmPk() interface{} {
+	return m.{{.Pk.Name}}
+}
+
+func (m *{{.Type}}) OrmPkProp() skyorm.Prop {
+	return {{.Store}}.Pk()
+}
+
+func (m *{{.Type}}) OrmPkPointer() interface{} {
+	return &m.{{.Pk.Name}}
+}
+
+func (m *{{.Type}}) OrmProps() []skyorm.Prop {
+	return {{.Store}}.Props()
+}
+
+func (m *{{.Type}}) OrmPointers() []interface{} {
+	return []interface{}{
+{{- range .Fields}}
+		&m.{{.Name}},
+{{- end}}
+	}
+}
+
+func (m *{{.Type}}) OrmVals() []interface{} {
+	return []interface{}{
+{{- range .Fields}}
+		m.{{.Name}},
+{{- end}}
+	}
+}
+{{end}}`))
+
+// GenerateModels returns formatted Go source of package pkg with skyorm.Model
+// and skyorm.Store implementations of the tables, e.g. returned by Introspect.
+// Tables outside of public schema are addressed by schema-qualified names.
+// Tables without single column primary key are skipped, as stores need one.
+func GenerateModels(pkg string, tables []TableDef) ([]byte, error) {
+	data := struct {
+		Package   string
+		SQL, Time bool
+		Models    []genModel
+	}{Package: pkg}
+	for _, t := range tables {
+		m := genModel{Table: t.Name, Type: goName(t.Name), PkIndex: -1}
+		if t.Schema != "" && t.Schema != "public" {
+			m.Table = t.Schema + "." + t.Name
+			m.Type = goName(t.Schema) + m.Type
+		}
+		m.Store = m.Type + "Store"
+		for i, c := range t.Columns {
+			f := genField{Name: goName(c.Name), Type: goType(c), Column: c.Name, ColumnType: c.Type, IsPk: c.IsPk}
+			if c.IsPk {
+				if m.PkIndex >= 0 {
+					m.PkIndex = -2
+				} else if m.PkIndex == -1 {
+					m.PkIndex, m.Pk = i, f
+				}
+			}
+			m.Fields = append(m.Fields, f)
+		}
+		if m.PkIndex < 0 {
+			continue
+		}
+		for _, f := range m.Fields {
+			data.SQL = data.SQL || strings.HasPrefix(f.Type, "sql.")
+			data.Time = data.Time || strings.HasSuffix(f.Type, "time.Time")
+		}
+		data.Models = append(data.Models, m)
+	}
+	var b bytes.Buffer
+	if err := modelsTemplate.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
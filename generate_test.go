@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	gotoken "go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/skyorm/postgres"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerateModels(t *testing.T) {
+	src, err := postgres.GenerateModels("models", []postgres.TableDef{
+		{Schema: "public", Name: "users", Columns: []postgres.ColumnDef{
+			{Name: "id", Type: "int8", IsPk: true},
+			{Name: "name", Type: "text"},
+			{Name: "age", Type: "int2", Nullable: true},
+			{Name: "email", Type: "varchar", Nullable: true},
+			{Name: "tags", Type: "_text"},
+			{Name: "attrs", Type: "hstore"},
+			{Name: "created_at", Type: "timestamptz"},
+		}},
+		{Schema: "billing", Name: "invoice_items", Columns: []postgres.ColumnDef{
+			{Name: "uuid", Type: "uuid", IsPk: true},
+			{Name: "amounts", Type: "_numeric"},
+			{Name: "paid_at", Type: "timestamp", Nullable: true},
+		}},
+		{Schema: "public", Name: "user_roles", Columns: []postgres.ColumnDef{
+			{Name: "user_id", Type: "int8", IsPk: true},
+			{Name: "role_id", Type: "int8", IsPk: true},
+		}},
+		{Schema: "public", Name: "audit", Columns: []postgres.ColumnDef{
+			{Name: "message", Type: "text"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "models.golden")
+	if *update {
+		if err := os.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated source differs from %s:\n%s", golden, src)
+	}
+
+	fset := gotoken.NewFileSet()
+	f, err := parser.ParseFile(fset, "models.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("models", fset, []*ast.File{f}, nil); err != nil {
+		t.Errorf("generated source doesn't compile: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/skyorm/skyorm"
+)
+
+// earthRadius is the mean earth radius in meters.
+const earthRadius = 6371000.0
+
+// metersPerDegree is the length of a latitude degree in meters.
+const metersPerDegree = 111320.0
+
+// BoundingBox returns condition matching coordinates stored in plain double
+// latitude and longitude props within the box. Boxes crossing the antimeridian
+// are not supported.
+func BoundingBox(latProp, lngProp skyorm.Prop, minLat, minLng, maxLat, maxLng float64) skyorm.Cond {
+	return skyorm.And(
+		Between(latProp, minLat, maxLat),
+		Between(lngProp, minLng, maxLng),
+	)
+}
+
+// Near returns condition matching coordinates within radius meters from the point.
+// The bounding box of the circle pre-filters rows, so the index on latitude and
+// longitude props can be used, and haversine distance filters the rest.
+func Near(latProp, lngProp skyorm.Prop, lat, lng, radius float64) skyorm.Cond {
+	dLat := radius / metersPerDegree
+	dLng := 180.0
+	if c := math.Cos(lat * math.Pi / 180); c > 1e-6 {
+		dLng = math.Min(dLat/c, 180)
+	}
+	distance := &exprCond{skyorm.Eq(latProp, lat), func(n *int) (string, []interface{}) {
+		s := haversine(latProp, lngProp, placeholder(n), placeholder(n)) + " <= " + placeholder(n)
+		return s, []interface{}{lat, lng, radius}
+	}}
+	return skyorm.And(
+		BoundingBox(latProp, lngProp, lat-dLat, lng-dLng, lat+dLat, lng+dLng),
+		distance,
+	)
+}
+
+// Distance returns property of haversine distance in meters from the point, to be
+// used in order, e.g. Asc(Distance(lat, lng, 52.52, 13.40)).
+func Distance(latProp, lngProp skyorm.Prop, lat, lng float64) skyorm.Prop {
+	return &exprProp{latProp, haversine(latProp, lngProp, formatFloat(lat), formatFloat(lng))}
+}
+
+// CreateGeoIndex creates composite index on latitude and longitude props supporting
+// BoundingBox and Near conditions.
+func (p *provider) CreateGeoIndex(ctx context.Context, store skyorm.Store, latProp, lngProp skyorm.Prop) error {
+	_, table := splitTable(resolveTable(ctx, store.Name()))
+	query := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s ON %[2]s (%[3]s, %[4]s)",
+		quoteIdent(table+"_"+latProp.Name()+"_"+lngProp.Name()+"_idx"), p.table(ctx, store.Name()), quoteColumn(latProp.Name()), quoteColumn(lngProp.Name()))
+	_, err := p.exec(ctx, query)
+	return err
+}
+
+// haversine returns SQL expression of distance in meters between props and point.
+func haversine(latProp, lngProp skyorm.Prop, lat, lng string) string {
+	return fmt.Sprintf(
+		"(2 * %s * asin(sqrt(power(sin(radians(%s - %s) / 2), 2) + cos(radians(%s)) * cos(radians(%s)) * power(sin(radians(%s - %s) / 2), 2))))",
+		formatFloat(earthRadius), quoteColumn(latProp.Name()), lat, lat, quoteColumn(latProp.Name()), quoteColumn(lngProp.Name()), lng,
+	)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type place struct {
+	ID       int64
+	Lat, Lng float64
+}
+
+var placeStore = skyorm.NewStore("places", 0, func() skyorm.Model {
+	return &place{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("lat", "float64", false),
+	skyorm.NewProp("lng", "float64", false),
+)
+
+func (m *place) OrmStore() skyorm.Store     { return placeStore }
+func (m *place) OrmPk() interface{}         { return m.ID }
+func (m *place) OrmPkProp() skyorm.Prop     { return placeStore.Pk() }
+func (m *place) OrmPkPointer() interface{}  { return &m.ID }
+func (m *place) OrmProps() []skyorm.Prop    { return placeStore.Props() }
+func (m *place) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Lat, &m.Lng} }
+func (m *place) OrmVals() []interface{}     { return []interface{}{m.ID, m.Lat, m.Lng} }
+
+func TestGeoConditions(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lat, lng := placeStore.Props()[1], placeStore.Props()[2]
+	columns := []string{"id", "lat", "lng"}
+
+	mock.ExpectQuery(`^SELECT id, lat, lng FROM places WHERE \(lat BETWEEN \$1 AND \$2 AND lng BETWEEN \$3 AND \$4\)$`).
+		WithArgs(52.0, 53.0, 13.0, 14.0).WillReturnRows(columns, []interface{}{int64(1), 52.5, 13.4})
+	l, err := p.Find(ctx, placeStore, postgres.BoundingBox(lat, lng, 52, 13, 53, 14), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0].(*place).Lat != 52.5 {
+		t.Errorf("found %v in bounding box", l)
+	}
+
+	// the box of a degree of latitude around the equator is a degree of longitude wide.
+	distance := func(lat, lng string) string {
+		return regexp.QuoteMeta("(2 * 6371000 * asin(sqrt(power(sin(radians(lat - " + lat + ") / 2), 2) + cos(radians(" + lat +
+			")) * cos(radians(lat)) * power(sin(radians(lng - " + lng + ") / 2), 2))))")
+	}
+	mock.ExpectQuery(`^SELECT id, lat, lng FROM places WHERE \(\(lat BETWEEN \$1 AND \$2 AND lng BETWEEN \$3 AND \$4\) AND `+
+		distance(`$5`, `$6`)+` <= \$7\) ORDER BY `+distance("0.5", "-0.25")+` LIMIT 1$`).
+		WithArgs(-1.0, 1.0, -1.0, 1.0, 0.0, 0.0, 111320.0).WillReturnRows(columns, []interface{}{int64(2), 0.5, -0.25})
+	m, err := p.FindOne(ctx, placeStore, postgres.Near(lat, lng, 0, 0, 111320), postgres.Asc(postgres.Distance(lat, lng, 0.5, -0.25)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.(*place).ID != 2 {
+		t.Errorf("found %v near the point", m)
+	}
+
+	mock.ExpectExec(`^CREATE INDEX IF NOT EXISTS places_lat_lng_idx ON places \(lat, lng\)$`).WillReturnResult(0)
+	if err = p.CreateGeoIndex(ctx, placeStore, lat, lng); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
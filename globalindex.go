@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// GlobalIndex maps a secondary key of store models to the shard and pk of the model,
+// so lookups by the secondary key don't have to be scattered to every shard.
+type GlobalIndex struct {
+	// Store is the indexed store.
+	Store skyorm.Store
+	// Table is the name of mapping table.
+	Table string
+	// Key returns secondary key of the model. Keys must be unique across
+	// shards, a model put with the key of another model takes over its mapping.
+	Key func(m skyorm.Model) interface{}
+}
+
+// CreateIndexTables creates mapping tables of global indexes on the index database.
+func (s *Sharded) CreateIndexTables(ctx context.Context) error {
+	for _, idx := range s.cfg.Indexes {
+		query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (key TEXT PRIMARY KEY, shard INT NOT NULL, pk TEXT NOT NULL)", quoteTable(idx.Table))
+		if _, err := s.cfg.IndexDB.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByIndex returns model by secondary key of global index with the given table.
+// Mappings which no longer point to a model with this key are removed.
+func (s *Sharded) FindByIndex(ctx context.Context, table string, key interface{}) (skyorm.Model, error) {
+	idx, ok := s.index(table)
+	if !ok {
+		return nil, fmt.Errorf("global index %s is not configured", table)
+	}
+	var (
+		shard int
+		pk    string
+		k     = fmt.Sprint(key)
+	)
+	query := fmt.Sprintf("SELECT shard, pk FROM %s WHERE key = $1", quoteTable(idx.Table))
+	if err := s.cfg.IndexDB.QueryRowContext(ctx, query, k).Scan(&shard, &pk); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, s.ErrNotFound()
+		}
+		return nil, err
+	}
+	if shard < 0 || shard >= len(s.shards) {
+		return nil, fmt.Errorf("global index %s points to unknown shard %d", idx.Table, shard)
+	}
+	m := idx.Store.Model()
+	err := s.shards[shard].Populate(ctx, m, pk)
+	if err == nil && fmt.Sprint(idx.Key(m)) == k {
+		return m, nil
+	}
+	if err != nil && err != s.shards[shard].ErrNotFound() {
+		return nil, err
+	}
+	// model was deleted or its key was updated since the mapping was written.
+	query = fmt.Sprintf("DELETE FROM %s WHERE key = $1 AND shard = $2 AND pk = $3", quoteTable(idx.Table))
+	if _, err := s.cfg.IndexDB.ExecContext(ctx, query, k, shard, pk); err != nil {
+		return nil, err
+	}
+	return nil, s.ErrNotFound()
+}
+
+// storeIndexes returns global indexes of the store.
+func (s *Sharded) storeIndexes(store skyorm.Store) []GlobalIndex {
+	var l []GlobalIndex
+	for _, idx := range s.cfg.Indexes {
+		if idx.Store.Name() == store.Name() {
+			l = append(l, idx)
+		}
+	}
+	return l
+}
+
+// indexed runs write on shard i in a transaction of the shard when models it
+// writes have global indexes. put writes their new mappings in a transaction
+// of IndexDB committed before the shard, and drop removes their old mappings in
+// one committed after it, so models never lack their mappings. Mappings left by
+// writes failing to commit or by drops failing are stale and removed by FindByIndex.
+func (s *Sharded) indexed(ctx context.Context, i int, indexes bool, write func(shard skyorm.Provider) error,
+	put func(shard skyorm.Provider, tx *sql.Tx) error, drop func(tx *sql.Tx) error) error {
+	if !indexes {
+		return write(s.shards[i])
+	}
+	p, ok := s.shards[i].(Provider)
+	if !ok {
+		return fmt.Errorf("shard %d doesn't support transactions of global indexes", i)
+	}
+	stx, err := p.Begin(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stx.Rollback()
+	}()
+	if err = write(stx); err != nil {
+		return err
+	}
+	if put != nil {
+		if err = s.indexTx(ctx, func(tx *sql.Tx) error {
+			return put(stx, tx)
+		}); err != nil {
+			return err
+		}
+	}
+	if err = stx.Commit(); err != nil {
+		return err
+	}
+	if drop == nil {
+		return nil
+	}
+	return s.indexTx(ctx, drop)
+}
+
+// indexTx runs f in a transaction of IndexDB.
+func (s *Sharded) indexTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := s.cfg.IndexDB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = f(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// putIndexes writes mappings of the models put into shard.
+func (s *Sharded) putIndexes(ctx context.Context, tx *sql.Tx, shard int, models []skyorm.Model) error {
+	for _, m := range models {
+		for _, idx := range s.storeIndexes(m.OrmStore()) {
+			query := fmt.Sprintf(
+				"INSERT INTO %s (key, shard, pk) VALUES ($1, $2, $3) ON CONFLICT (key) DO UPDATE SET shard = EXCLUDED.shard, pk = EXCLUDED.pk",
+				quoteTable(idx.Table),
+			)
+			if _, err := tx.ExecContext(ctx, query, fmt.Sprint(idx.Key(m)), shard, fmt.Sprint(m.OrmPk())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dropIndexes deletes mappings of models of the store with the pks in shard,
+// except for mappings of the kept models.
+func (s *Sharded) dropIndexes(ctx context.Context, tx *sql.Tx, store skyorm.Store, shard int, pks []string, kept []skyorm.Model) error {
+	if len(pks) == 0 {
+		return nil
+	}
+	for _, idx := range s.storeIndexes(store) {
+		keys := make([]string, len(kept))
+		for i, m := range kept {
+			keys[i] = fmt.Sprint(idx.Key(m))
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE shard = $1 AND pk = ANY($2) AND NOT key = ANY($3)", quoteTable(idx.Table))
+		if _, err := tx.ExecContext(ctx, query, shard, pq.Array(pks), pq.Array(keys)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchedPks returns pks of models of the store matching condition in shard.
+func matchedPks(ctx context.Context, shard skyorm.Provider, store skyorm.Store, condition skyorm.Cond) ([]interface{}, error) {
+	l, err := shard.Find(ctx, store, condition, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	pks := make([]interface{}, len(l))
+	for i, m := range l {
+		pks[i] = m.OrmPk()
+	}
+	return pks, nil
+}
+
+// updateIndexed updates models of the store matching condition in shard i and
+// rewrites mappings of the updated models, as their keys may have changed.
+func (s *Sharded) updateIndexed(ctx context.Context, i int, store skyorm.Store, condition skyorm.Cond, values ...skyorm.Val) error {
+	var (
+		pks     []interface{}
+		updated []skyorm.Model
+		indexes = len(s.storeIndexes(store)) > 0
+	)
+	return s.indexed(ctx, i, indexes, func(shard skyorm.Provider) error {
+		var err error
+		if indexes {
+			if pks, err = matchedPks(ctx, shard, store, condition); err != nil {
+				return err
+			}
+		}
+		return shard.Update(ctx, store, condition, values...)
+	}, func(shard skyorm.Provider, tx *sql.Tx) error {
+		if len(pks) == 0 {
+			return nil
+		}
+		var err error
+		if updated, err = shard.Find(ctx, store, In(store.Pk(), pks...), 0, 0); err != nil {
+			return err
+		}
+		return s.putIndexes(ctx, tx, i, updated)
+	}, func(tx *sql.Tx) error {
+		// mappings of old keys are dropped only once the new ones are committed.
+		return s.dropIndexes(ctx, tx, store, i, pkStrings(pks), updated)
+	})
+}
+
+// deleteIndexed deletes models of the store matching condition in shard i and
+// mappings of the deleted models once the deletion is committed.
+func (s *Sharded) deleteIndexed(ctx context.Context, i int, store skyorm.Store, condition skyorm.Cond) error {
+	var (
+		pks     []interface{}
+		indexes = len(s.storeIndexes(store)) > 0
+	)
+	return s.indexed(ctx, i, indexes, func(shard skyorm.Provider) error {
+		var err error
+		if indexes {
+			if pks, err = matchedPks(ctx, shard, store, condition); err != nil {
+				return err
+			}
+		}
+		return shard.Delete(ctx, store, condition)
+	}, nil, func(tx *sql.Tx) error {
+		return s.dropIndexes(ctx, tx, store, i, pkStrings(pks), nil)
+	})
+}
+
+func (s *Sharded) index(table string) (GlobalIndex, bool) {
+	for _, idx := range s.cfg.Indexes {
+		if idx.Table == table {
+			return idx, true
+		}
+	}
+	return GlobalIndex{}, false
+}
@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestGlobalIndexMaintained(t *testing.T) {
+	shard, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, index := postgrestest.NewMockDB()
+	s, err := postgres.NewSharded([]skyorm.Provider{shard}, postgres.ShardedConfig{
+		Indexes: []postgres.GlobalIndex{{Store: userStore, Table: "users_by_name", Key: func(m skyorm.Model) interface{} {
+			return m.(*user).Name
+		}}},
+		IndexDB: db,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := userStore.Props()[1]
+	ctx := context.Background()
+
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE name = \$1$`).WithArgs("a").
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"})
+	mock.ExpectExec(`^UPDATE users SET name = \$1 WHERE name = \$2$`).WithArgs("b", "a").WillReturnResult(1)
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE id IN \(\$1\)$`).WithArgs(1).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "b"})
+	// the new mapping is written before the old one is dropped.
+	index.ExpectExec(`^INSERT INTO users_by_name \(key, shard, pk\) VALUES`).WithArgs("b", 0, "1").WillReturnResult(1)
+	index.ExpectExec(`^DELETE FROM users_by_name WHERE shard = \$1 AND pk = ANY\(\$2\) AND NOT key = ANY\(\$3\)$`).
+		WithArgs(0, "{\"1\"}", "{\"b\"}").WillReturnResult(1)
+	if err = s.Update(ctx, userStore, skyorm.Eq(name, "a"), skyorm.NewVal(name, "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE name = \$1$`).WithArgs("b").
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "b"})
+	mock.ExpectExec(`^DELETE FROM users WHERE name = \$1$`).WithArgs("b").WillReturnResult(1)
+	index.ExpectExec(`^DELETE FROM users_by_name WHERE shard = \$1 AND pk = ANY\(\$2\) AND NOT key = ANY\(\$3\)$`).
+		WithArgs(0, "{\"1\"}", "{}").WillReturnResult(1)
+	if err = s.Delete(ctx, userStore, skyorm.Eq(name, "b")); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []*postgrestest.Mock{mock, index} {
+		if err = m.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// BeforeInserter is implemented by models validated or normalized before Put.
+type BeforeInserter interface {
+	BeforeInsert(ctx context.Context) error
+}
+
+// AfterInserter is implemented by models notified after they were inserted by Put.
+type AfterInserter interface {
+	AfterInsert(ctx context.Context) error
+}
+
+// AfterFinder is implemented by models which compute fields after they were
+// read by Populate, Find, FindOne and other finds.
+type AfterFinder interface {
+	AfterFind(ctx context.Context) error
+}
+
+// StoreHooks are callbacks provider invokes around operations on models of a
+// store, e.g. for validation or cache invalidation. Any of them may be nil and
+// an error returned by a callback fails the operation. Model hooks run before
+// store hooks.
+type StoreHooks struct {
+	BeforeInsert func(ctx context.Context, m skyorm.Model) error
+	AfterInsert  func(ctx context.Context, m skyorm.Model) error
+	AfterFind    func(ctx context.Context, m skyorm.Model) error
+	// BeforeUpdate may return replaced values, e.g. with updated_at added.
+	BeforeUpdate func(ctx context.Context, condition skyorm.Cond, values []skyorm.Val) ([]skyorm.Val, error)
+	AfterUpdate  func(ctx context.Context, condition skyorm.Cond, values []skyorm.Val) error
+	BeforeDelete func(ctx context.Context, condition skyorm.Cond) error
+	AfterDelete  func(ctx context.Context, condition skyorm.Cond) error
+}
+
+var (
+	hooksMu sync.RWMutex
+	hooks   = make(map[string]StoreHooks)
+)
+
+// RegisterHooks registers hooks of the store, replacing hooks registered before.
+func RegisterHooks(store skyorm.Store, h StoreHooks) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks[store.Name()] = h
+}
+
+func storeHooks(store skyorm.Store) StoreHooks {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	return hooks[store.Name()]
+}
+
+func beforeInsert(ctx context.Context, m skyorm.Model) error {
+	if h, ok := m.(BeforeInserter); ok {
+		if err := h.BeforeInsert(ctx); err != nil {
+			return err
+		}
+	}
+	if h := storeHooks(m.OrmStore()).BeforeInsert; h != nil {
+		return h(ctx, m)
+	}
+	return nil
+}
+
+func afterInsert(ctx context.Context, m skyorm.Model) error {
+	if h, ok := m.(AfterInserter); ok {
+		if err := h.AfterInsert(ctx); err != nil {
+			return err
+		}
+	}
+	if h := storeHooks(m.OrmStore()).AfterInsert; h != nil {
+		return h(ctx, m)
+	}
+	return nil
+}
+
+func afterFind(ctx context.Context, m skyorm.Model) error {
+	if h, ok := m.(AfterFinder); ok {
+		if err := h.AfterFind(ctx); err != nil {
+			return err
+		}
+	}
+	if h := storeHooks(m.OrmStore()).AfterFind; h != nil {
+		return h(ctx, m)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+type note struct {
+	ID      int64
+	Text    string
+	Updated int64
+}
+
+var noteStore = skyorm.NewStore("notes", 0, func() skyorm.Model {
+	return &note{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("text", "string", false),
+	skyorm.NewProp("updated", "int64", false),
+)
+
+func (m *note) OrmStore() skyorm.Store     { return noteStore }
+func (m *note) OrmPk() interface{}         { return m.ID }
+func (m *note) OrmPkProp() skyorm.Prop     { return noteStore.Pk() }
+func (m *note) OrmPkPointer() interface{}  { return &m.ID }
+func (m *note) OrmProps() []skyorm.Prop    { return noteStore.Props() }
+func (m *note) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Text, &m.Updated} }
+func (m *note) OrmVals() []interface{}     { return []interface{}{m.ID, m.Text, m.Updated} }
+
+func TestHooksOfWritePaths(t *testing.T) {
+	var calls []string
+	updated := noteStore.Props()[2]
+	postgres.RegisterHooks(noteStore, postgres.StoreHooks{
+		BeforeUpdate: func(ctx context.Context, condition skyorm.Cond, values []skyorm.Val) ([]skyorm.Val, error) {
+			calls = append(calls, "before update")
+			return append(values, skyorm.NewVal(updated, int64(7))), nil
+		},
+		AfterUpdate: func(ctx context.Context, condition skyorm.Cond, values []skyorm.Val) error {
+			calls = append(calls, "after update")
+			return nil
+		},
+		BeforeDelete: func(ctx context.Context, condition skyorm.Cond) error {
+			calls = append(calls, "before delete")
+			return nil
+		},
+		AfterDelete: func(ctx context.Context, condition skyorm.Cond) error {
+			calls = append(calls, "after delete")
+			return nil
+		},
+	})
+	defer postgres.RegisterHooks(noteStore, postgres.StoreHooks{})
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	text := noteStore.Props()[1]
+	mock.ExpectExec(`^UPDATE notes SET text = CASE id WHEN \$1 THEN \$2 ELSE text END, updated = CASE id WHEN \$3 THEN \$4 ELSE updated END WHERE id IN \(\$5\)$`).
+		WithArgs(int64(1), "a", int64(1), int64(7), int64(1)).WillReturnResult(1)
+	if err = p.UpdateMany(ctx, noteStore, []postgres.PkValueSet{{Pk: int64(1), Values: []skyorm.Val{skyorm.NewVal(text, "a")}}}); err != nil {
+		t.Fatal(err)
+	}
+	// update hooks make batch updated by UpdateMany.
+	mock.ExpectExec(`^UPDATE notes SET text = CASE id WHEN \$1 THEN \$2 ELSE text END, updated = CASE id WHEN \$3 THEN \$4 ELSE updated END WHERE id IN \(\$5\)$`).
+		WithArgs(int64(2), "b", int64(2), int64(7), int64(2)).WillReturnResult(1)
+	n, err := p.UpdateBatch(ctx, noteStore, []skyorm.Prop{text}, &note{ID: 2, Text: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("updated %d rows", n)
+	}
+	mock.ExpectExec(`^TRUNCATE notes$`).WillReturnResult(0)
+	if err = p.Truncate(ctx, noteStore, false, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"before update", "after update", "before update", "after update", "before delete", "after delete"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls: %v, want %v", calls, want)
+	}
+}
+
+func TestMergeRejectsReplacedValues(t *testing.T) {
+	postgres.RegisterHooks(noteStore, postgres.StoreHooks{
+		BeforeUpdate: func(ctx context.Context, condition skyorm.Cond, values []skyorm.Val) ([]skyorm.Val, error) {
+			return values[:1], nil
+		},
+	})
+	defer postgres.RegisterHooks(noteStore, postgres.StoreHooks{})
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Merge(context.Background(), noteStore, postgres.MergeSpec{Matched: postgres.MergeUpdate}, &note{ID: 1}); err == nil {
+		t.Fatal("merged values replaced by hook")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
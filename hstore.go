@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/lib/pq/hstore"
+	"github.com/skyorm/skyorm"
+)
+
+// Hstore wraps a pointer to map property stored in hstore column, e.g. of legacy
+// schemas predating jsonb. Map properties are bound and scanned as hstore without
+// it, it's needed only for values passed to other drivers. Nil map is NULL, NULL
+// values of keys are scanned as empty strings.
+func Hstore(m *map[string]string) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	return &hstoreMap{m}
+}
+
+type hstoreMap struct {
+	m *map[string]string
+}
+
+func (h *hstoreMap) Value() (driver.Value, error) {
+	if h.m == nil || *h.m == nil {
+		return nil, nil
+	}
+	return hstoreValue(*h.m).Value()
+}
+
+func (h *hstoreMap) Scan(src interface{}) error {
+	// hstore.Hstore panics on anything but []byte, e.g. strings of other drivers.
+	switch s := src.(type) {
+	case nil, []byte:
+	case string:
+		src = []byte(s)
+	default:
+		return fmt.Errorf("can't scan %T into hstore", src)
+	}
+	var v hstore.Hstore
+	if err := v.Scan(src); err != nil {
+		return err
+	}
+	if v.Map == nil {
+		*h.m = nil
+		return nil
+	}
+	m := make(map[string]string, len(v.Map))
+	for k, s := range v.Map {
+		m[k] = s.String
+	}
+	*h.m = m
+	return nil
+}
+
+func hstoreValue(m map[string]string) hstore.Hstore {
+	v := hstore.Hstore{Map: make(map[string]sql.NullString, len(m))}
+	for k, s := range m {
+		v.Map[k] = sql.NullString{String: s, Valid: true}
+	}
+	return v
+}
+
+// HasKey returns condition matching hstore property having key (?).
+func HasKey(prop skyorm.Prop, key string) skyorm.Cond {
+	return binaryCond(prop, "?", key)
+}
+
+// HasAllKeys returns condition matching hstore property having all keys (?&).
+func HasAllKeys(prop skyorm.Prop, keys ...string) skyorm.Cond {
+	return binaryCond(prop, "?&", keys)
+}
+
+// HasAnyKey returns condition matching hstore property having any of keys (?|).
+func HasAnyKey(prop skyorm.Prop, keys ...string) skyorm.Cond {
+	return binaryCond(prop, "?|", keys)
+}
+
+// HstoreContains returns condition matching hstore property containing all
+// pairs of m (@>).
+func HstoreContains(prop skyorm.Prop, m map[string]string) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, m), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " @> " + placeholder(n) + "::hstore", []interface{}{Hstore(&m)}
+	}}
+}
+
+// HstoreValue returns property of text value of key of hstore property (->),
+// so it can be compared by regular conditions and used in order:
+//
+//	skyorm.Eq(postgres.HstoreValue(prop, "color"), "red")
+func HstoreValue(prop skyorm.Prop, key string) skyorm.Prop {
+	return &exprProp{prop, "(" + quoteColumn(prop.Name()) + " -> " + pq.QuoteLiteral(key) + ")"}
+}
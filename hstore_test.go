@@ -0,0 +1,109 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type profile struct {
+	ID    int64
+	Attrs map[string]string
+}
+
+var profileStore = skyorm.NewStore("profiles", 0, func() skyorm.Model {
+	return &profile{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("attrs", "map[string]string", false),
+)
+
+func (m *profile) OrmStore() skyorm.Store     { return profileStore }
+func (m *profile) OrmPk() interface{}         { return m.ID }
+func (m *profile) OrmPkProp() skyorm.Prop     { return profileStore.Pk() }
+func (m *profile) OrmPkPointer() interface{}  { return &m.ID }
+func (m *profile) OrmProps() []skyorm.Prop    { return profileStore.Props() }
+func (m *profile) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Attrs} }
+func (m *profile) OrmVals() []interface{}     { return []interface{}{m.ID, m.Attrs} }
+
+func TestHstoreRoundTrip(t *testing.T) {
+	for _, m := range []map[string]string{
+		{},
+		{"color": "red"},
+		{`say "hi"`: `C:\temp`, "a=>b": "c, d", "empty": ""},
+	} {
+		v, err := postgres.Hstore(&m).Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var back map[string]string
+		if err = postgres.Hstore(&back).Scan(v); err != nil {
+			t.Fatalf("scan %v: %v", v, err)
+		}
+		if !reflect.DeepEqual(back, m) {
+			t.Fatalf("round trip of %v: %v", m, back)
+		}
+	}
+	var m map[string]string
+	if v, err := postgres.Hstore(&m).Value(); err != nil || v != nil {
+		t.Fatalf("value of nil map %v, %v", v, err)
+	}
+	m = map[string]string{"stale": "x"}
+	if err := postgres.Hstore(&m).Scan(nil); err != nil || m != nil {
+		t.Fatalf("scan of NULL %v, %v", m, err)
+	}
+	if err := postgres.Hstore(&m).Scan(1); err == nil {
+		t.Fatal("scanned int into hstore")
+	}
+	// NULL values are scanned as empty strings, keys are kept.
+	if err := postgres.Hstore(&m).Scan([]byte(`"a"=>NULL, "b"=>"NULL", "c\"d"=>"e\\f"`)); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"a": "", "b": "NULL", `c"d`: `e\f`}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("scanned %v, want %v", m, want)
+	}
+}
+
+func TestHstoreProps(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mock.ExpectQuery(`^INSERT INTO profiles \(id, attrs\) VALUES \(\$1, \$2\) RETURNING id$`).
+		WithArgs(int64(1), `"color"=>"red"`).WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(ctx, &profile{ID: 1, Attrs: map[string]string{"color": "red"}}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, attrs FROM profiles$`).WillReturnRows([]string{"id", "attrs"},
+		[]interface{}{1, `"color"=>"red", "size"=>NULL`}, []interface{}{2, nil})
+	l, err := p.Find(ctx, profileStore, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := l[0].(*profile); !reflect.DeepEqual(m.Attrs, map[string]string{"color": "red", "size": ""}) {
+		t.Fatalf("scanned %+v", m)
+	}
+	if m := l[1].(*profile); m.Attrs != nil {
+		t.Fatalf("scanned %+v", m)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHstoreConds(t *testing.T) {
+	attrs := skyorm.NewProp("attrs", "map[string]string", false)
+	expectFind(t, postgres.HasKey(attrs, "color"), `^SELECT id, name FROM users WHERE attrs \? \$1$`, "color")
+	expectFind(t, postgres.HasAllKeys(attrs, "color", "size"), `^SELECT id, name FROM users WHERE attrs \?& \$1$`, `{"color","size"}`)
+	expectFind(t, postgres.HasAnyKey(attrs, "color", "size"), `^SELECT id, name FROM users WHERE attrs \?\| \$1$`, `{"color","size"}`)
+	expectFind(t, postgres.HstoreContains(attrs, map[string]string{"color": `"red"`}),
+		`^SELECT id, name FROM users WHERE attrs @> \$1::hstore$`, `"color"=>"\"red\""`)
+	expectFind(t, skyorm.Eq(postgres.HstoreValue(attrs, "it's"), "red"),
+		`^SELECT id, name FROM users WHERE \(attrs -> 'it''s'\) = \$1$`, "red")
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+type identityMapKey struct{}
+
+type identityMap struct {
+	mu     sync.Mutex
+	models map[string]skyorm.Model
+}
+
+// WithIdentityMap returns context which reads return the same model instance
+// for the same pk, e.g. for scope of a request or transaction. Models loaded
+// first are kept, so later reads of the scope don't overwrite their changes
+// made in memory. Populate of a known pk copies the known model into the given
+// one without a query. Writes of a store other than Put evict its models.
+func WithIdentityMap(ctx context.Context) context.Context {
+	return context.WithValue(ctx, identityMapKey{}, &identityMap{models: make(map[string]skyorm.Model)})
+}
+
+func identityKey(store string, pk interface{}) string {
+	return fmt.Sprintf("%s\x00%v", store, pk)
+}
+
+// identify returns model of identity map of ctx with pk of m, registering m
+// when there is none.
+func identify(ctx context.Context, m skyorm.Model) skyorm.Model {
+	im, _ := ctx.Value(identityMapKey{}).(*identityMap)
+	if im == nil || isPkEmpty(m.OrmPk()) || projected(ctx, m.OrmStore()) {
+		return m
+	}
+	key := identityKey(m.OrmStore().Name(), m.OrmPk())
+	im.mu.Lock()
+	defer im.mu.Unlock()
+	if known, ok := im.models[key]; ok {
+		return known
+	}
+	im.models[key] = m
+	return m
+}
+
+// forget removes model with pk of m from identity map of ctx, e.g. when it's deleted.
+func forget(ctx context.Context, m skyorm.Model) {
+	im, _ := ctx.Value(identityMapKey{}).(*identityMap)
+	if im == nil {
+		return
+	}
+	im.mu.Lock()
+	delete(im.models, identityKey(m.OrmStore().Name(), m.OrmPk()))
+	im.mu.Unlock()
+}
+
+// forgetStore removes models of the store from identity map of ctx, e.g. when
+// rows of the store are updated or deleted by condition.
+func forgetStore(ctx context.Context, store skyorm.Store) {
+	im, _ := ctx.Value(identityMapKey{}).(*identityMap)
+	if im == nil {
+		return
+	}
+	prefix := identityKey(store.Name(), "")
+	im.mu.Lock()
+	for key := range im.models {
+		if strings.HasPrefix(key, prefix) {
+			delete(im.models, key)
+		}
+	}
+	im.mu.Unlock()
+}
+
+// identifyAll replaces models of the list with the ones of identity map of ctx.
+func identifyAll(ctx context.Context, l []skyorm.Model) []skyorm.Model {
+	if ctx.Value(identityMapKey{}) == nil {
+		return l
+	}
+	for i, m := range l {
+		l[i] = identify(ctx, m)
+	}
+	return l
+}
+
+// populateKnown copies model with pk of identity map of ctx into model and
+// reports whether there is one.
+func populateKnown(ctx context.Context, model skyorm.Model, pk interface{}) bool {
+	im, _ := ctx.Value(identityMapKey{}).(*identityMap)
+	if im == nil {
+		return false
+	}
+	im.mu.Lock()
+	known, ok := im.models[identityKey(model.OrmStore().Name(), pk)]
+	im.mu.Unlock()
+	if !ok {
+		return false
+	}
+	dst, src := reflect.ValueOf(model), reflect.ValueOf(known)
+	if dst == src {
+		return true
+	}
+	if dst.Kind() != reflect.Ptr || src.Type() != dst.Type() {
+		return false
+	}
+	dst.Elem().Set(src.Elem())
+	return true
+}
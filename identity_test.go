@@ -0,0 +1,42 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestIdentityMapForgetsUpdated(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := postgres.WithIdentityMap(context.Background())
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE id = \$1$`).WithArgs(1).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"})
+	mock.ExpectExec(`^UPDATE users SET name = \$1 WHERE id = \$2$`).WithArgs("b", 1).WillReturnResult(1)
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE id = \$1$`).WithArgs(1).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "b"})
+	u := &user{}
+	if err = p.Populate(ctx, u, 1); err != nil {
+		t.Fatal(err)
+	}
+	name := userStore.Props()[1]
+	if err = p.Update(ctx, userStore, skyorm.Eq(userStore.Pk(), 1), skyorm.NewVal(name, "b")); err != nil {
+		t.Fatal(err)
+	}
+	u = &user{}
+	if err = p.Populate(ctx, u, 1); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "b" {
+		t.Fatalf("populated name %q, want b", u.Name)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// IndexSpec is an index of a store.
+type IndexSpec struct {
+	// Name is the index name, table and prop names joined with _idx suffix by default.
+	Name   string
+	Props  []skyorm.Prop
+	Unique bool
+	// Method is the index method, e.g. btree, hash, gin, gist or brin, btree by default.
+	Method string
+	// Partial is the predicate of partial index, e.g. "deleted_at IS NULL".
+	Partial string
+	// Concurrently builds the index without locking writes of the table. It
+	// can't be used inside of a transaction, so the build runs without session
+	// variables and statement timeout of the context.
+	Concurrently bool
+}
+
+// name returns index name of the spec for the unqualified table.
+func (s IndexSpec) name(table string) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	parts := []string{table}
+	for _, prop := range s.Props {
+		if e, ok := prop.(*exprProp); ok {
+			// expressions aren't valid in names, e.g. of EqFold index.
+			parts = append(parts, e.Prop.Name()+"_expr")
+			continue
+		}
+		parts = append(parts, prop.Name())
+	}
+	return strings.Join(parts, "_") + "_idx"
+}
+
+// createSQL returns CREATE INDEX statement of the spec on the quoted table,
+// which may be schema-qualified.
+func (s IndexSpec) createSQL(table string) string {
+	var b strings.Builder
+	b.WriteString("CREATE ")
+	if s.Unique {
+		b.WriteString("UNIQUE ")
+	}
+	b.WriteString("INDEX ")
+	if s.Concurrently {
+		b.WriteString("CONCURRENTLY ")
+	}
+	columns := make([]string, len(s.Props))
+	for i, prop := range s.Props {
+		columns[i] = quoteColumn(prop.Name())
+	}
+	b.WriteString(fmt.Sprintf("IF NOT EXISTS %s ON %s", quoteIdent(s.name(unqualified(table))), table))
+	if s.Method != "" {
+		b.WriteString(" USING " + s.Method)
+	}
+	b.WriteString(" (" + strings.Join(columns, ", ") + ")")
+	if s.Partial != "" {
+		b.WriteString(" WHERE " + s.Partial)
+	}
+	return b.String()
+}
+
+var (
+	indexesMu sync.RWMutex
+	indexes   = make(map[string][]IndexSpec)
+)
+
+// RegisterIndex declares index of the store, so it's created by EnsureIndexes
+// and included in GenerateDDL.
+func RegisterIndex(store skyorm.Store, spec IndexSpec) {
+	indexesMu.Lock()
+	defer indexesMu.Unlock()
+	indexes[store.Name()] = append(indexes[store.Name()], spec)
+}
+
+func storeIndexes(store skyorm.Store) []IndexSpec {
+	indexesMu.RLock()
+	defer indexesMu.RUnlock()
+	return append([]IndexSpec(nil), indexes[store.Name()]...)
+}
+
+// EnsureIndex creates index of the store if it doesn't exist. Index left invalid
+// by a failed concurrent build is dropped and built again.
+func (p *provider) EnsureIndex(ctx context.Context, store skyorm.Store, spec IndexSpec) error {
+	if len(spec.Props) == 0 {
+		return fmt.Errorf("index %s of %s has no props", p.index(ctx, store, spec), store.Name())
+	}
+	if spec.Concurrently {
+		if _, ok := p.conn.(*sql.DB); !ok {
+			return fmt.Errorf("index %s of %s can't be built concurrently in a transaction", p.index(ctx, store, spec), store.Name())
+		}
+		ctx = context.WithValue(ctx, bareKey{}, true)
+		var invalid bool
+		err := p.queryRow(ctx, "SELECT NOT indisvalid FROM pg_index WHERE indexrelid = to_regclass($1)",
+			[]interface{}{p.index(ctx, store, spec)}, &invalid)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if invalid {
+			p.logf(LevelWarn, "INDEX %s IS INVALID, REBUILDING", p.index(ctx, store, spec))
+			if err = p.DropIndex(ctx, store, spec); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := p.exec(ctx, spec.createSQL(p.table(ctx, store.Name())))
+	return err
+}
+
+// EnsureIndexes creates indexes registered for the store which don't exist.
+func (p *provider) EnsureIndexes(ctx context.Context, store skyorm.Store) error {
+	for _, spec := range storeIndexes(store) {
+		if err := p.EnsureIndex(ctx, store, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropIndex drops index of the store if it exists, concurrently when the spec
+// is concurrent.
+func (p *provider) DropIndex(ctx context.Context, store skyorm.Store, spec IndexSpec) error {
+	query := "DROP INDEX "
+	if spec.Concurrently {
+		ctx = context.WithValue(ctx, bareKey{}, true)
+		query += "CONCURRENTLY "
+	}
+	_, err := p.exec(ctx, query+"IF EXISTS "+p.index(ctx, store, spec))
+	return err
+}
+
+// index returns index name of the spec for table of the store resolved by
+// ctx, qualified with schema of the table.
+func (p *provider) index(ctx context.Context, store skyorm.Store, spec IndexSpec) string {
+	schema, table := splitTable(resolveTable(ctx, store.Name()))
+	if schema == "" {
+		schema = Tenant(ctx)
+	}
+	if schema != "" {
+		return quoteIdent(schema) + "." + quoteIdent(spec.name(table))
+	}
+	return quoteIdent(spec.name(table))
+}
+
+// unqualified returns unquoted table part of the table name.
+func unqualified(table string) string {
+	_, t := splitTable(table)
+	return t
+}
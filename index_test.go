@@ -0,0 +1,31 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestEnsureIndexConcurrently(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT NOT indisvalid FROM pg_index WHERE indexrelid = to_regclass\(\$1\)$`).
+		WithArgs("users_2024_name_idx").WillReturnRows([]string{"invalid"})
+	mock.ExpectExec(`^CREATE INDEX CONCURRENTLY IF NOT EXISTS users_2024_name_idx ON users_2024 \(name\)$`).
+		WillReturnResult(0)
+	ctx := postgres.WithTableResolver(context.Background(), postgres.TableSuffix("_2024", "users"))
+	ctx = postgres.WithSessionVars(ctx, map[string]string{"app.user_id": "1"})
+	spec := postgres.IndexSpec{Props: []skyorm.Prop{userStore.Props()[1]}, Concurrently: true}
+	if err = p.EnsureIndex(ctx, userStore, spec); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+// QueryInfo is a query passed through interceptors.
+type QueryInfo struct {
+	// Op is the operation, e.g. OpFind, or command of queries run by helpers, e.g. SELECT.
+	Op    string
+	Query string
+	Args  []interface{}
+}
+
+// Interceptor observes and rewrites queries of provider. BeforeQuery may replace
+// query text and arguments of q and return context passed down the chain, or
+// fail the query with an error without running it. AfterQuery is called for
+// every interceptor which BeforeQuery was called, also when the query failed.
+type Interceptor interface {
+	BeforeQuery(ctx context.Context, q *QueryInfo) (context.Context, error)
+	AfterQuery(ctx context.Context, q *QueryInfo, d time.Duration, err error)
+}
+
+// InterceptorFuncs implements Interceptor with optional functions.
+type InterceptorFuncs struct {
+	Before func(ctx context.Context, q *QueryInfo) (context.Context, error)
+	After  func(ctx context.Context, q *QueryInfo, d time.Duration, err error)
+}
+
+// BeforeQuery calls Before if it's set.
+func (f InterceptorFuncs) BeforeQuery(ctx context.Context, q *QueryInfo) (context.Context, error) {
+	if f.Before == nil {
+		return ctx, nil
+	}
+	return f.Before(ctx, q)
+}
+
+// AfterQuery calls After if it's set.
+func (f InterceptorFuncs) AfterQuery(ctx context.Context, q *QueryInfo, d time.Duration, err error) {
+	if f.After != nil {
+		f.After(ctx, q, d, err)
+	}
+}
+
+// WithInterceptors chains interceptors around every query. BeforeQuery of
+// interceptors is called in order and AfterQuery in reverse order, so the
+// first interceptor wraps all others.
+func WithInterceptors(l ...Interceptor) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, l...)
+	}
+}
+
+// interceptBefore calls BeforeQuery of interceptors and returns the number of
+// interceptors called.
+func (p *provider) interceptBefore(ctx context.Context, q *QueryInfo) (context.Context, int, error) {
+	for i, in := range p.interceptors {
+		next, err := in.BeforeQuery(ctx, q)
+		if err != nil {
+			return ctx, i + 1, err
+		}
+		if next != nil {
+			ctx = next
+		}
+	}
+	return ctx, len(p.interceptors), nil
+}
+
+// interceptAfter calls AfterQuery of the first n interceptors in reverse order.
+func (p *provider) interceptAfter(ctx context.Context, q *QueryInfo, n int, d time.Duration, err error) {
+	for i := n - 1; i >= 0; i-- {
+		p.interceptors[i].AfterQuery(ctx, q, d, err)
+	}
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ColumnDef is a column of introspected table.
+type ColumnDef struct {
+	Name string
+	// Type is the type name, e.g. int8, text or _int4 for arrays of int4.
+	Type     string
+	Nullable bool
+	IsPk     bool
+	// Default is the default expression, empty when there is none.
+	Default string
+}
+
+// TableDef is an introspected table.
+type TableDef struct {
+	Schema  string
+	Name    string
+	Columns []ColumnDef
+}
+
+// Introspect returns tables of the database with their columns in order,
+// system schemas excluded.
+func (p *provider) Introspect(ctx context.Context) ([]TableDef, error) {
+	res, err := p.query(ctx, `SELECT c.table_schema, c.table_name, c.column_name, c.udt_name, c.is_nullable = 'YES', c.column_default,
+	EXISTS (SELECT 1 FROM information_schema.table_constraints tc
+		INNER JOIN information_schema.key_column_usage k ON k.constraint_schema = tc.constraint_schema AND k.constraint_name = tc.constraint_name
+		WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = c.table_schema AND tc.table_name = c.table_name AND k.column_name = c.column_name)
+FROM information_schema.columns c
+INNER JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+WHERE t.table_type = 'BASE TABLE' AND c.table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY c.table_schema, c.table_name, c.ordinal_position`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]TableDef, 0)
+	for res.Next() {
+		var (
+			schema, table string
+			c             ColumnDef
+			def           sql.NullString
+		)
+		if err = res.Scan(&schema, &table, &c.Name, &c.Type, &c.Nullable, &def, &c.IsPk); err != nil {
+			return nil, err
+		}
+		c.Default = def.String
+		if n := len(l); n == 0 || l[n-1].Schema != schema || l[n-1].Name != table {
+			l = append(l, TableDef{Schema: schema, Name: table})
+		}
+		t := &l[len(l)-1]
+		t.Columns = append(t.Columns, c)
+	}
+	return l, res.Err()
+}
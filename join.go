@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Join joins another store to the queried one.
+type Join struct {
+	Kind  string
+	Store skyorm.Store
+	On    skyorm.Cond
+}
+
+// InnerJoin returns INNER JOIN of the store on condition.
+func InnerJoin(store skyorm.Store, on skyorm.Cond) Join {
+	return Join{"INNER", store, on}
+}
+
+// LeftJoin returns LEFT JOIN of the store on condition.
+func LeftJoin(store skyorm.Store, on skyorm.Cond) Join {
+	return Join{"LEFT", store, on}
+}
+
+// Qualify returns prop qualified by the table name of the store, as props of
+// joined stores have to be referenced in join and query conditions. The schema
+// is left out, as resolved tables are aliased by the table name, see
+// WithTableResolver.
+func Qualify(store skyorm.Store, prop skyorm.Prop) skyorm.Prop {
+	return &exprProp{prop, quoteIdent(unqualified(store.Name())) + "." + quoteColumn(prop.Name())}
+}
+
+// EqProp returns condition matching equal values of two props, e.g. in join condition.
+func EqProp(a, b skyorm.Prop) skyorm.Cond {
+	return &exprCond{skyorm.Eq(a, nil), func(n *int) (string, []interface{}) {
+		return quoteColumn(a.Name()) + " = " + quoteColumn(b.Name()), nil
+	}}
+}
+
+// FindJoin finds models of the store joined with other stores, e.g. orders whose
+// customer has a given country. Only props of the store are selected, so models
+// are duplicated when a join matches several rows.
+func (p *provider) FindJoin(ctx context.Context, store skyorm.Store, joins []Join, condition skyorm.Cond, limit, offset int) ([]skyorm.Model, error) {
+	columns, computed := joinedColumns(store, selectedProps(ctx, store), quoteIdent(unqualified(store.Name())))
+	var (
+		b    strings.Builder
+		args = make([]interface{}, 0)
+		n    = newN()
+	)
+	b.WriteString(fmt.Sprintf("SELECT %s FROM %s%s", columns, p.tableAs(ctx, store.Name()), computed))
+	for _, j := range joins {
+		on, v := parseCond(p.bindCond(ctx, j.On), n)
+		b.WriteString(fmt.Sprintf(" %s JOIN %s ON %s", j.Kind, p.tableAs(ctx, j.Store.Name()), on))
+		args = append(args, v...)
+	}
+	query, v := buildWhere(p.bindCond(ctx, condition), strings.Replace(b.String(), "%", "%%", -1), n)
+	args = append(args, v...)
+	query += p.limit(limit, offset)
+	ctx = withOp(ctx, OpFindJoin)
+	return p.findQuery(ctx, store, query, args...)
+}
@@ -0,0 +1,56 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestFindJoinOnPropGroup(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT orders\.id, orders\.user_id, orders\.total FROM orders INNER JOIN users `+
+		`ON \(orders\.user_id = users\.id AND orders\.total = users\.id\) WHERE users\.name = \$1$`).
+		WithArgs("a").WillReturnRows([]string{"id", "user_id", "total"}, []interface{}{1, 2, 3})
+	on := skyorm.And(
+		postgres.EqProp(postgres.Qualify(orderStore, orderStore.Props()[1]), postgres.Qualify(userStore, userStore.Pk())),
+		postgres.EqProp(postgres.Qualify(orderStore, orderStore.Props()[2]), postgres.Qualify(userStore, userStore.Pk())),
+	)
+	cond := skyorm.Eq(postgres.Qualify(userStore, userStore.Props()[1]), "a")
+	l, err := p.FindJoin(context.Background(), orderStore, []postgres.Join{postgres.InnerJoin(userStore, on)}, cond, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 || l[0].(*order).Total != 3 {
+		t.Fatalf("FindJoin() = %v, want order with total 3", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindJoinResolvedSchemaTable(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// props of schema-qualified stores are qualified by the alias of their resolved tables.
+	mock.ExpectQuery(`^SELECT orders\.id, orders\.user_id, orders\.total FROM orders_2024 AS orders ` +
+		`INNER JOIN Billing\.Order_2024 AS "order" ON orders\.user_id = "order"\.id WHERE "order"\."user" = \$1$`).
+		WithArgs("a").WillReturnRows([]string{"id", "user_id", "total"})
+	ctx := postgres.WithTableResolver(context.Background(), postgres.TableSuffix("_2024"))
+	on := postgres.EqProp(postgres.Qualify(orderStore, orderStore.Props()[1]), postgres.Qualify(grantStore, grantStore.Pk()))
+	cond := skyorm.Eq(postgres.Qualify(grantStore, grantStore.Props()[1]), "a")
+	if _, err = p.FindJoin(ctx, orderStore, []postgres.Join{postgres.InnerJoin(grantStore, on)}, cond, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// JSONB wraps a pointer to struct or map property stored in jsonb column.
+// Models return it from OrmPointers and OrmVals, the same way as pq.Array.
+func JSONB(v interface{}) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	return &jsonb{v}
+}
+
+type jsonb struct {
+	v interface{}
+}
+
+func (j *jsonb) Value() (driver.Value, error) {
+	if j.v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (j *jsonb) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, j.v)
+	case string:
+		return json.Unmarshal([]byte(src), j.v)
+	}
+	return fmt.Errorf("can't scan %T into jsonb", src)
+}
+
+// JSONContains returns condition matching jsonb property containing v (@>).
+func JSONContains(prop skyorm.Prop, v interface{}) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, v), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " @> " + placeholder(n) + "::jsonb", []interface{}{JSONB(v)}
+	}}
+}
+
+// JSONHasKey returns condition matching jsonb property having top level key (?).
+func JSONHasKey(prop skyorm.Prop, key string) skyorm.Cond {
+	return binaryCond(prop, "?", key)
+}
+
+// JSONPath returns property of text value at path of jsonb property (#>>, the
+// path form of ->>), so it can be compared by regular conditions and used in order:
+//
+//	skyorm.Eq(postgres.JSONPath(prop, "address", "city"), "Berlin")
+func JSONPath(prop skyorm.Prop, path ...string) skyorm.Prop {
+	return &exprProp{prop, "(" + quoteColumn(prop.Name()) + " #>> " + pq.QuoteLiteral(textArrayLiteral(path)) + ")"}
+}
+
+func textArrayLiteral(l []string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	q := make([]string, len(l))
+	for i, s := range l {
+		q[i] = `"` + r.Replace(s) + `"`
+	}
+	return "{" + strings.Join(q, ",") + "}"
+}
+
+// JSONSet returns update value setting only the value at path of jsonb property
+// with jsonb_set, without rewriting the whole document on the client.
+func JSONSet(prop skyorm.Prop, path []string, v interface{}) skyorm.Val {
+	return &exprVal{prop: prop, val: JSONB(v), expr: func(ph string) string {
+		return "jsonb_set(" + quoteColumn(prop.Name()) + ", " + pq.QuoteLiteral(textArrayLiteral(path)) + ", " + ph + "::jsonb)"
+	}}
+}
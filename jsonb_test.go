@@ -0,0 +1,53 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestJSONBConditions(t *testing.T) {
+	attrs := skyorm.NewProp("attrs", "map[string]interface{}", false)
+	expectFind(t, postgres.JSONContains(attrs, map[string]string{"city": "Berlin"}),
+		`^SELECT id, name FROM users WHERE attrs @> \$1::jsonb$`, `{"city":"Berlin"}`)
+	expectFind(t, postgres.JSONHasKey(attrs, "city"), `^SELECT id, name FROM users WHERE attrs \? \$1$`, "city")
+	expectFind(t, skyorm.Eq(postgres.JSONPath(attrs, "address", "city"), "Berlin"),
+		`^SELECT id, name FROM users WHERE \(attrs #>> '\{"address","city"\}'\) = \$1$`, "Berlin")
+}
+
+func TestJSONBValue(t *testing.T) {
+	in := map[string]int{"a": 1}
+	v, err := postgres.JSONB(&in).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]int
+	if err = postgres.JSONB(&out).Scan([]byte(v.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("scanned %v, want %v", out, in)
+	}
+}
+
+func TestJSONSet(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := skyorm.NewProp("attrs", "map[string]interface{}", false)
+	mock.ExpectExec(`^UPDATE users SET attrs = jsonb_set\(attrs, '\{"address","city"\}', \$1::jsonb\) WHERE id = \$2$`).
+		WithArgs(`"Berlin"`, 1).WillReturnResult(1)
+	err = p.Update(context.Background(), userStore, skyorm.Eq(userStore.Pk(), 1), postgres.JSONSet(attrs, []string{"address", "city"}, "Berlin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
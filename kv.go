@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// KVConfig is a configuration of key-value store.
+type KVConfig struct {
+	// Table is the values table, skyorm_kv by default.
+	Table string
+	// Unlogged creates UNLOGGED table, which is faster to write but is truncated
+	// after crash and isn't replicated.
+	Unlogged bool
+}
+
+// KVEntry is a value of key-value store.
+type KVEntry struct {
+	Key   string
+	Value []byte
+	// ExpiresAt is zero for values without ttl.
+	ExpiresAt time.Time
+}
+
+// KV is a namespaced key-value store backed by postgres table, suitable for
+// feature flags and small configuration blobs.
+type KV struct {
+	p   *provider
+	cfg KVConfig
+}
+
+// KV returns key-value store configured by cfg.
+func (p *provider) KV(cfg KVConfig) *KV {
+	if cfg.Table == "" {
+		cfg.Table = "skyorm_kv"
+	}
+	return &KV{p, cfg}
+}
+
+// CreateTable creates values table if it doesn't exist.
+func (kv *KV) CreateTable(ctx context.Context) error {
+	unlogged := ""
+	if kv.cfg.Unlogged {
+		unlogged = "UNLOGGED "
+	}
+	_, table := splitTable(kv.cfg.Table)
+	queries := []string{
+		fmt.Sprintf(`CREATE %sTABLE IF NOT EXISTS %s (
+	namespace TEXT NOT NULL,
+	key TEXT NOT NULL,
+	value BYTEA,
+	expires_at TIMESTAMPTZ,
+	PRIMARY KEY (namespace, key)
+)`, unlogged, quoteTable(kv.cfg.Table)),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (expires_at) WHERE expires_at IS NOT NULL",
+			quoteIdent(table+"_expires_idx"), quoteTable(kv.cfg.Table)),
+	}
+	for _, query := range queries {
+		if _, err := kv.p.exec(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set stores value of key in namespace, the value expires after ttl unless ttl is zero.
+// Expiration time is computed by the server, which reads compare it with, so
+// clock skew of clients doesn't shorten or extend ttl.
+func (kv *KV) Set(ctx context.Context, namespace, key string, value []byte, ttl time.Duration) error {
+	var interval interface{}
+	if ttl > 0 {
+		interval = strconv.FormatInt(ttl.Microseconds(), 10) + " microseconds"
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (namespace, key, value, expires_at) VALUES ($1, $2, $3, now() + $4::interval)
+ON CONFLICT (namespace, key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at`, quoteTable(kv.cfg.Table))
+	_, err := kv.p.exec(ctx, query, namespace, key, value, interval)
+	return err
+}
+
+// Get returns value of key in namespace or ErrNotFound error when the key doesn't
+// exist or expired.
+func (kv *KV) Get(ctx context.Context, namespace, key string) ([]byte, error) {
+	query := fmt.Sprintf("SELECT value FROM %s WHERE namespace = $1 AND key = $2 AND (expires_at IS NULL OR expires_at > now())", quoteTable(kv.cfg.Table))
+	var value []byte
+	if err := kv.p.queryRow(ctx, query, []interface{}{namespace, key}, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Delete removes key from namespace.
+func (kv *KV) Delete(ctx context.Context, namespace, key string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE namespace = $1 AND key = $2", quoteTable(kv.cfg.Table))
+	_, err := kv.p.exec(ctx, query, namespace, key)
+	return err
+}
+
+// List returns unexpired entries of namespace which keys start with prefix, ordered by key.
+func (kv *KV) List(ctx context.Context, namespace, prefix string) ([]KVEntry, error) {
+	query := fmt.Sprintf(`SELECT key, value, expires_at FROM %s
+WHERE namespace = $1 AND left(key, length($2)) = $2 AND (expires_at IS NULL OR expires_at > now()) ORDER BY key`, quoteTable(kv.cfg.Table))
+	res, err := kv.p.query(ctx, query, namespace, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]KVEntry, 0)
+	for res.Next() {
+		var (
+			e         KVEntry
+			expiresAt sql.NullTime
+		)
+		if err = res.Scan(&e.Key, &e.Value, &expiresAt); err != nil {
+			return nil, err
+		}
+		e.ExpiresAt = expiresAt.Time
+		l = append(l, e)
+	}
+	return l, res.Err()
+}
+
+// Cleanup deletes expired entries and returns their number. Expired entries are
+// invisible to reads, so cleanup only reclaims space.
+func (kv *KV) Cleanup(ctx context.Context) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= now()", quoteTable(kv.cfg.Table))
+	res, err := kv.p.exec(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunCleanup deletes expired entries every interval until ctx is done. It
+// returns error of ctx once it's done, or at once when interval isn't positive.
+func (kv *KV) RunCleanup(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid cleanup interval %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		if n, err := kv.Cleanup(ctx); err != nil {
+			if ctx.Err() == nil {
+				kv.p.logf(LevelError, "KV CLEANUP ERROR: %v", err)
+			}
+		} else if n > 0 {
+			kv.p.logf(LevelDebug, "KV CLEANUP: %d expired entries deleted", n)
+		}
+	}
+}
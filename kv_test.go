@@ -0,0 +1,33 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestKVSetExpiresOnServerClock(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv := p.KV(postgres.KVConfig{})
+	mock.ExpectExec(`^INSERT INTO skyorm_kv \(namespace, key, value, expires_at\) VALUES \(\$1, \$2, \$3, now\(\) \+ \$4::interval\)`).
+		WithArgs("ns", "k", []byte("v"), "1500000 microseconds").WillReturnResult(1)
+	if err = kv.Set(context.Background(), "ns", "k", []byte("v"), 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^INSERT INTO skyorm_kv`).WithArgs("ns", "k", []byte("v"), nil).WillReturnResult(1)
+	if err = kv.Set(context.Background(), "ns", "k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = kv.RunCleanup(context.Background(), 0); err == nil {
+		t.Fatal("ran cleanup without interval")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
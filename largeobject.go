@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"io"
+)
+
+// LargeObjects streams large objects, e.g. file blobs, in chunks with lo_get and
+// lo_put, so they are never buffered fully in memory. Chunks are written by
+// separate statements, so objects written outside of a transaction may be
+// left partially written by errors. Models keep oid of their objects.
+type LargeObjects struct {
+	p     *provider
+	chunk int
+}
+
+// LargeObjects returns large objects read and written in chunks of chunkSize
+// bytes, 1MB by default.
+func (p *provider) LargeObjects(chunkSize int) *LargeObjects {
+	if chunkSize <= 0 {
+		chunkSize = 1 << 20
+	}
+	return &LargeObjects{p, chunkSize}
+}
+
+// Create creates an empty large object and returns its oid.
+func (lo *LargeObjects) Create(ctx context.Context) (uint32, error) {
+	var oid uint32
+	err := lo.p.queryRow(ctx, "SELECT lo_create(0)", nil, &oid)
+	return oid, err
+}
+
+// Store creates a large object with contents of r and returns its oid, the
+// object is removed when r fails.
+func (lo *LargeObjects) Store(ctx context.Context, r io.Reader) (uint32, error) {
+	oid, err := lo.Create(ctx)
+	if err != nil {
+		return 0, err
+	}
+	w := lo.Writer(ctx, oid)
+	if _, err = io.Copy(w, r); err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		if uerr := lo.Unlink(ctx, oid); uerr != nil {
+			lo.p.logf(LevelError, "LARGE OBJECT %d UNLINK ERROR: %v", oid, uerr)
+		}
+		return 0, err
+	}
+	return oid, nil
+}
+
+// Reader returns reader of contents of the large object.
+func (lo *LargeObjects) Reader(ctx context.Context, oid uint32) io.Reader {
+	return &loReader{lo: lo, ctx: ctx, oid: oid}
+}
+
+// Writer returns writer of contents of the large object from its start, which
+// must be closed to write the last chunk.
+func (lo *LargeObjects) Writer(ctx context.Context, oid uint32) io.WriteCloser {
+	return &loWriter{lo: lo, ctx: ctx, oid: oid, buf: make([]byte, 0, lo.chunk)}
+}
+
+// Unlink removes the large object.
+func (lo *LargeObjects) Unlink(ctx context.Context, oid uint32) error {
+	var n int
+	return lo.p.queryRow(ctx, "SELECT lo_unlink($1)", []interface{}{oid}, &n)
+}
+
+type loReader struct {
+	lo     *LargeObjects
+	ctx    context.Context
+	oid    uint32
+	offset int64
+	buf    []byte
+	eof    bool
+}
+
+func (r *loReader) Read(b []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+		if err := r.lo.p.queryRow(r.ctx, "SELECT lo_get($1, $2, $3)",
+			[]interface{}{r.oid, r.offset, r.lo.chunk}, &r.buf); err != nil {
+			return 0, err
+		}
+		r.offset += int64(len(r.buf))
+		r.eof = len(r.buf) < r.lo.chunk
+		if len(r.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+type loWriter struct {
+	lo     *LargeObjects
+	ctx    context.Context
+	oid    uint32
+	offset int64
+	buf    []byte
+}
+
+func (w *loWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], b)
+		w.buf = w.buf[:len(w.buf)+n]
+		b = b[n:]
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *loWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	if _, err := w.lo.p.exec(w.ctx, "SELECT lo_put($1, $2, $3)", w.oid, w.offset, w.buf); err != nil {
+		return err
+	}
+	w.offset += int64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+func (w *loWriter) Close() error {
+	return w.flush()
+}
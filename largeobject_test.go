@@ -0,0 +1,105 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+const loGet = `^SELECT lo_get\(\$1, \$2, \$3\)$`
+
+func TestLargeObjectReader(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lo := p.LargeObjects(4)
+	for _, tt := range []struct {
+		contents string
+		chunks   []string
+	}{
+		// a short chunk is the last one.
+		{"abcdefghij", []string{"abcd", "efgh", "ij"}},
+		// an empty chunk follows a full last one.
+		{"abcdefgh", []string{"abcd", "efgh", ""}},
+		{"", []string{""}},
+	} {
+		for i, c := range tt.chunks {
+			mock.ExpectQuery(loGet).WithArgs(int64(7), int64(4*i), int64(4)).
+				WillReturnRows([]string{"lo_get"}, []interface{}{[]byte(c)})
+		}
+		b, err := io.ReadAll(lo.Reader(ctx, 7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.contents {
+			t.Errorf("read %q, want %q", b, tt.contents)
+		}
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLargeObjectStore(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lo := p.LargeObjects(4)
+	mock.ExpectQuery(`^SELECT lo_create\(0\)$`).WillReturnRows([]string{"lo_create"}, []interface{}{int64(7)})
+	for i, c := range []string{"abcd", "efgh"} {
+		mock.ExpectExec(`^SELECT lo_put\(\$1, \$2, \$3\)$`).WithArgs(int64(7), int64(4*i), []byte(c)).WillReturnResult(1)
+	}
+	// the rest is written on Close.
+	mock.ExpectExec(`^SELECT lo_put\(\$1, \$2, \$3\)$`).WithArgs(int64(7), int64(8), []byte("ij")).WillReturnResult(1)
+	oid, err := lo.Store(ctx, strings.NewReader("abcdefghij"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oid != 7 {
+		t.Errorf("oid %d, want 7", oid)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing is left to write by Close of full chunks.
+	mock.ExpectExec(`^SELECT lo_put\(\$1, \$2, \$3\)$`).WithArgs(int64(7), int64(0), []byte("abcd")).WillReturnResult(1)
+	w := lo.Writer(ctx, 7)
+	if _, err = io.Copy(w, bytes.NewReader([]byte("abcd"))); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the object is removed when the reader fails.
+	failed := errors.New("failed")
+	mock.ExpectQuery(`^SELECT lo_create\(0\)$`).WillReturnRows([]string{"lo_create"}, []interface{}{int64(8)})
+	mock.ExpectQuery(`^SELECT lo_unlink\(\$1\)$`).WithArgs(int64(8)).WillReturnRows([]string{"lo_unlink"}, []interface{}{int64(1)})
+	if _, err = lo.Store(ctx, io.MultiReader(strings.NewReader("ab"), errReader{failed})); !errors.Is(err, failed) {
+		t.Errorf("error %v, want %v", err, failed)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
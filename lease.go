@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Lease is a resource owned by a worker until the lease expires.
+type Lease struct {
+	Resource  string
+	Owner     string
+	Heartbeat time.Time
+	ExpiresAt time.Time
+}
+
+// Leases grants time limited ownership of resources to workers, owners keep
+// their leases alive with heartbeats and expired leases are reaped by others.
+type Leases struct {
+	p     *provider
+	table string
+	ttl   time.Duration
+}
+
+// Leases returns leases persisted into the table, skyorm_leases by default,
+// which expire after ttl without heartbeat, 30s by default. Leases can't be
+// kept alive by heartbeats in less than a millisecond, so shorter ttls fail.
+func (p *provider) Leases(table string, ttl time.Duration) (*Leases, error) {
+	if table == "" {
+		table = "skyorm_leases"
+	}
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	if ttl < time.Millisecond {
+		return nil, fmt.Errorf("invalid lease ttl %s", ttl)
+	}
+	return &Leases{p, table, ttl}, nil
+}
+
+// CreateTable creates leases table if it doesn't exist.
+func (l *Leases) CreateTable(ctx context.Context) error {
+	_, table := splitTable(l.table)
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	resource TEXT PRIMARY KEY,
+	owner TEXT NOT NULL,
+	heartbeat TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL
+)`, quoteTable(l.table)),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (owner)", quoteIdent(table+"_owner_idx"), quoteTable(l.table)),
+	}
+	for _, query := range queries {
+		if _, err := l.p.exec(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Acquire takes the lease of resource for owner and reports whether it succeeded.
+// It succeeds when the resource is free, its lease expired or owner already holds it.
+func (l *Leases) Acquire(ctx context.Context, resource, owner string) (bool, error) {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (resource, owner, heartbeat, expires_at)
+VALUES ($1, $2, now(), now() + $3 * interval '1 microsecond')
+ON CONFLICT (resource) DO UPDATE SET owner = EXCLUDED.owner, heartbeat = EXCLUDED.heartbeat, expires_at = EXCLUDED.expires_at
+WHERE %[1]s.owner = EXCLUDED.owner OR %[1]s.expires_at <= now()
+RETURNING resource`, quoteTable(l.table))
+	var r string
+	err := l.p.queryRow(ctx, query, []interface{}{resource, owner, l.ttl.Microseconds()}, &r)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Heartbeat extends all unexpired leases of owner and returns the number of them.
+// Leases which expired before the heartbeat are lost, even if not reaped yet.
+func (l *Leases) Heartbeat(ctx context.Context, owner string) (int64, error) {
+	query := fmt.Sprintf(`UPDATE %s SET heartbeat = now(), expires_at = now() + $1 * interval '1 microsecond'
+WHERE owner = $2 AND expires_at > now()`, quoteTable(l.table))
+	res, err := l.p.exec(ctx, query, l.ttl.Microseconds(), owner)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Release gives up the lease of resource held by owner.
+func (l *Leases) Release(ctx context.Context, resource, owner string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE resource = $1 AND owner = $2", quoteTable(l.table))
+	_, err := l.p.exec(ctx, query, resource, owner)
+	return err
+}
+
+// Get returns the current lease of resource, expired or not, or ErrNotFound error.
+func (l *Leases) Get(ctx context.Context, resource string) (*Lease, error) {
+	query := fmt.Sprintf("SELECT resource, owner, heartbeat, expires_at FROM %s WHERE resource = $1", quoteTable(l.table))
+	ls := &Lease{}
+	err := l.p.queryRow(ctx, query, []interface{}{resource}, &ls.Resource, &ls.Owner, &ls.Heartbeat, &ls.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return ls, nil
+}
+
+// Reap reassigns up to limit expired leases to owner and returns them with their
+// previous owners, so the new owner can take over their work. Concurrent reapers
+// never reassign the same lease.
+func (l *Leases) Reap(ctx context.Context, owner string, limit int) ([]Lease, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET owner = $1, heartbeat = now(), expires_at = now() + $2 * interval '1 microsecond'
+FROM (SELECT resource, owner FROM %[1]s WHERE expires_at <= now() ORDER BY expires_at LIMIT $3 FOR UPDATE SKIP LOCKED) expired
+WHERE %[1]s.resource = expired.resource
+RETURNING %[1]s.resource, expired.owner, %[1]s.heartbeat, %[1]s.expires_at`, quoteTable(l.table))
+	res, err := l.p.query(ctx, query, owner, l.ttl.Microseconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	leases := make([]Lease, 0)
+	for res.Next() {
+		var ls Lease
+		if err = res.Scan(&ls.Resource, &ls.Owner, &ls.Heartbeat, &ls.ExpiresAt); err != nil {
+			return nil, err
+		}
+		leases = append(leases, ls)
+	}
+	return leases, res.Err()
+}
+
+// Keep sends heartbeats of owner every third of lease ttl until ctx is done.
+// lost, if not nil, is called after a heartbeat which extended no lease.
+func (l *Leases) Keep(ctx context.Context, owner string, lost func()) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := l.Heartbeat(ctx, owner)
+		if err != nil {
+			if ctx.Err() == nil {
+				l.p.logf(LevelError, "LEASE HEARTBEAT %s ERROR: %v", owner, err)
+			}
+			continue
+		}
+		if n == 0 && lost != nil {
+			lost()
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package postgres_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestLeasesRejectShortTTL(t *testing.T) {
+	p, _, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Leases("", time.Nanosecond); err == nil {
+		t.Fatal("leases of 1ns ttl")
+	}
+	if _, err = p.Leases("", 0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,373 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Level is a log level.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	// LevelOff disables logging when used as minimal or operation level.
+	LevelOff
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+	LevelOff:   "OFF",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Operations of provider, used to configure per operation behaviour.
+const (
+	OpPut              = "PUT"
+	OpPopulate         = "POPULATE"
+	OpFind             = "FIND"
+	OpFindOne          = "FIND ONE"
+	OpFindJoin         = "FIND JOIN"
+	OpUpdate           = "UPDATE"
+	OpUpdateMany       = "UPDATE MANY"
+	OpDelete           = "DELETE"
+	OpCount            = "COUNT"
+	OpExists           = "EXISTS"
+	OpNearestNeighbors = "NEAREST NEIGHBORS"
+	OpRollup           = "ROLLUP"
+	OpAssociate        = "ASSOCIATE"
+	OpDissociate       = "DISSOCIATE"
+	OpFindRelated      = "FIND RELATED"
+	OpEnqueue          = "ENQUEUE"
+	OpDequeue          = "DEQUEUE"
+	OpFindDescendants  = "FIND DESCENDANTS"
+	OpFindAncestors    = "FIND ANCESTORS"
+	OpTimeBuckets      = "TIME BUCKETS"
+	OpMerge            = "MERGE"
+)
+
+// LeveledLogger receives provider log messages with their levels.
+type LeveledLogger interface {
+	Log(level Level, msg string)
+}
+
+// PrintfLogger adapts skyorm.Logger, prefixing messages with level.
+func PrintfLogger(l skyorm.Logger) LeveledLogger {
+	return printfLogger{l}
+}
+
+type printfLogger struct {
+	l skyorm.Logger
+}
+
+func (l printfLogger) Log(level Level, msg string) {
+	if level == LevelDebug {
+		l.l.Printf("%s\n", msg)
+		return
+	}
+	l.l.Printf("%s %s\n", level, msg)
+}
+
+// FormatLogger adapts loggers with formatting level methods, such as
+// zap.SugaredLogger and logrus.Logger or logrus.Entry.
+func FormatLogger(l interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}) LeveledLogger {
+	return LeveledLoggerFunc(func(level Level, msg string) {
+		switch level {
+		case LevelDebug:
+			l.Debugf("%s", msg)
+		case LevelInfo:
+			l.Infof("%s", msg)
+		case LevelWarn:
+			l.Warnf("%s", msg)
+		case LevelError:
+			l.Errorf("%s", msg)
+		}
+	})
+}
+
+// StructuredLogger adapts loggers with message level methods, such as slog.Logger.
+func StructuredLogger(l interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}) LeveledLogger {
+	return LeveledLoggerFunc(func(level Level, msg string) {
+		switch level {
+		case LevelDebug:
+			l.Debug(msg)
+		case LevelInfo:
+			l.Info(msg)
+		case LevelWarn:
+			l.Warn(msg)
+		case LevelError:
+			l.Error(msg)
+		}
+	})
+}
+
+// LeveledLoggerFunc is a function implementing LeveledLogger.
+type LeveledLoggerFunc func(level Level, msg string)
+
+// Log calls f.
+func (f LeveledLoggerFunc) Log(level Level, msg string) {
+	f(level, msg)
+}
+
+// MultiLogger returns logger sending messages to every logger.
+func MultiLogger(loggers ...LeveledLogger) LeveledLogger {
+	return LeveledLoggerFunc(func(level Level, msg string) {
+		for _, l := range loggers {
+			l.Log(level, msg)
+		}
+	})
+}
+
+// QueryEvent describes an executed query.
+type QueryEvent struct {
+	// Op is the operation, e.g. OpFind, or command of queries run by helpers, e.g. SELECT.
+	Op    string
+	Query string
+	// Args are query arguments, values of redacted columns are replaced with "[REDACTED]".
+	Args     []interface{}
+	Duration time.Duration
+	// Rows is the number of affected or returned rows, -1 when it's unknown.
+	Rows int64
+	Err  error
+	// Meta is operation meta of the context.
+	Meta map[string]string
+}
+
+// QueryHook receives executed queries with levels they are logged at.
+type QueryHook func(ctx context.Context, level Level, e QueryEvent)
+
+// WithLogger replaces logger passed to New with leveled logger.
+func WithLogger(l LeveledLogger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithLogLevel sets minimal level of logged messages. Queries are logged at
+// debug level, so WithLogLevel(LevelInfo) silences query logging.
+func WithLogLevel(level Level) Option {
+	return func(o *options) {
+		o.logLevel = level
+	}
+}
+
+// WithOpLogLevel overrides level of queries logged by the operation, e.g. OpFind.
+func WithOpLogLevel(op string, level Level) Option {
+	return func(o *options) {
+		if o.opLogLevels == nil {
+			o.opLogLevels = make(map[string]Level)
+		}
+		o.opLogLevels[op] = level
+	}
+}
+
+// WithQueryHook makes provider send executed queries to h, in addition to the
+// logger. Hook receives only queries of enabled levels.
+func WithQueryHook(h QueryHook) Option {
+	return func(o *options) {
+		o.queryHook = h
+	}
+}
+
+// WithSlowQueryThreshold makes queries taking longer than d logged at warn level.
+func WithSlowQueryThreshold(d time.Duration) Option {
+	return func(o *options) {
+		o.slowQuery = d
+	}
+}
+
+// WithSlowQueryExplain makes queries exceeding slow query threshold explained
+// and their plans logged at warn level, so bad plans can be diagnosed without
+// reconstructing queries by hand. Slow queries are explained at most once a
+// second, on the database or in the transaction which ran them.
+func WithSlowQueryExplain() Option {
+	return func(o *options) {
+		o.explainSlow = true
+	}
+}
+
+// WithRedactedColumns makes query hook receive values bound to the columns,
+// such as password or token, redacted. Values are matched to columns by the
+// statements binding them, such as inserts, updates and conditions over props.
+// All values of statements which args are replaced by interceptors are redacted
+// when any of them was bound to a redacted column.
+func WithRedactedColumns(columns ...string) Option {
+	return func(o *options) {
+		if o.redacted == nil {
+			o.redacted = make(map[string]bool)
+		}
+		for _, c := range columns {
+			o.redacted[strings.ToLower(c)] = true
+		}
+	}
+}
+
+func (p *provider) logf(level Level, format string, v ...interface{}) {
+	if level < p.logLevel || level == LevelOff {
+		return
+	}
+	p.logger.Log(level, fmt.Sprintf(format, v...))
+}
+
+// logQuery logs executed query at the level of its operation, warn level when
+// it's slow and error level when it failed.
+func (p *provider) logQuery(ctx context.Context, query string, args []interface{}, columns []string, d time.Duration, rows int64, err error) {
+	op := queryOp(ctx, query)
+	level, ok := p.opLogLevels[op]
+	if !ok {
+		level = LevelDebug
+	}
+	if p.slowQuery > 0 && d >= p.slowQuery && level < LevelWarn {
+		level = LevelWarn
+	}
+	if err != nil && err != sql.ErrNoRows {
+		level = LevelError
+	}
+	if level < p.logLevel || level == LevelOff {
+		return
+	}
+	if p.queryHook != nil {
+		p.queryHook(ctx, level, QueryEvent{
+			Op:       op,
+			Query:    query,
+			Args:     redactArgs(args, columns, p.redacted),
+			Duration: d,
+			Rows:     rows,
+			Err:      err,
+			Meta:     OpMeta(ctx),
+		})
+	}
+	msg := fmt.Sprintf("%s QUERY: %s (%s", op, query, d)
+	if rows >= 0 {
+		msg += fmt.Sprintf(", %d rows", rows)
+	}
+	msg += ")"
+	if meta := formatOpMeta(ctx); meta != "" {
+		msg += " [" + meta + "]"
+	}
+	if err != nil && err != sql.ErrNoRows {
+		msg += ": " + err.Error()
+	}
+	p.logger.Log(level, msg)
+}
+
+// columnArg is a query argument bound to the column, which builders of queries
+// mark arguments with, so values of redacted columns are known by position
+// rather than by parsing the query. run binds the value only.
+type columnArg struct {
+	column string
+	val    interface{}
+}
+
+// bindColumn marks v as bound to the column of prop.
+func bindColumn(prop skyorm.Prop, v interface{}) interface{} {
+	return columnArg{prop.Name(), v}
+}
+
+// bindColumns marks values as bound to the columns of props of the same positions.
+func bindColumns(props []skyorm.Prop, values []interface{}) []interface{} {
+	l := make([]interface{}, len(values))
+	for i, v := range values {
+		l[i] = bindColumn(props[i], v)
+	}
+	return l
+}
+
+// argValue returns value of the argument, which may be bound to a column.
+func argValue(v interface{}) interface{} {
+	if a, ok := v.(columnArg); ok {
+		return a.val
+	}
+	return v
+}
+
+// unbindColumns returns values of args and columns they are bound to, columns
+// are nil when no argument is bound to a column.
+func unbindColumns(args []interface{}) ([]interface{}, []string) {
+	var columns []string
+	for i, v := range args {
+		a, ok := v.(columnArg)
+		if !ok {
+			continue
+		}
+		if columns == nil {
+			columns = make([]string, len(args))
+			args = append([]interface{}(nil), args...)
+		}
+		args[i], columns[i] = a.val, a.column
+	}
+	return args, columns
+}
+
+// rebindColumns returns columns of args rewritten by interceptors. Columns of
+// args replaced with another slice are unknown, so every rewritten arg is bound
+// to a redacted column when any of the original ones was.
+func (p *provider) rebindColumns(args, rewritten []interface{}, columns []string) []string {
+	if len(columns) == 0 || len(args) == len(rewritten) && (len(args) == 0 || &args[0] == &rewritten[0]) {
+		return columns
+	}
+	for _, c := range columns {
+		if p.redacted[strings.ToLower(c)] {
+			l := make([]string, len(rewritten))
+			for i := range l {
+				l[i] = c
+			}
+			return l
+		}
+	}
+	return nil
+}
+
+// redactArgs returns copy of args with values bound to redacted columns replaced.
+func redactArgs(args []interface{}, columns []string, redacted map[string]bool) []interface{} {
+	if len(redacted) == 0 || len(columns) == 0 {
+		return args
+	}
+	l := append([]interface{}(nil), args...)
+	for i := 0; i < len(l) && i < len(columns); i++ {
+		if redacted[strings.ToLower(columns[i])] {
+			l[i] = "[REDACTED]"
+		}
+	}
+	return l
+}
+
+type opKey struct{}
+
+// withOp returns context carrying the operation, so queries run with it are
+// logged and measured as queries of the operation.
+func withOp(ctx context.Context, op string) context.Context {
+	return context.WithValue(ctx, opKey{}, op)
+}
+
+// queryOp returns operation of ctx, or command of the query run by helpers
+// without an operation, e.g. SELECT.
+func queryOp(ctx context.Context, query string) string {
+	if op, ok := ctx.Value(opKey{}).(string); ok {
+		return op
+	}
+	return sqlOperation(query)
+}
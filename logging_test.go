@@ -0,0 +1,97 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+func TestWithRedactedColumns(t *testing.T) {
+	var args [][]interface{}
+	p, mock, err := postgrestest.NewMock(
+		postgres.WithRedactedColumns("Name"),
+		postgres.WithQueryHook(func(ctx context.Context, level postgres.Level, e postgres.QueryEvent) {
+			args = append(args, e.Args)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	name := userStore.Props()[1]
+	mock.ExpectQuery(`^INSERT INTO users`).WithArgs(int64(1), "secret").WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(ctx, &user{ID: 1, Name: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^UPDATE users SET name = CASE id WHEN \$1 THEN \$2 WHEN \$3 THEN \$4 ELSE name END WHERE id IN \(\$5, \$6\)$`).
+		WillReturnResult(2)
+	if err = p.UpdateMany(ctx, userStore, []postgres.PkValueSet{
+		{Pk: int64(1), Values: []skyorm.Val{skyorm.NewVal(name, "a")}},
+		{Pk: int64(2), Values: []skyorm.Val{skyorm.NewVal(name, "b")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`server_version_num`).WillReturnRows([]string{"v"}, []interface{}{170000})
+	mock.ExpectQuery(`^SELECT attname`).WillReturnRows([]string{"attname", "type"},
+		[]interface{}{"id", "bigint"}, []interface{}{"name", "text"})
+	mock.ExpectQuery(`^WITH m AS \(MERGE INTO users AS t USING \(VALUES \(\$1::bigint, \$2::text\), \(\$3::bigint, \$4::text\)\)`).
+		WillReturnRows([]string{"count"}, []interface{}{int64(2)})
+	if _, err = p.Merge(ctx, userStore, postgres.MergeSpec{Matched: postgres.MergeUpdate},
+		&user{ID: 1, Name: "a"}, &user{ID: 2, Name: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE \(name = \$1 AND id > \$2\)$`).
+		WillReturnRows([]string{"id", "name"})
+	if _, err = p.Find(ctx, userStore, skyorm.And(skyorm.Eq(name, "a"), skyorm.Gt(userStore.Pk(), 0)), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	r := "[REDACTED]"
+	want := [][]interface{}{
+		{int64(1), r},
+		{int64(1), r, int64(2), r, int64(1), int64(2)},
+		nil,
+		{"users"},
+		{int64(1), r, int64(2), r},
+		{r, 0},
+	}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("args: %v, want %v", args, want)
+	}
+}
+
+func TestRedactedColumnsOfRewrittenArgs(t *testing.T) {
+	var args []interface{}
+	p, mock, err := postgrestest.NewMock(
+		postgres.WithRedactedColumns("name"),
+		postgres.WithInterceptors(postgres.InterceptorFuncs{Before: func(ctx context.Context, q *postgres.QueryInfo) (context.Context, error) {
+			q.Query = "/* tenant $1 */ " + q.Query
+			q.Args = append([]interface{}{"acme"}, q.Args...)
+			return ctx, nil
+		}}),
+		postgres.WithQueryHook(func(ctx context.Context, level postgres.Level, e postgres.QueryEvent) {
+			args = e.Args
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`INSERT INTO users`).WithArgs("acme", int64(1), "secret").WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(context.Background(), &user{ID: 1, Name: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	// positions of args moved, so none of them is logged in the clear.
+	r := "[REDACTED]"
+	if want := []interface{}{r, r, r}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("args: %v, want %v", args, want)
+	}
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// ErrReadOnlyStore is returned by writes of models of read-only stores, e.g.
+// materialized views.
+var ErrReadOnlyStore = errors.New("store is read-only")
+
+var (
+	matViewsMu sync.RWMutex
+	matViews   = make(map[string]bool)
+)
+
+// RegisterMaterializedView marks the store as a materialized view, so its models
+// are found and counted as usual, but writes fail with ErrReadOnlyStore. They are
+// updated by RefreshMaterializedView.
+func RegisterMaterializedView(store skyorm.Store) {
+	matViewsMu.Lock()
+	defer matViewsMu.Unlock()
+	matViews[store.Name()] = true
+}
+
+func isMaterializedView(store skyorm.Store) bool {
+	if v, ok := storeView(store); ok {
+		return v.Materialized
+	}
+	matViewsMu.RLock()
+	defer matViewsMu.RUnlock()
+	return matViews[store.Name()]
+}
+
+// checkWritable returns ErrReadOnlyStore for stores of views.
+func checkWritable(store skyorm.Store) error {
+	if _, ok := storeView(store); ok || isMaterializedView(store) {
+		return fmt.Errorf("%w: %s is a view", ErrReadOnlyStore, store.Name())
+	}
+	return nil
+}
+
+// RefreshMaterializedView refreshes materialized view of the store. Concurrent
+// refresh doesn't block finds, but needs a unique index of the view.
+func (p *provider) RefreshMaterializedView(ctx context.Context, store skyorm.Store, concurrently bool) error {
+	query := "REFRESH MATERIALIZED VIEW "
+	if concurrently {
+		query += "CONCURRENTLY "
+	}
+	if _, err := p.exec(ctx, query+p.table(ctx, store.Name())); err != nil {
+		return err
+	}
+	if dryRun(ctx) {
+		return nil
+	}
+	p.invalidateCache(store)
+	return nil
+}
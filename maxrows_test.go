@@ -0,0 +1,34 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestFindTruncated(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithMaxRows(2, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, name FROM users LIMIT 3 OFFSET 0$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"}, []interface{}{2, "b"}, []interface{}{3, "c"})
+	l, err := p.Find(context.Background(), userStore, nil, 0, 0)
+	if !errors.Is(err, postgres.ErrRowsTruncated) {
+		t.Fatalf("Find() error = %v, want ErrRowsTruncated", err)
+	}
+	if len(l) != 2 {
+		t.Fatalf("found %d users, want 2", len(l))
+	}
+	mock.ExpectQuery(`^SELECT id, name FROM users LIMIT 1 OFFSET 1$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{2, "b"})
+	if _, err = p.Find(context.Background(), userStore, nil, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,356 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// ErrMergeUnsupported is returned by Merge of specs which can't fall back to
+// INSERT ... ON CONFLICT, UPDATE or DELETE on servers before PostgreSQL 15.
+var ErrMergeUnsupported = errors.New("merge is not supported by server")
+
+// MergeAction is an action applied by Merge to rows matching source models.
+type MergeAction int
+
+const (
+	MergeNothing MergeAction = iota
+	MergeUpdate
+	MergeDelete
+)
+
+// MergeSpec tells how Merge syncs source models into rows of their store.
+type MergeSpec struct {
+	// On are props matching source models to rows, the pk by default. Fallback
+	// to ON CONFLICT needs unique index on them.
+	On []skyorm.Prop
+	// Matched is the action applied to matching rows.
+	Matched MergeAction
+	// Update are props updated by MergeUpdate, all props except On and the pk
+	// by default.
+	Update []skyorm.Prop
+	// Insert inserts models without matching rows, with or without their pks
+	// by pk strategy of the store like Put. Models have to omit their pks alike.
+	Insert bool
+}
+
+// Merge syncs models of the store into its rows with MERGE on PostgreSQL 17+,
+// falling back to INSERT ... ON CONFLICT, UPDATE ... FROM or DELETE ... USING
+// on older servers, and returns number of affected rows. Specs both inserting
+// and deleting need MERGE of PostgreSQL 15 or 16, which rows affected aren't
+// reported by lib/pq, so Merge returns 0 for them there. BeforeInsert hooks run
+// for models when Insert is set, and update or delete hooks of the store run for
+// every model with condition matching it by On props, as rows matched aren't
+// reported. Values replaced by BeforeUpdate hooks can't be merged and fail Merge.
+// AfterInsert hooks don't run, as inserted models aren't reported either. Models
+// which values don't fit into bind parameters of a statement are merged by
+// several statements, which are atomic only in a transaction.
+func (p *provider) Merge(ctx context.Context, store skyorm.Store, spec MergeSpec, models ...skyorm.Model) (int64, error) {
+	if err := checkWritable(store); err != nil {
+		return 0, err
+	}
+	if len(models) == 0 || spec.Matched == MergeNothing && !spec.Insert {
+		return 0, nil
+	}
+	omitted := spec.Insert && pkOmitted(ctx, models[0])
+	for _, m := range models {
+		if err := checkEnumModel(m); err != nil {
+			return 0, err
+		}
+		if spec.Insert && pkOmitted(ctx, m) != omitted {
+			return 0, fmt.Errorf("merge of %s mixes models with and without pks", store.Name())
+		}
+	}
+	if len(spec.On) == 0 {
+		spec.On = []skyorm.Prop{store.Pk()}
+	}
+	if spec.Update == nil {
+		on := make(map[string]bool)
+		for _, prop := range spec.On {
+			on[prop.Name()] = true
+		}
+		for _, prop := range storedProps(store, store.Props()) {
+			if !prop.IsPk() && !on[prop.Name()] {
+				spec.Update = append(spec.Update, prop)
+			}
+		}
+	}
+	if err := checkStored(store, spec.Update...); err != nil {
+		return 0, err
+	}
+	if spec.Matched == MergeUpdate && len(spec.Update) == 0 {
+		spec.Matched = MergeNothing
+		if !spec.Insert {
+			return 0, nil
+		}
+	}
+	if err := beforeMerge(ctx, store, spec, models); err != nil {
+		return 0, err
+	}
+	ctx = withOp(ctx, OpMerge)
+	version, err := p.serverVersionNum(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// lib/pq doesn't parse rows affected of MERGE command tags, so MERGE counts
+	// them by RETURNING on PostgreSQL 17+, and older servers use fallbacks
+	// which report them unless the spec needs MERGE.
+	returning := version >= 170000
+	merge := returning || version >= 150000 && spec.Insert && spec.Matched == MergeDelete
+	if !merge && spec.Insert && spec.Matched == MergeDelete {
+		return 0, fmt.Errorf("%w: inserting and deleting in one statement needs PostgreSQL 15", ErrMergeUnsupported)
+	}
+	table := p.table(ctx, store.Name())
+	var types map[string]string
+	// inserts type values themselves.
+	if merge || !spec.Insert {
+		if types, err = p.tableColumnTypes(ctx, table); err != nil {
+			return 0, err
+		}
+	}
+	size := maxBindParams / len(storedProps(store, store.Props()))
+	var total int64
+	for start := 0; start < len(models); start += size {
+		end := start + size
+		if end > len(models) {
+			end = len(models)
+		}
+		var (
+			query string
+			args  []interface{}
+		)
+		switch {
+		case merge:
+			query, args = mergeQuery(table, types, store, spec, models[start:end], omitted)
+		case spec.Insert:
+			query, args = mergeInsertQuery(table, store, spec, models[start:end], omitted)
+		case spec.Matched == MergeUpdate:
+			query, args = mergeSourceQuery(table, types, store, spec, models[start:end], "UPDATE %[1]s AS t SET %[3]s FROM %[2]s WHERE %[4]s")
+		default:
+			query, args = mergeSourceQuery(table, types, store, spec, models[start:end], "DELETE FROM %[1]s AS t USING %[2]s WHERE %[4]s")
+		}
+		if returning {
+			var n int64
+			if err = p.queryRow(ctx, "WITH m AS ("+query+" RETURNING 1) SELECT count(*) FROM m", args, &n); err != nil {
+				return total, err
+			}
+			total += n
+			continue
+		}
+		res, err := p.exec(ctx, query, args...)
+		if err != nil {
+			return total, err
+		}
+		if dryRun(ctx) {
+			continue
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	if dryRun(ctx) {
+		return 0, nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	if err = afterMerge(ctx, store, spec, models); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+// beforeMerge runs hooks of models and of the store before merge.
+func beforeMerge(ctx context.Context, store skyorm.Store, spec MergeSpec, models []skyorm.Model) error {
+	if spec.Insert {
+		for _, m := range models {
+			if err := beforeInsert(ctx, m); err != nil {
+				return err
+			}
+		}
+	}
+	h := storeHooks(store)
+	switch {
+	case spec.Matched == MergeUpdate && h.BeforeUpdate != nil:
+		for _, m := range models {
+			vals := modelVals(m, spec.Update)
+			l, err := h.BeforeUpdate(ctx, mergeCond(spec.On, m), vals)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(l, vals) {
+				return fmt.Errorf("values of %s replaced by BeforeUpdate hook can't be merged", store.Name())
+			}
+		}
+	case spec.Matched == MergeDelete && h.BeforeDelete != nil:
+		for _, m := range models {
+			if err := h.BeforeDelete(ctx, mergeCond(spec.On, m)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterMerge runs hooks of the store after merge.
+func afterMerge(ctx context.Context, store skyorm.Store, spec MergeSpec, models []skyorm.Model) error {
+	h := storeHooks(store)
+	switch {
+	case spec.Matched == MergeUpdate && h.AfterUpdate != nil:
+		for _, m := range models {
+			if err := h.AfterUpdate(ctx, mergeCond(spec.On, m), modelVals(m, spec.Update)); err != nil {
+				return err
+			}
+		}
+	case spec.Matched == MergeDelete && h.AfterDelete != nil:
+		for _, m := range models {
+			if err := h.AfterDelete(ctx, mergeCond(spec.On, m)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeCond returns condition matching rows of the model by the props.
+func mergeCond(on []skyorm.Prop, m skyorm.Model) skyorm.Cond {
+	vals := modelVals(m, on)
+	l := make([]skyorm.Cond, len(vals))
+	for i, v := range vals {
+		l[i] = skyorm.Eq(v.Prop(), v.Val())
+	}
+	if len(l) == 1 {
+		return l[0]
+	}
+	return skyorm.And(l...)
+}
+
+// mergeSource returns VALUES list of models with placeholders cast to types of
+// columns of the table, which aren't inferred from the source otherwise.
+func mergeSource(types map[string]string, store skyorm.Store, models []skyorm.Model) (string, []interface{}) {
+	props := storedProps(store, store.Props())
+	columns := make([]string, len(props))
+	for i, prop := range props {
+		columns[i] = quoteColumn(prop.Name())
+	}
+	rows := make([]string, len(models))
+	args := make([]interface{}, 0, len(models)*len(props))
+	n := 1
+	for i, m := range models {
+		phs := make([]string, len(props))
+		for j, prop := range props {
+			phs[j] = placeholder(&n)
+			if typ, ok := types[foldIdent(prop.Name())]; ok {
+				phs[j] += "::" + typ
+			}
+		}
+		rows[i] = "(" + strings.Join(phs, ", ") + ")"
+		lp, vals := storedVals(m, false)
+		args = append(args, bindColumns(lp, vals)...)
+	}
+	return fmt.Sprintf("(VALUES %s) AS s (%s)", strings.Join(rows, ", "), strings.Join(columns, ", ")), args
+}
+
+// tableColumnTypes returns types of columns of the table by column names.
+func (p *provider) tableColumnTypes(ctx context.Context, table string) (map[string]string, error) {
+	res, err := p.query(ctx, `SELECT attname, format_type(atttypid, atttypmod) FROM pg_attribute
+WHERE attrelid = to_regclass($1) AND attnum > 0 AND NOT attisdropped`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	types := make(map[string]string)
+	for res.Next() {
+		var name, typ string
+		if err = res.Scan(&name, &typ); err != nil {
+			return nil, err
+		}
+		types[name] = typ
+	}
+	return types, res.Err()
+}
+
+// mergeSourceQuery formats query of fallback with the table, the source, sets
+// of Update props and match condition.
+func mergeSourceQuery(table string, types map[string]string, store skyorm.Store, spec MergeSpec, models []skyorm.Model, format string) (string, []interface{}) {
+	source, args := mergeSource(types, store, models)
+	return fmt.Sprintf(format, table, source, mergeSets(spec.Update), mergeOn(spec.On)), args
+}
+
+// mergeQuery returns MERGE statement of models, which are inserted without pk
+// when it's omitted.
+func mergeQuery(table string, types map[string]string, store skyorm.Store, spec MergeSpec, models []skyorm.Model, omitted bool) (string, []interface{}) {
+	source, args := mergeSource(types, store, models)
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("MERGE INTO %s AS t USING %s ON %s", table, source, mergeOn(spec.On)))
+	switch spec.Matched {
+	case MergeUpdate:
+		b.WriteString(" WHEN MATCHED THEN UPDATE SET " + mergeSets(spec.Update))
+	case MergeDelete:
+		b.WriteString(" WHEN MATCHED THEN DELETE")
+	}
+	if spec.Insert {
+		props, _ := storedVals(models[0], omitted)
+		columns := make([]string, len(props))
+		values := make([]string, len(props))
+		for i, prop := range props {
+			columns[i] = quoteColumn(prop.Name())
+			values[i] = "s." + quoteColumn(prop.Name())
+		}
+		b.WriteString(fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s)%s VALUES (%s)",
+			strings.Join(columns, ", "), pkOverriding(models[0], omitted), strings.Join(values, ", ")))
+	}
+	return b.String(), args
+}
+
+// mergeInsertQuery returns INSERT ... ON CONFLICT fallback of merge inserting
+// models, without pks when they are omitted. Values are typed by the insert.
+func mergeInsertQuery(table string, store skyorm.Store, spec MergeSpec, models []skyorm.Model, omitted bool) (string, []interface{}) {
+	props, _ := storedVals(models[0], omitted)
+	rows := make([]string, len(models))
+	args := make([]interface{}, 0, len(models)*len(props))
+	n := 1
+	for i, m := range models {
+		phs := make([]string, len(props))
+		for j := range props {
+			phs[j] = placeholder(&n)
+		}
+		rows[i] = "(" + strings.Join(phs, ", ") + ")"
+		lp, vals := storedVals(m, omitted)
+		args = append(args, bindColumns(lp, vals)...)
+	}
+	action := "DO NOTHING"
+	if spec.Matched == MergeUpdate {
+		sets := make([]string, len(spec.Update))
+		for i, prop := range spec.Update {
+			sets[i] = quoteColumn(prop.Name()) + " = EXCLUDED." + quoteColumn(prop.Name())
+		}
+		action = "DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+	target := Conflict{Props: spec.On}
+	return fmt.Sprintf("INSERT INTO %s (%s)%s VALUES %s ON CONFLICT%s %s",
+		table, buildQueryProperties(props, false), pkOverriding(models[0], omitted), strings.Join(rows, ", "), target.target(), action), args
+}
+
+func mergeOn(on []skyorm.Prop) string {
+	l := make([]string, len(on))
+	for i, prop := range on {
+		l[i] = "t." + quoteColumn(prop.Name()) + " = s." + quoteColumn(prop.Name())
+	}
+	return strings.Join(l, " AND ")
+}
+
+func mergeSets(props []skyorm.Prop) string {
+	l := make([]string, len(props))
+	for i, prop := range props {
+		l[i] = quoteColumn(prop.Name()) + " = s." + quoteColumn(prop.Name())
+	}
+	return strings.Join(l, ", ")
+}
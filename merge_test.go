@@ -0,0 +1,180 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+func TestMergeInChunks(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 params per user, the last one doesn't fit into the first statement.
+	models := make([]skyorm.Model, 65535/2+1)
+	for i := range models {
+		models[i] = &user{ID: int64(i + 1)}
+	}
+	mock.ExpectQuery(`server_version_num`).WillReturnRows([]string{"v"}, []interface{}{140000})
+	mock.ExpectExec(`^INSERT INTO users \(id, name\) VALUES \(\$1, \$2\), .*\(\$65533, \$65534\) ON CONFLICT \(id\) DO NOTHING$`).
+		WillReturnResult(3)
+	mock.ExpectExec(`^INSERT INTO users \(id, name\) VALUES \(\$1, \$2\) ON CONFLICT \(id\) DO NOTHING$`).
+		WithArgs(int64(len(models)), "").WillReturnResult(1)
+	n, err := p.Merge(context.Background(), userStore, postgres.MergeSpec{Insert: true}, models...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Fatalf("merged %d rows", n)
+	}
+	// server version is queried once.
+	mock.ExpectExec(`^INSERT INTO users`).WillReturnResult(1)
+	if _, err = p.Merge(context.Background(), userStore, postgres.MergeSpec{Insert: true}, &user{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeQuery(t *testing.T) {
+	defer postgres.RegisterPkStrategy(eventStore, postgres.PkAuto)
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	spec := postgres.MergeSpec{Matched: postgres.MergeUpdate, Insert: true}
+	mock.ExpectQuery(`server_version_num`).WillReturnRows([]string{"v"}, []interface{}{170000})
+	mock.ExpectQuery(`^SELECT attname`).WithArgs("events").
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	// rows affected are counted from RETURNING, as lib/pq drops them from MERGE command tags.
+	mock.ExpectQuery(`^WITH m AS \(MERGE INTO events AS t USING \(VALUES \(\$1::bigint, \$2::text\)\) AS s \(id, kind\) ON t\.id = s\.id `+
+		`WHEN MATCHED THEN UPDATE SET kind = s\.kind WHEN NOT MATCHED THEN INSERT \(id, kind\) VALUES \(s\.id, s\.kind\) `+
+		`RETURNING 1\) SELECT count\(\*\) FROM m$`).
+		WithArgs(int64(1), "a").WillReturnRows([]string{"count"}, []interface{}{int64(1)})
+	n, err := p.Merge(ctx, eventStore, spec, &event{ID: 1, Kind: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("merged %d rows, want 1", n)
+	}
+	// serial pks are assigned by the column default.
+	postgres.RegisterPkStrategy(eventStore, postgres.PkSerial)
+	mock.ExpectQuery(`^SELECT attname`).
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	mock.ExpectQuery(`WHEN NOT MATCHED THEN INSERT \(kind\) VALUES \(s\.kind\) RETURNING 1\)`).
+		WillReturnRows([]string{"count"}, []interface{}{int64(1)})
+	if _, err = p.Merge(ctx, eventStore, spec, &event{ID: 1, Kind: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	postgres.RegisterPkStrategy(eventStore, postgres.PkIdentity)
+	mock.ExpectQuery(`^SELECT attname`).
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	mock.ExpectQuery(`WHEN NOT MATCHED THEN INSERT \(id, kind\) OVERRIDING SYSTEM VALUE VALUES \(s\.id, s\.kind\) RETURNING 1\)`).
+		WillReturnRows([]string{"count"}, []interface{}{int64(1)})
+	if _, err = p.Merge(ctx, eventStore, spec, &event{ID: 1, Kind: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Merge(ctx, eventStore, spec, &event{ID: 1}, &event{}); err == nil {
+		t.Fatal("merged models with and without pks")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeFallbacks(t *testing.T) {
+	defer postgres.RegisterPkStrategy(eventStore, postgres.PkAuto)
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mock.ExpectQuery(`server_version_num`).WillReturnRows([]string{"v"}, []interface{}{140000})
+	mock.ExpectExec(`^INSERT INTO events \(kind\) VALUES \(\$1\) ON CONFLICT \(id\) DO UPDATE SET kind = EXCLUDED\.kind$`).
+		WithArgs("a").WillReturnResult(1)
+	if _, err = p.Merge(ctx, eventStore, postgres.MergeSpec{Matched: postgres.MergeUpdate, Insert: true}, &event{Kind: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	postgres.RegisterPkStrategy(eventStore, postgres.PkIdentity)
+	mock.ExpectExec(`^INSERT INTO events \(id, kind\) OVERRIDING SYSTEM VALUE VALUES \(\$1, \$2\) ON CONFLICT \(id\) DO NOTHING$`).
+		WithArgs(int64(1), "a").WillReturnResult(1)
+	if _, err = p.Merge(ctx, eventStore, postgres.MergeSpec{Insert: true}, &event{ID: 1, Kind: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT attname`).
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	mock.ExpectExec(`^UPDATE events AS t SET kind = s\.kind FROM \(VALUES \(\$1::bigint, \$2::text\)\) AS s \(id, kind\) WHERE t\.id = s\.id$`).
+		WithArgs(int64(1), "b").WillReturnResult(1)
+	if _, err = p.Merge(ctx, eventStore, postgres.MergeSpec{Matched: postgres.MergeUpdate}, &event{ID: 1, Kind: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT attname`).
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	mock.ExpectExec(`^DELETE FROM events AS t USING \(VALUES \(\$1::bigint, \$2::text\)\) AS s \(id, kind\) WHERE t\.id = s\.id$`).
+		WithArgs(int64(1), "b").WillReturnResult(1)
+	if _, err = p.Merge(ctx, eventStore, postgres.MergeSpec{Matched: postgres.MergeDelete}, &event{ID: 1, Kind: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Merge(ctx, eventStore, postgres.MergeSpec{Matched: postgres.MergeDelete, Insert: true}, &event{ID: 1}); !errors.Is(err, postgres.ErrMergeUnsupported) {
+		t.Fatalf("Merge() error = %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeBeforePostgres17(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	mock.ExpectQuery(`server_version_num`).WillReturnRows([]string{"v"}, []interface{}{150000})
+	// fallbacks report rows affected, unlike MERGE command tags.
+	mock.ExpectQuery(`^SELECT attname`).
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	mock.ExpectExec(`^UPDATE events AS t SET kind = s\.kind FROM`).WithArgs(int64(1), "b").WillReturnResult(1)
+	n, err := p.Merge(ctx, eventStore, postgres.MergeSpec{Matched: postgres.MergeUpdate}, &event{ID: 1, Kind: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("merged %d rows, want 1", n)
+	}
+	mock.ExpectQuery(`^SELECT attname`).
+		WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"}, []interface{}{"kind", "text"})
+	mock.ExpectExec(`^MERGE INTO events AS t .* WHEN MATCHED THEN DELETE WHEN NOT MATCHED THEN INSERT \(id, kind\) VALUES \(s\.id, s\.kind\)$`).
+		WithArgs(int64(1), "b").WillReturnResult(1)
+	if _, err = p.Merge(ctx, eventStore, postgres.MergeSpec{Matched: postgres.MergeDelete, Insert: true}, &event{ID: 1, Kind: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMergeTypesOfFoldedColumns(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`server_version_num`).WillReturnRows([]string{"v"}, []interface{}{170000})
+	mock.ExpectQuery(`^SELECT attname`).WithArgs("Ledgers").WillReturnRows([]string{"attname", "type"}, []interface{}{"id", "bigint"})
+	// ID column is created as id, so it's typed by the type of id.
+	mock.ExpectQuery(`^WITH m AS \(MERGE INTO Ledgers AS t USING \(VALUES \(\$1::bigint\)\) AS s \(ID\) ON t\.ID = s\.ID `).
+		WithArgs(int64(1)).WillReturnRows([]string{"count"}, []interface{}{int64(1)})
+	if _, err = p.Merge(context.Background(), ledgerStore, postgres.MergeSpec{Insert: true}, &ledger{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Metrics receives metrics of provider. It's implemented by a thin adapter
+// registering Prometheus collectors on a user supplied registerer, so the
+// package doesn't depend on Prometheus:
+//
+//	func (m promMetrics) ObserveQuery(op string, d time.Duration) {
+//		m.latency.WithLabelValues(op).Observe(d.Seconds())
+//	}
+//
+//	func (m promMetrics) CountError(op, class string) {
+//		m.errors.WithLabelValues(op, class).Inc()
+//	}
+//
+//	func (m promMetrics) SetPoolStats(s sql.DBStats) {
+//		m.openConns.Set(float64(s.OpenConnections))
+//		m.waitCount.Set(float64(s.WaitCount))
+//		m.waitDuration.Set(s.WaitDuration.Seconds())
+//	}
+type Metrics interface {
+	// ObserveQuery records latency of a query of the operation, such as OpFind.
+	// Queries of helpers without an operation are recorded by their command, e.g. SELECT.
+	ObserveQuery(op string, d time.Duration)
+	// CountError counts failed query of the operation by SQLSTATE class, e.g.
+	// "23" for integrity constraint violations, or "client" for errors which
+	// don't come from the server.
+	CountError(op, class string)
+	// SetPoolStats reports connection pool statistics of the primary database.
+	SetPoolStats(stats sql.DBStats)
+}
+
+// MetaMetrics is implemented by metrics labeling queries with operation meta
+// set by WithOpMeta. Only keys allowed by WithMetricsMeta are passed, all of
+// them, empty when the context has no value, so label sets stay fixed and
+// cardinality stays bounded.
+type MetaMetrics interface {
+	Metrics
+	// ObserveQueryMeta records latency of a query of the operation with meta.
+	ObserveQueryMeta(op string, meta map[string]string, d time.Duration)
+	// CountErrorMeta counts failed query of the operation with meta like CountError.
+	CountErrorMeta(op, class string, meta map[string]string)
+}
+
+// WithMetricsMeta makes provider pass operation meta of the keys, e.g. route,
+// to metrics implementing MetaMetrics. Metrics get no meta by default.
+func WithMetricsMeta(keys ...string) Option {
+	return func(o *options) {
+		o.metricsMeta = keys
+	}
+}
+
+// WithMetrics makes provider report query metrics to m and pool statistics
+// every poolInterval, 15s by default.
+func WithMetrics(m Metrics, poolInterval time.Duration) Option {
+	return func(o *options) {
+		if poolInterval <= 0 {
+			poolInterval = 15 * time.Second
+		}
+		o.metrics = m
+		o.metricsInterval = poolInterval
+	}
+}
+
+// errorClass returns SQLSTATE class of query error.
+func errorClass(err error) string {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code.Class())
+	}
+	return "client"
+}
+
+func (p *provider) recordMetrics(ctx context.Context, op string, d time.Duration, err error) {
+	if p.metrics == nil {
+		return
+	}
+	if mm, ok := p.metrics.(MetaMetrics); ok && len(p.metricsMeta) > 0 {
+		meta := metricsMeta(ctx, p.metricsMeta)
+		mm.ObserveQueryMeta(op, meta, d)
+		if err != nil && err != sql.ErrNoRows {
+			mm.CountErrorMeta(op, errorClass(err), meta)
+		}
+		return
+	}
+	p.metrics.ObserveQuery(op, d)
+	if err != nil && err != sql.ErrNoRows {
+		p.metrics.CountError(op, errorClass(err))
+	}
+}
+
+// metricsMeta returns operation meta of ctx of the keys.
+func metricsMeta(ctx context.Context, keys []string) map[string]string {
+	m := OpMeta(ctx)
+	meta := make(map[string]string, len(keys))
+	for _, k := range keys {
+		meta[k] = m[k]
+	}
+	return meta
+}
+
+// poolReporter reports pool statistics until it's closed.
+type poolReporter struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newPoolReporter(db *sql.DB, m Metrics, interval time.Duration) *poolReporter {
+	r := &poolReporter{make(chan struct{}), make(chan struct{})}
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			m.SetPoolStats(db.Stats())
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return r
+}
+
+func (r *poolReporter) close() {
+	close(r.stop)
+	<-r.done
+}
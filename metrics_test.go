@@ -0,0 +1,50 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type metaMetrics struct {
+	meta []map[string]string
+}
+
+func (m *metaMetrics) ObserveQuery(op string, d time.Duration) {}
+func (m *metaMetrics) CountError(op, class string)             {}
+func (m *metaMetrics) SetPoolStats(stats sql.DBStats)          {}
+
+func (m *metaMetrics) ObserveQueryMeta(op string, meta map[string]string, d time.Duration) {
+	m.meta = append(m.meta, meta)
+}
+
+func (m *metaMetrics) CountErrorMeta(op, class string, meta map[string]string) {}
+
+func TestMetricsMeta(t *testing.T) {
+	m := &metaMetrics{}
+	p, mock, err := postgrestest.NewMock(postgres.WithMetrics(m, time.Hour), postgres.WithMetricsMeta("route", "tenant"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := postgres.WithOpMeta(context.Background(), map[string]string{"route": "/users", "user": "1"})
+	mock.ExpectQuery(`^SELECT id, name FROM users`).WillReturnRows([]string{"id", "name"})
+	if _, err = p.Find(ctx, userStore, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	// keys not allowed are dropped and missing ones are empty.
+	want := []map[string]string{{"route": "/users", "tenant": ""}}
+	if !reflect.DeepEqual(m.meta, want) {
+		t.Fatalf("meta: %v, want %v", m.meta, want)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if err = p.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package postgres_test
+
+import (
+	"github.com/skyorm/skyorm"
+)
+
+type user struct {
+	ID   int64
+	Name string
+}
+
+var userStore = skyorm.NewStore("users", 0, func() skyorm.Model {
+	return &user{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("name", "string", false),
+)
+
+func (m *user) OrmStore() skyorm.Store     { return userStore }
+func (m *user) OrmPk() interface{}         { return m.ID }
+func (m *user) OrmPkProp() skyorm.Prop     { return userStore.Pk() }
+func (m *user) OrmPkPointer() interface{}  { return &m.ID }
+func (m *user) OrmProps() []skyorm.Prop    { return userStore.Props() }
+func (m *user) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Name} }
+func (m *user) OrmVals() []interface{}     { return []interface{}{m.ID, m.Name} }
+
+type order struct {
+	ID     int64
+	UserID int64
+	Total  int64
+}
+
+var orderStore = skyorm.NewStore("orders", 0, func() skyorm.Model {
+	return &order{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("user_id", "int64", false),
+	skyorm.NewProp("total", "int64", false),
+)
+
+func (m *order) OrmStore() skyorm.Store     { return orderStore }
+func (m *order) OrmPk() interface{}         { return m.ID }
+func (m *order) OrmPkProp() skyorm.Prop     { return orderStore.Pk() }
+func (m *order) OrmPkPointer() interface{}  { return &m.ID }
+func (m *order) OrmProps() []skyorm.Prop    { return orderStore.Props() }
+func (m *order) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.UserID, &m.Total} }
+func (m *order) OrmVals() []interface{}     { return []interface{}{m.ID, m.UserID, m.Total} }
+
+type event struct {
+	ID   int64
+	Kind string
+}
+
+var eventStore = skyorm.NewStore("events", 0, func() skyorm.Model {
+	return &event{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("kind", "string", false),
+)
+
+func (m *event) OrmStore() skyorm.Store     { return eventStore }
+func (m *event) OrmPk() interface{}         { return m.ID }
+func (m *event) OrmPkProp() skyorm.Prop     { return eventStore.Pk() }
+func (m *event) OrmPkPointer() interface{}  { return &m.ID }
+func (m *event) OrmProps() []skyorm.Prop    { return eventStore.Props() }
+func (m *event) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Kind} }
+func (m *event) OrmVals() []interface{}     { return []interface{}{m.ID, m.Kind} }
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// bindNetwork returns text of net.IP, net.IPNet and net.HardwareAddr values
+// bound to inet, cidr and macaddr columns, which are []byte or struct otherwise.
+// Nil ones are NULL.
+func bindNetwork(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case net.IP:
+		if v == nil {
+			return nil, true
+		}
+		return v.String(), true
+	case net.IPNet:
+		if v.IP == nil {
+			return nil, true
+		}
+		return v.String(), true
+	case net.HardwareAddr:
+		if v == nil {
+			return nil, true
+		}
+		return v.String(), true
+	}
+	return nil, false
+}
+
+// networkPointer scans inet, cidr and macaddr columns into pointers to net.IP,
+// net.IPNet and net.HardwareAddr or pointers to pointers to them when nullable.
+type networkPointer struct {
+	p reflect.Value
+}
+
+var networkTypes = map[reflect.Type]bool{
+	reflect.TypeOf(net.IP{}):           true,
+	reflect.TypeOf(net.IPNet{}):        true,
+	reflect.TypeOf(net.HardwareAddr{}): true,
+}
+
+// scanNetwork returns scanner of pointer to network type.
+func scanNetwork(p interface{}) (networkPointer, bool) {
+	t := reflect.TypeOf(p)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return networkPointer{}, false
+	}
+	if t.Elem().Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return networkPointer{reflect.ValueOf(p)}, networkTypes[t.Elem()]
+}
+
+func (n networkPointer) Scan(src interface{}) error {
+	dest := n.p.Elem()
+	if src == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	var s string
+	switch src := src.(type) {
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	default:
+		return fmt.Errorf("can't scan %T into %s", src, dest.Type())
+	}
+	t := dest.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	v, err := parseNetwork(t, s)
+	if err != nil {
+		return err
+	}
+	if dest.Kind() == reflect.Ptr {
+		ptr := reflect.New(t)
+		ptr.Elem().Set(v)
+		v = ptr
+	}
+	dest.Set(v)
+	return nil
+}
+
+// parseNetwork parses text of inet, cidr or macaddr value into value of t.
+// Addresses of inet values with prefix are kept by net.IPNet.
+func parseNetwork(t reflect.Type, s string) (reflect.Value, error) {
+	switch t {
+	case reflect.TypeOf(net.HardwareAddr{}):
+		mac, err := net.ParseMAC(s)
+		return reflect.ValueOf(mac), err
+	case reflect.TypeOf(net.IPNet{}):
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return reflect.Value{}, fmt.Errorf("invalid address %q", s)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			return reflect.ValueOf(net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}), nil
+		}
+		ip, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if ip.To4() != nil {
+			ip = ip.To4()
+		}
+		return reflect.ValueOf(net.IPNet{IP: ip, Mask: n.Mask}), nil
+	}
+	// inet values of addresses have no prefix, but they may have one.
+	ip := net.ParseIP(strings.SplitN(s, "/", 2)[0])
+	if ip == nil {
+		return reflect.Value{}, fmt.Errorf("invalid address %q", s)
+	}
+	return reflect.ValueOf(ip), nil
+}
+
+// InSubnet returns condition matching inet or cidr property strictly within
+// subnet (<<), e.g. "10.0.0.0/8" or net.IPNet. Network props are net.IP,
+// net.IPNet and net.HardwareAddr rather than netip.Addr and netip.Prefix, which
+// need Go 1.18 while the module supports Go 1.16.
+func InSubnet(prop skyorm.Prop, subnet interface{}) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, subnet), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " << " + placeholder(n) + "::inet", []interface{}{subnet}
+	}}
+}
+
+// SubnetContains returns condition matching inet or cidr property strictly
+// containing addr (>>), e.g. net.IP or a subnet.
+func SubnetContains(prop skyorm.Prop, addr interface{}) skyorm.Cond {
+	return &exprCond{skyorm.Eq(prop, addr), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " >> " + placeholder(n) + "::inet", []interface{}{addr}
+	}}
+}
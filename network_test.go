@@ -0,0 +1,90 @@
+package postgres_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type host struct {
+	ID     int64
+	Addr   net.IP
+	Subnet net.IPNet
+	Route  *net.IPNet
+	MAC    net.HardwareAddr
+}
+
+var hostStore = skyorm.NewStore("hosts", 0, func() skyorm.Model {
+	return &host{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("addr", "net.IP", false),
+	skyorm.NewProp("subnet", "net.IPNet", false),
+	skyorm.NewProp("route", "*net.IPNet", false),
+	skyorm.NewProp("mac", "net.HardwareAddr", false),
+)
+
+func (m *host) OrmStore() skyorm.Store    { return hostStore }
+func (m *host) OrmPk() interface{}        { return m.ID }
+func (m *host) OrmPkProp() skyorm.Prop    { return hostStore.Pk() }
+func (m *host) OrmPkPointer() interface{} { return &m.ID }
+func (m *host) OrmProps() []skyorm.Prop   { return hostStore.Props() }
+func (m *host) OrmPointers() []interface{} {
+	return []interface{}{&m.ID, &m.Addr, &m.Subnet, &m.Route, &m.MAC}
+}
+func (m *host) OrmVals() []interface{} { return []interface{}{m.ID, m.Addr, m.Subnet, m.Route, m.MAC} }
+
+func TestNetworkProps(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/8")
+	mac, _ := net.ParseMAC("08:00:2b:01:02:03")
+	mock.ExpectQuery(`^INSERT INTO hosts \(id, addr, subnet, route, mac\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING id$`).
+		WithArgs(int64(1), "10.1.2.3", "10.0.0.0/8", nil, "08:00:2b:01:02:03").WillReturnRows([]string{"id"}, []interface{}{1})
+	if err = p.Put(ctx, &host{ID: 1, Addr: net.ParseIP("10.1.2.3"), Subnet: *subnet, MAC: mac}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, addr, subnet, route, mac FROM hosts$`).
+		WillReturnRows([]string{"id", "addr", "subnet", "route", "mac"},
+			[]interface{}{1, "10.1.2.3/8", "10.1.2.3", "2001:db8::/32", "08:00:2b:01:02:03"},
+			[]interface{}{2, "::1", "192.168.0.0/16", nil, nil})
+	l, err := p.Find(ctx, hostStore, nil, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := l[0].(*host)
+	// inet values with prefix keep only the address in net.IP and both in net.IPNet.
+	if !h.Addr.Equal(net.ParseIP("10.1.2.3")) || h.Subnet.String() != "10.1.2.3/32" ||
+		h.Route == nil || h.Route.String() != "2001:db8::/32" || !reflect.DeepEqual(h.MAC, mac) {
+		t.Fatalf("scanned %+v", h)
+	}
+	h = l[1].(*host)
+	if !h.Addr.Equal(net.IPv6loopback) || h.Subnet.String() != "192.168.0.0/16" || h.Route != nil || h.MAC != nil {
+		t.Fatalf("scanned %+v", h)
+	}
+	mock.ExpectQuery(`^SELECT id, addr, subnet, route, mac FROM hosts$`).
+		WillReturnRows([]string{"id", "addr", "subnet", "route", "mac"}, []interface{}{1, "nowhere", nil, nil, nil})
+	if _, err = p.Find(ctx, hostStore, nil, 0, 0); err == nil {
+		t.Fatal("scanned invalid address")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubnetConds(t *testing.T) {
+	addr := skyorm.NewProp("addr", "net.IP", false)
+	_, subnet, _ := net.ParseCIDR("10.0.0.0/8")
+	expectFind(t, postgres.InSubnet(addr, "10.0.0.0/8"), `^SELECT id, name FROM users WHERE addr << \$1::inet$`, "10.0.0.0/8")
+	expectFind(t, postgres.InSubnet(addr, *subnet), `^SELECT id, name FROM users WHERE addr << \$1::inet$`, "10.0.0.0/8")
+	expectFind(t, postgres.SubnetContains(addr, net.ParseIP("10.1.2.3")), `^SELECT id, name FROM users WHERE addr >> \$1::inet$`, "10.1.2.3")
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+type nPlusOneKey struct{}
+
+type nPlusOneState struct {
+	mu        sync.Mutex
+	threshold int
+	shapes    map[string]int
+}
+
+// DetectNPlusOne returns context in which provider warns when the same read query
+// differing only by parameters is issued threshold times. It is intended for
+// development and test environments, e.g. in a request middleware.
+func DetectNPlusOne(ctx context.Context, threshold int) context.Context {
+	return context.WithValue(ctx, nPlusOneKey{}, &nPlusOneState{
+		threshold: threshold,
+		shapes:    make(map[string]int),
+	})
+}
+
+func (p *provider) detectNPlusOne(ctx context.Context, query string) {
+	st, ok := ctx.Value(nPlusOneKey{}).(*nPlusOneState)
+	if !ok || !strings.HasPrefix(query, "SELECT ") {
+		return
+	}
+	st.mu.Lock()
+	// queries are built with placeholders, so the same text means the same shape.
+	st.shapes[query]++
+	cnt := st.shapes[query]
+	st.mu.Unlock()
+	if cnt == st.threshold {
+		p.logf(LevelWarn, "N+1 QUERY: %d identical queries within one context: %s\n"+
+			"consider loading these models with a single Find by In condition or preloading them with FindWith\n%s", cnt, query, debug.Stack())
+	}
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// derefValue returns value pointed by pointer prop value, nil when the pointer
+// is nil so it's stored as NULL. Valuers, e.g. sql.Null types, are returned as
+// they are.
+func derefValue(v interface{}) interface{} {
+	if _, ok := v.(driver.Valuer); ok {
+		return v
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+		if _, ok := rv.Interface().(driver.Valuer); ok {
+			break
+		}
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// nullArray scans nullable array into pointer to slice, leaving it nil for NULL.
+type nullArray struct {
+	// p is the pointer to slice.
+	p reflect.Value
+}
+
+func (a nullArray) Scan(src interface{}) error {
+	if src == nil {
+		a.p.Set(reflect.Zero(a.p.Type()))
+		return nil
+	}
+	s := reflect.New(a.p.Type().Elem())
+	if err := pq.Array(s.Interface()).Scan(src); err != nil {
+		return err
+	}
+	a.p.Set(s)
+	return nil
+}
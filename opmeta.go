@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+type opMetaKey struct{}
+
+// WithOpMeta returns context which operations carry meta, e.g. tenant or request id.
+// Meta is attached to logged queries and appended to queries as SQL comment, so
+// a slow query in pg_stat_activity can be traced back to its origin. Meta of
+// parent context is inherited and overridden by m.
+func WithOpMeta(ctx context.Context, m map[string]string) context.Context {
+	merged := make(map[string]string, len(m))
+	for k, v := range OpMeta(ctx) {
+		merged[k] = v
+	}
+	for k, v := range m {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, opMetaKey{}, merged)
+}
+
+// OpMeta returns operation meta of the context.
+func OpMeta(ctx context.Context) map[string]string {
+	m, _ := ctx.Value(opMetaKey{}).(map[string]string)
+	return m
+}
+
+// formatOpMeta returns meta of the context as sorted key='value' pairs with
+// url encoded keys and values, as sqlcommenter does.
+func formatOpMeta(ctx context.Context) string {
+	return formatMeta(OpMeta(ctx))
+}
+
+func formatMeta(m map[string]string) string {
+	if len(m) == 0 {
+		return ""
+	}
+	l := make([]string, 0, len(m))
+	for k, v := range m {
+		l = append(l, url.QueryEscape(k)+"='"+url.QueryEscape(v)+"'")
+	}
+	sort.Strings(l)
+	return strings.Join(l, ",")
+}
+
+// annotate appends operation meta of the context to query as SQL comment.
+func annotate(ctx context.Context, query string) string {
+	if meta := formatOpMeta(ctx); meta != "" {
+		return query + " /*" + meta + "*/"
+	}
+	return query
+}
@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Option configures provider created by New.
+type Option func(o *options)
+
+type options struct {
+	binaryParameters bool
+	pingTimeout      time.Duration
+	pool             func(db *sql.DB)
+	logger           LeveledLogger
+	logLevel         Level
+	opLogLevels      map[string]Level
+	async            *AsyncConfig
+	txAttempts       int
+	txBackoff        time.Duration
+	replicas         []string
+	replicaPolicy    ReplicaPolicy
+	healthInterval   time.Duration
+	retry            *RetryPolicy
+	breaker          *BreakerConfig
+	tracer           Tracer
+	metrics          Metrics
+	metricsInterval  time.Duration
+	metricsMeta      []string
+	queryHook        QueryHook
+	slowQuery        time.Duration
+	redacted         map[string]bool
+	commenter        *SQLCommenter
+	interceptors     []Interceptor
+	connector        driver.Connector
+	statementTimeout time.Duration
+	maxRows          int
+	truncateRows     bool
+	explainSlow      bool
+	cache            *resultCache
+	converters       map[reflect.Type]Converter
+	location         *time.Location
+}
+
+// WithPool configures connection pool, zero values keep database/sql defaults.
+func WithPool(maxOpen, maxIdle int, maxLifetime, maxIdleTime time.Duration) Option {
+	return func(o *options) {
+		o.pool = func(db *sql.DB) {
+			if maxOpen > 0 {
+				db.SetMaxOpenConns(maxOpen)
+			}
+			if maxIdle > 0 {
+				db.SetMaxIdleConns(maxIdle)
+			}
+			if maxLifetime > 0 {
+				db.SetConnMaxLifetime(maxLifetime)
+			}
+			if maxIdleTime > 0 {
+				db.SetConnMaxIdleTime(maxIdleTime)
+			}
+		}
+	}
+}
+
+// WithConnector makes provider open the primary database with connector instead
+// of DSN passed to New, e.g. a connector of a fake driver in tests. Health checks
+// don't apply to it.
+func WithConnector(c driver.Connector) Option {
+	return func(o *options) {
+		o.connector = c
+	}
+}
+
+// WithPing makes New verify connectivity within timeout, so bad DSN, unreachable
+// host or wrong credentials fail on start rather than on the first query.
+func WithPing(timeout time.Duration) Option {
+	return func(o *options) {
+		o.pingTimeout = timeout
+	}
+}
+
+// WithTxRetry configures RunInTx to make up to maxAttempts attempts of a transaction
+// failed by serialization failure or deadlock, waiting baseBackoff doubled with
+// every retry. It defaults to 5 attempts and 10ms.
+func WithTxRetry(maxAttempts int, baseBackoff time.Duration) Option {
+	return func(o *options) {
+		o.txAttempts = maxAttempts
+		o.txBackoff = baseBackoff
+	}
+}
+
+// WithDefaultStatementTimeout sets statement_timeout connection parameter of
+// primary and replica databases, so the server cancels statements running longer
+// than d, e.g. a runaway Find holding a connection. WithStatementTimeout overrides
+// it per call. It doesn't apply to WithConnector. The timeout is rounded up to
+// whole milliseconds, non-positive d sets none.
+func WithDefaultStatementTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.statementTimeout = d
+	}
+}
+
+// WithMaxRows limits number of models returned by Find and other finds to n,
+// protecting services from accidentally unbounded reads. Finds of more models
+// fail with ErrTooManyRows, or return the first n models with ErrRowsTruncated
+// when truncate is set, which is logged as a warning. Finds read at most n+1
+// rows from the server.
+func WithMaxRows(n int, truncate bool) Option {
+	return func(o *options) {
+		o.maxRows = n
+		o.truncateRows = truncate
+	}
+}
+
+// WithBinaryParameters makes lib/pq send []byte parameters, e.g. bytea payloads,
+// in binary format instead of hex encoded text, which halves CPU and bandwidth
+// spent on large payloads. Results are still received in text format.
+//
+// Every []byte parameter is sent in binary format, so []byte values of jsonb
+// columns, such as props of models generated by GenerateModels, are rejected by
+// the server, which expects binary jsonb to start with a version byte. Bind them
+// as strings or with JSONB, which are sent as text. lib/pq has no binary format
+// of numeric, its parameters are sent as text either way.
+func WithBinaryParameters() Option {
+	return func(o *options) {
+		o.binaryParameters = true
+	}
+}
+
+// withDSNParam sets connection parameter in both URL and key=value DSN formats.
+func withDSNParam(dsn, key, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		q := u.Query()
+		q.Set(key, value)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(dsn + " " + key + "=" + value)
+}
@@ -0,0 +1,20 @@
+package postgres_test
+
+import (
+	"testing"
+
+	"github.com/skyorm/postgres"
+)
+
+func TestWithDSNParam(t *testing.T) {
+	for dsn, want := range map[string]string{
+		"postgres://u:p@localhost/db":               "postgres://u:p@localhost/db?binary_parameters=yes",
+		"postgresql://localhost/db?sslmode=disable": "postgresql://localhost/db?binary_parameters=yes&sslmode=disable",
+		"host=localhost dbname=db":                  "host=localhost dbname=db binary_parameters=yes",
+		"":                                          "binary_parameters=yes",
+	} {
+		if got := postgres.WithDSNParam(dsn, "binary_parameters", "yes"); got != want {
+			t.Errorf("DSN %q: %s, want %s", dsn, got, want)
+		}
+	}
+}
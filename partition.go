@@ -0,0 +1,210 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PartitionInterval is the time span of a time-based partition.
+type PartitionInterval int
+
+const (
+	PartitionDaily PartitionInterval = iota
+	PartitionMonthly
+)
+
+// layout returns layout of partition name suffix, e.g. 2024_06 for monthly partitions.
+func (i PartitionInterval) layout() string {
+	if i == PartitionMonthly {
+		return "2006_01"
+	}
+	return "2006_01_02"
+}
+
+// start returns start of the partition containing t.
+func (i PartitionInterval) start(t time.Time) time.Time {
+	t = t.UTC()
+	if i == PartitionMonthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// next returns start of the partition following the one starting at t.
+func (i PartitionInterval) next(t time.Time) time.Time {
+	if i == PartitionMonthly {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// PartitionConfig is a configuration of partitions of a declaratively partitioned
+// table, i.e. created with PARTITION BY RANGE or PARTITION BY LIST.
+type PartitionConfig struct {
+	// Table is the partitioned table. Inserts into it are routed to partitions
+	// by postgres, so stores keep using the table name.
+	Table string
+	// Interval is the span of time-based range partitions created by Maintain.
+	Interval PartitionInterval
+	// Premake is the number of future time-based partitions Maintain keeps created,
+	// 1 by default.
+	Premake int
+	// Retention makes Maintain drop time-based partitions which end before
+	// now - Retention, zero keeps every partition.
+	Retention time.Duration
+}
+
+// Partitions manages partitions of a partitioned table. Time-based partitions
+// are named by the table and their start, e.g. events_2024_06, so they can be
+// addressed directly with TableSuffix.
+type Partitions struct {
+	p   *provider
+	cfg PartitionConfig
+}
+
+// Partitions returns partitions of the table configured by cfg.
+func (p *provider) Partitions(cfg PartitionConfig) *Partitions {
+	if cfg.Premake <= 0 {
+		cfg.Premake = 1
+	}
+	return &Partitions{p, cfg}
+}
+
+// CreateRange creates partition holding values from inclusive to exclusive bounds
+// if it doesn't exist.
+func (ps *Partitions) CreateRange(ctx context.Context, name string, from, to interface{}) error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+		quoteTable(name), quoteTable(ps.cfg.Table), partitionBound(from), partitionBound(to))
+	_, err := ps.p.exec(ctx, query)
+	return err
+}
+
+// CreateList creates partition holding the values if it doesn't exist.
+func (ps *Partitions) CreateList(ctx context.Context, name string, values ...interface{}) error {
+	bounds := make([]string, len(values))
+	for i, v := range values {
+		bounds[i] = partitionBound(v)
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES IN (%s)",
+		quoteTable(name), quoteTable(ps.cfg.Table), strings.Join(bounds, ", "))
+	_, err := ps.p.exec(ctx, query)
+	return err
+}
+
+// CreateDefault creates partition holding values without other partition if it doesn't exist.
+func (ps *Partitions) CreateDefault(ctx context.Context, name string) error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF %s DEFAULT", quoteTable(name), quoteTable(ps.cfg.Table))
+	_, err := ps.p.exec(ctx, query)
+	return err
+}
+
+// Drop drops the partition with its rows.
+func (ps *Partitions) Drop(ctx context.Context, name string) error {
+	_, err := ps.p.exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", quoteTable(name)))
+	return err
+}
+
+// List returns names of partitions of the table.
+func (ps *Partitions) List(ctx context.Context) ([]string, error) {
+	res, err := ps.p.query(ctx, `SELECT c.relname FROM pg_inherits i
+INNER JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = $1::regclass ORDER BY c.relname`, quoteTable(ps.cfg.Table))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]string, 0)
+	for res.Next() {
+		var name string
+		if err = res.Scan(&name); err != nil {
+			return nil, err
+		}
+		l = append(l, name)
+	}
+	return l, res.Err()
+}
+
+// Name returns name of time-based partition containing t.
+func (ps *Partitions) Name(t time.Time) string {
+	return ps.cfg.Table + "_" + ps.cfg.Interval.start(t).Format(ps.cfg.Interval.layout())
+}
+
+// Maintain creates time-based partitions from the current one to Premake
+// partitions ahead and drops the ones out of Retention.
+func (ps *Partitions) Maintain(ctx context.Context) error {
+	now := time.Now()
+	start := ps.cfg.Interval.start(now)
+	for i := 0; i <= ps.cfg.Premake; i++ {
+		end := ps.cfg.Interval.next(start)
+		if err := ps.CreateRange(ctx, ps.Name(start), start, end); err != nil {
+			return err
+		}
+		start = end
+	}
+	if ps.cfg.Retention <= 0 {
+		return nil
+	}
+	names, err := ps.List(ctx)
+	if err != nil {
+		return err
+	}
+	schema, table := splitTable(ps.cfg.Table)
+	for _, name := range names {
+		t, err := time.Parse(ps.cfg.Interval.layout(), strings.TrimPrefix(name, table+"_"))
+		if err != nil {
+			// not a time-based partition, e.g. the default one.
+			continue
+		}
+		if ps.cfg.Interval.next(t).Before(now.Add(-ps.cfg.Retention)) {
+			if schema != "" {
+				name = schema + "." + name
+			}
+			if err = ps.Drop(ctx, name); err != nil {
+				return err
+			}
+			ps.p.logf(LevelInfo, "PARTITION %s DROPPED", name)
+		}
+	}
+	return nil
+}
+
+// RunMaintenance maintains time-based partitions every interval until ctx is
+// done, errors are logged. It returns error of ctx once it's done, or at once
+// when interval isn't positive.
+func (ps *Partitions) RunMaintenance(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid maintenance interval %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := ps.Maintain(ctx); err != nil && ctx.Err() == nil {
+			ps.p.logf(LevelError, "PARTITION %s MAINTENANCE ERROR: %v", ps.cfg.Table, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// partitionBound returns value as literal of partition bound, DDL can't have
+// parameters.
+func partitionBound(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		return pq.QuoteLiteral(v.Format("2006-01-02 15:04:05.999999Z07:00"))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	}
+	return pq.QuoteLiteral(fmt.Sprint(v))
+}
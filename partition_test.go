@@ -0,0 +1,23 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestRunMaintenanceRejectsNonPositiveInterval(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps := p.Partitions(postgres.PartitionConfig{Table: "events"})
+	if err = ps.RunMaintenance(context.Background(), -1); err == nil {
+		t.Fatal("ran maintenance without interval")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// PkStrategy tells how pks of a store are assigned on insert.
+type PkStrategy int
+
+const (
+	// PkAuto guesses the strategy from the pk value: models with zero pk are
+	// inserted without it, so it's assigned by the column default.
+	PkAuto PkStrategy = iota
+	// PkSerial always inserts models without pk, which is assigned by serial
+	// column default, even when the model has one.
+	PkSerial
+	// PkIdentity inserts models without pk unless it's set, which is assigned by
+	// identity column. Set pks override values generated always.
+	PkIdentity
+	// PkClientAssigned always inserts pk of the model, zero values included.
+	PkClientAssigned
+)
+
+var (
+	pkStrategiesMu sync.RWMutex
+	pkStrategies   = make(map[string]PkStrategy)
+)
+
+// RegisterPkStrategy sets pk strategy of the store, PkAuto by default.
+func RegisterPkStrategy(store skyorm.Store, s PkStrategy) {
+	pkStrategiesMu.Lock()
+	defer pkStrategiesMu.Unlock()
+	pkStrategies[store.Name()] = s
+}
+
+func storePkStrategy(store skyorm.Store) PkStrategy {
+	pkStrategiesMu.RLock()
+	defer pkStrategiesMu.RUnlock()
+	return pkStrategies[store.Name()]
+}
+
+type clientPksKey struct{}
+
+// withClientPks makes inserts of ctx keep pks of models regardless of their pk
+// strategy, e.g. pks assigned by another database.
+func withClientPks(ctx context.Context) context.Context {
+	return context.WithValue(ctx, clientPksKey{}, true)
+}
+
+// clientPks reports whether inserts of ctx keep pks of models.
+func clientPks(ctx context.Context) bool {
+	client, _ := ctx.Value(clientPksKey{}).(bool)
+	return client
+}
+
+// pkOmitted returns whether the model is inserted by ctx without pk column.
+func pkOmitted(ctx context.Context, m skyorm.Model) bool {
+	if clientPks(ctx) {
+		return false
+	}
+	switch storePkStrategy(m.OrmStore()) {
+	case PkSerial:
+		return true
+	case PkClientAssigned:
+		return false
+	}
+	return isPkEmpty(m.OrmPk())
+}
+
+// pkOverriding returns OVERRIDING clause of insert of the model with pk into
+// identity column.
+func pkOverriding(m skyorm.Model, omitted bool) string {
+	if omitted || storePkStrategy(m.OrmStore()) != PkIdentity {
+		return ""
+	}
+	return " OVERRIDING SYSTEM VALUE"
+}
+
+// isPkEmpty returns whether pk is zero number or empty string, nil pointers
+// included.
+func isPkEmpty(pk interface{}) bool {
+	v := reflect.ValueOf(derefValue(pk))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String:
+		return v.String() == ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	}
+	return false
+}
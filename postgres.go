@@ -3,90 +3,450 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/skyorm/skyorm"
 )
 
+// Provider is a skyorm provider with postgres specific extensions.
+type Provider interface {
+	skyorm.Provider
+	// Exists reports whether at least one model of the store matches condition.
+	Exists(ctx context.Context, store skyorm.Store, condition skyorm.Cond) (bool, error)
+	// FindOne returns the first model matching condition in the given order
+	// or ErrNotFound error when there is no such model.
+	FindOne(ctx context.Context, store skyorm.Store, condition skyorm.Cond, order ...Order) (skyorm.Model, error)
+	// FindWindowed returns models matching condition with values of the windows.
+	FindWindowed(ctx context.Context, store skyorm.Store, condition skyorm.Cond, windows []Window, limit, offset int) ([]WindowedModel, error)
+	// FindFirstPerGroup returns the first model in the order of every group of partitionBy values.
+	FindFirstPerGroup(ctx context.Context, store skyorm.Store, condition skyorm.Cond, partitionBy []skyorm.Prop, order ...Order) ([]skyorm.Model, error)
+	// FindUnion returns models matching any of the specs in the order, combined with UNION or UNION ALL.
+	FindUnion(ctx context.Context, all bool, specs []FindSpec, order []Order, limit, offset int) ([]skyorm.Model, error)
+	// PutIgnore inserts models skipping conflicting ones and reports whether each was inserted.
+	PutIgnore(ctx context.Context, models ...skyorm.Model) ([]bool, error)
+	// PutIgnoreOn is PutIgnore skipping models only on conflicts of the target.
+	PutIgnoreOn(ctx context.Context, target Conflict, models ...skyorm.Model) ([]bool, error)
+	// Upsert inserts or updates models by pk and reports whether each was inserted.
+	Upsert(ctx context.Context, models ...skyorm.Model) ([]bool, error)
+	// UpsertOn is Upsert updating models conflicting on the target.
+	UpsertOn(ctx context.Context, target Conflict, models ...skyorm.Model) ([]bool, error)
+	// Merge syncs models into rows of the store with MERGE, or its fallbacks on older servers.
+	Merge(ctx context.Context, store skyorm.Store, spec MergeSpec, models ...skyorm.Model) (int64, error)
+	// PutBatch inserts models of the same store with a single INSERT ... SELECT FROM unnest statement.
+	PutBatch(ctx context.Context, models ...skyorm.Model) error
+	// UpdateBatch sets the props of rows to values of models with a single UPDATE ... FROM unnest statement.
+	UpdateBatch(ctx context.Context, store skyorm.Store, props []skyorm.Prop, models ...skyorm.Model) (int64, error)
+	// DeleteReturning deletes models matching condition and returns them.
+	DeleteReturning(ctx context.Context, store skyorm.Store, condition skyorm.Cond) ([]skyorm.Model, error)
+	// CallFunction returns call of stored function with args, run when its result is scanned.
+	CallFunction(ctx context.Context, name string, args ...interface{}) *FunctionCall
+	// CallProcedure calls stored procedure with args.
+	CallProcedure(ctx context.Context, name string, args ...interface{}) error
+	// EnsureViews creates registered views of the stores, replacing plain views.
+	EnsureViews(ctx context.Context, stores ...skyorm.Store) error
+	// RefreshMaterializedView refreshes materialized view of the store.
+	RefreshMaterializedView(ctx context.Context, store skyorm.Store, concurrently bool) error
+	// Truncate removes all models of the store.
+	Truncate(ctx context.Context, store skyorm.Store, restartIdentity, cascade bool) error
+	// CurrentSequenceValue returns the last value of the sequence backing pk of the store.
+	CurrentSequenceValue(ctx context.Context, store skyorm.Store) (int64, error)
+	// NextSequenceValue advances the sequence backing pk of the store and returns its next value.
+	NextSequenceValue(ctx context.Context, store skyorm.Store) (int64, error)
+	// ResetSequence sets the sequence backing pk of the store so the next pk it generates is to.
+	ResetSequence(ctx context.Context, store skyorm.Store, to int64) error
+	// SyncSequence sets the sequence backing pk of the store past the greatest pk.
+	SyncSequence(ctx context.Context, store skyorm.Store) error
+	// DeleteInBatches deletes models matching condition by batches of batchSize rows.
+	DeleteInBatches(ctx context.Context, store skyorm.Store, condition skyorm.Cond, batchSize int) (int64, error)
+	// Explain returns query plan of Find of models matching condition.
+	Explain(ctx context.Context, store skyorm.Store, condition skyorm.Cond, analyze bool) (string, error)
+	// Exec runs raw SQL statement, e.g. DDL, with logging and instrumentation of provider.
+	Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	// Ping verifies connectivity, failures are returned as *ConnectError.
+	Ping(ctx context.Context) error
+	// NearestNeighbors returns k models closest to embedding by pgvector metric.
+	NearestNeighbors(ctx context.Context, store skyorm.Store, prop skyorm.Prop, embedding []float32, k int, metric VectorMetric) ([]skyorm.Model, error)
+	// UpdateMany applies different values to many models with a single statement.
+	UpdateMany(ctx context.Context, store skyorm.Store, updates []PkValueSet) error
+	// FindWith finds models and preloads their relations.
+	FindWith(ctx context.Context, store skyorm.Store, condition skyorm.Cond, preload ...Relation) ([]skyorm.Model, error)
+	// FindJoin finds models of the store joined with other stores.
+	FindJoin(ctx context.Context, store skyorm.Store, joins []Join, condition skyorm.Cond, limit, offset int) ([]skyorm.Model, error)
+	// CatchUpRollup aggregates source models inserted since the last catch-up.
+	CatchUpRollup(ctx context.Context, r Rollup) (int64, error)
+	// RunRollups catches up rollups every interval until ctx is done.
+	RunRollups(ctx context.Context, interval time.Duration, rollups ...Rollup) error
+	// EnsureIndex creates index of the store if it doesn't exist.
+	EnsureIndex(ctx context.Context, store skyorm.Store, spec IndexSpec) error
+	// EnsureIndexes creates indexes registered for the store which don't exist.
+	EnsureIndexes(ctx context.Context, store skyorm.Store) error
+	// DropIndex drops index of the store if it exists.
+	DropIndex(ctx context.Context, store skyorm.Store, spec IndexSpec) error
+	// EnsureConstraints adds registered constraints and foreign keys of the store which don't exist.
+	EnsureConstraints(ctx context.Context, store skyorm.Store) error
+	// EnsureEnum creates the enum type if it doesn't exist and adds its missing values.
+	EnsureEnum(ctx context.Context, e Enum) error
+	// EnsureEnums ensures enum types of props of the store.
+	EnsureEnums(ctx context.Context, store skyorm.Store) error
+	// CreateGeoIndex creates composite index on latitude and longitude props.
+	CreateGeoIndex(ctx context.Context, store skyorm.Store, latProp, lngProp skyorm.Prop) error
+	// Associate links the model with pk to target models of many-to-many association.
+	Associate(ctx context.Context, a Association, pk interface{}, targetPks ...interface{}) error
+	// Dissociate unlinks the model with pk from target models of many-to-many association.
+	Dissociate(ctx context.Context, a Association, pk interface{}, targetPks ...interface{}) error
+	// FindRelated returns target models linked to the model with pk.
+	FindRelated(ctx context.Context, a Association, pk interface{}, limit, offset int) ([]skyorm.Model, error)
+	// FindDescendants returns descendants of the model in self-referencing store.
+	FindDescendants(ctx context.Context, store skyorm.Store, parentProp skyorm.Prop, pk interface{}, maxDepth int) ([]TreeNode, error)
+	// FindAncestors returns ancestors of the model in self-referencing store.
+	FindAncestors(ctx context.Context, store skyorm.Store, parentProp skyorm.Prop, pk interface{}, maxDepth int) ([]TreeNode, error)
+	// Queue returns job queue configured by cfg.
+	Queue(cfg QueueConfig) *Queue
+	// Scheduler returns cron scheduler persisting schedules into the table.
+	Scheduler(table string) *Scheduler
+	// Leases returns worker leases persisted into the table.
+	Leases(table string, ttl time.Duration) (*Leases, error)
+	// DocStore returns jsonb document store persisting documents into the table.
+	DocStore(table string) *DocStore
+	// Partitions returns partitions of a declaratively partitioned table configured by cfg.
+	Partitions(cfg PartitionConfig) *Partitions
+	// Hypertable returns TimescaleDB hypertable of the store partitioned by timeProp.
+	Hypertable(store skyorm.Store, timeProp skyorm.Prop) *Hypertable
+	// KV returns namespaced key-value store configured by cfg.
+	KV(cfg KVConfig) *KV
+	// LargeObjects returns large objects streamed in chunks of chunkSize bytes.
+	LargeObjects(chunkSize int) *LargeObjects
+	// FeatureFlags returns feature flag store persisting flags into kv.
+	FeatureFlags(kv *KV) *FeatureFlags
+	// Capabilities reports server version, installed extensions and available features.
+	Capabilities(ctx context.Context) (*Capabilities, error)
+	// Introspect returns tables of the database with their columns.
+	Introspect(ctx context.Context) ([]TableDef, error)
+	// Begin starts a transaction and returns provider bound to it.
+	Begin(ctx context.Context, opts *TxOptions) (Tx, error)
+	// RunInTx runs fn in a transaction, retrying it on serialization failures and deadlocks.
+	RunInTx(ctx context.Context, opts *TxOptions, fn func(tx skyorm.Provider) error) error
+	// Close flushes buffered writes and closes the database.
+	Close() error
+}
+
+// Order is an ordering of query results by property.
+type Order struct {
+	Prop skyorm.Prop
+	Desc bool
+}
+
+// Asc returns ascending order by property.
+func Asc(prop skyorm.Prop) Order {
+	return Order{Prop: prop}
+}
+
+// Desc returns descending order by property.
+func Desc(prop skyorm.Prop) Order {
+	return Order{Prop: prop, Desc: true}
+}
+
 // New returns new postgres provider.
-func New(dsn string, log skyorm.Logger) (skyorm.Provider, error) {
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, err
+func New(dsn string, log skyorm.Logger, opts ...Option) (Provider, error) {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	dsns := append([]string{dsn}, o.replicas...)
+	if o.binaryParameters {
+		for i := range dsns {
+			dsns[i] = withDSNParam(dsns[i], "binary_parameters", "yes")
+		}
+	}
+	if o.statementTimeout > 0 {
+		for i := range dsns {
+			dsns[i] = withDSNParam(dsns[i], "statement_timeout", timeoutMillis(o.statementTimeout))
+		}
+	}
+	var (
+		db        *sql.DB
+		connector *failoverConnector
+		err       error
+	)
+	switch {
+	case o.connector != nil:
+		db = sql.OpenDB(o.connector)
+	case o.healthInterval > 0:
+		if connector, err = newFailoverConnector(dsns); err != nil {
+			return nil, err
+		}
+		db = sql.OpenDB(connector)
+	default:
+		if db, err = sql.Open("postgres", dsns[0]); err != nil {
+			return nil, err
+		}
+	}
+	if o.pool != nil {
+		o.pool(db)
+	}
+	var replicas *replicaSet
+	if len(o.replicas) > 0 {
+		replicas = &replicaSet{policy: o.replicaPolicy, down: make([]int32, len(o.replicas))}
+		for _, rdsn := range dsns[1:] {
+			rdb, err := sql.Open("postgres", rdsn)
+			if err != nil {
+				replicas.close()
+				_ = db.Close()
+				return nil, err
+			}
+			if o.pool != nil {
+				o.pool(rdb)
+			}
+			replicas.dbs = append(replicas.dbs, rdb)
+		}
 	}
 	if log == nil {
 		log = skyorm.DefaultLogger
 	}
-	return &provider{db, log}, nil
+	if o.logger == nil {
+		o.logger = PrintfLogger(log)
+	}
+	if o.txAttempts <= 0 {
+		o.txAttempts = 5
+	}
+	if o.txBackoff <= 0 {
+		o.txBackoff = 10 * time.Millisecond
+	}
+	p := &provider{
+		db:           db,
+		conn:         db,
+		logger:       o.logger,
+		logLevel:     o.logLevel,
+		opLogLevels:  o.opLogLevels,
+		txAttempts:   o.txAttempts,
+		txBackoff:    o.txBackoff,
+		replicas:     replicas,
+		dsn:          dsns[0],
+		retry:        o.retry,
+		tracer:       o.tracer,
+		interceptors: o.interceptors,
+		metrics:      o.metrics,
+		metricsMeta:  o.metricsMeta,
+		queryHook:    o.queryHook,
+		slowQuery:    o.slowQuery,
+		redacted:     o.redacted,
+		commenter:    o.commenter,
+		maxRows:      o.maxRows,
+		truncateRows: o.truncateRows,
+		cache:        o.cache,
+		converters:   o.converters,
+		location:     o.location,
+		version:      &serverVersion{},
+	}
+	if o.breaker != nil {
+		p.breaker = &breaker{cfg: *o.breaker, windowStart: time.Now()}
+	}
+	if o.explainSlow {
+		p.explains = newSlowExplains()
+	}
+	if connector != nil {
+		if p.health, err = newHealthMonitor(p, connector, dsns, o.healthInterval); err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+	}
+	if o.metrics != nil {
+		p.pool = newPoolReporter(db, o.metrics, o.metricsInterval)
+	}
+	if o.async != nil {
+		p.async = newAsyncWriter(p, *o.async)
+	}
+	if o.pingTimeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), o.pingTimeout)
+		defer cancel()
+		if err = p.Ping(ctx); err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// conn is either the database or a transaction, queries of provider are run on it.
+type conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 type provider struct {
-	db     *sql.DB
-	logger skyorm.Logger
+	db           *sql.DB
+	conn         conn
+	logger       LeveledLogger
+	logLevel     Level
+	opLogLevels  map[string]Level
+	async        *asyncWriter
+	txAttempts   int
+	txBackoff    time.Duration
+	replicas     *replicaSet
+	health       *healthMonitor
+	dsn          string
+	retry        *RetryPolicy
+	breaker      *breaker
+	tracer       Tracer
+	interceptors []Interceptor
+	metrics      Metrics
+	metricsMeta  []string
+	pool         *poolReporter
+	queryHook    QueryHook
+	slowQuery    time.Duration
+	redacted     map[string]bool
+	commenter    *SQLCommenter
+	maxRows      int
+	truncateRows bool
+	explains     *slowExplains
+	version      *serverVersion
+	cache        *resultCache
+	converters   map[reflect.Type]Converter
+	location     *time.Location
+	// txWrites are stores written in transaction, their cached results are
+	// invalidated again on commit.
+	txWrites *[]string
 }
 
 func (p *provider) Put(ctx context.Context, models ...skyorm.Model) error {
 	for _, m := range models {
-		isSerial := isPkEmpty(m.OrmPk())
-		vl := len(m.OrmVals())
-		if isSerial {
-			vl--
-		}
-		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s",
-			m.OrmStore().Name(),
-			buildQueryProperties(m.OrmProps(), isSerial),
-			buildInsertPlaceholders(vl),
-			m.OrmPkProp().Name(),
-		)
-		values := m.OrmVals()
-		if isSerial {
-			values = make([]interface{}, 0, vl)
-			for i, v := range m.OrmVals() {
-				if m.OrmProps()[i].IsPk() {
-					continue
-				}
-				values = append(values, v)
+		if err := checkWritable(m.OrmStore()); err != nil {
+			return err
+		}
+		if err := beforeInsert(ctx, m); err != nil {
+			return err
+		}
+		if err := checkEnumModel(m); err != nil {
+			return err
+		}
+		// dry-run inserts are captured rather than buffered, and buffered writes
+		// don't keep ctx forcing pks.
+		if p.async != nil && p.async.accepts(m) && !dryRun(ctx) && !clientPks(ctx) {
+			if err := p.async.enqueue(ctx, m); err != nil {
+				return err
 			}
+			continue
+		}
+		if err := p.insert(ctx, m, "", ""); err != nil {
+			return err
 		}
-		p.logLn("PUT QUERY: %s", query)
-		row := p.db.QueryRowContext(ctx, query, values...)
-		if row.Err() != nil {
-			return row.Err()
+		if dryRun(ctx) {
+			continue
 		}
-		if err := row.Scan(m.OrmPkPointer()); err != nil {
+		p.invalidateCache(m.OrmStore())
+		identify(ctx, m)
+		if err := afterInsert(ctx, m); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// insert inserts the model with INSERT statement ending with onConflict clause
+// and scans the pk into it, followed by returning expressions into dest. It
+// fails with sql.ErrNoRows when conflicting model wasn't inserted.
+func (p *provider) insert(ctx context.Context, m skyorm.Model, onConflict, returning string, dest ...interface{}) error {
+	isSerial := pkOmitted(ctx, m)
+	props, values := storedVals(m, isSerial)
+	query := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)%s RETURNING %s",
+		p.table(ctx, m.OrmStore().Name()),
+		buildQueryProperties(props, false),
+		pkOverriding(m, isSerial),
+		buildValuePlaceholders(values),
+		onConflict,
+		quoteColumn(m.OrmPkProp().Name())+returning,
+	)
+	return p.queryRow(withOp(ctx, OpPut), query, bindColumns(props, values), append([]interface{}{m.OrmPkPointer()}, dest...)...)
+}
+
 func (p *provider) Populate(ctx context.Context, model skyorm.Model, pk interface{}) error {
+	if populateKnown(ctx, model, pk) {
+		return nil
+	}
 	query, args := buildWhere(
 		skyorm.Eq(model.OrmPkProp(), pk),
 		"SELECT %s FROM %s",
 		nil,
-		buildQueryProperties(model.OrmProps(), false),
-		model.OrmStore().Name(),
+		selectColumns(model.OrmStore(), selectedProps(ctx, model.OrmStore())),
+		p.tableAs(ctx, model.OrmStore().Name()),
 	)
-	p.logLn("GET QUERY: " + query)
-	return p.db.QueryRowContext(ctx, query, args...).Scan(model.OrmPointers()...)
+	if err := p.queryRow(forRead(withOp(ctx, OpPopulate)), query, args, selectedPointers(ctx, model)...); err != nil {
+		return err
+	}
+	if err := afterFind(ctx, model); err != nil {
+		return err
+	}
+	identify(ctx, model)
+	return nil
 }
 
 func (p *provider) Find(ctx context.Context, store skyorm.Store, condition skyorm.Cond, limit, offset int) ([]skyorm.Model, error) {
-	query, args := buildWhere(condition,
+	query, args := p.findSQL(ctx, store, condition)
+	query += p.limit(limit, offset)
+	query, args = withCTEs(ctx, query, args)
+	return p.findThrough(withOp(ctx, OpFind), store, condition, query, args)
+}
+
+// limit returns LIMIT and OFFSET clauses of finds, finds limited to more models
+// than WithMaxRows allows, or not limited at all, read a row more than allowed
+// to tell whether there are more models.
+func (p *provider) limit(limit, offset int) string {
+	if p.maxRows > 0 && (limit <= 0 || limit > p.maxRows) {
+		limit = p.maxRows + 1
+	}
+	if limit <= 0 {
+		if offset > 0 {
+			return fmt.Sprintf(" OFFSET %d", offset)
+		}
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+}
+
+// findThrough runs findQuery through the cache. Models truncated by WithMaxRows
+// are returned with ErrRowsTruncated and aren't cached, so every find of them
+// reports it.
+func (p *provider) findThrough(ctx context.Context, store skyorm.Store, condition skyorm.Cond, query string, args []interface{}) ([]skyorm.Model, error) {
+	var truncated []skyorm.Model
+	v, err := p.readThrough(ctx, store, condition, query, args, func() (interface{}, error) {
+		l, err := p.findQuery(ctx, store, query, args...)
+		if errors.Is(err, ErrRowsTruncated) {
+			truncated = l
+		}
+		return l, err
+	})
+	if truncated != nil {
+		return identifyAll(ctx, truncated), err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return identifyAll(ctx, v.([]skyorm.Model)), nil
+}
+
+// findSQL returns query of Find selecting all props of models matching condition.
+func (p *provider) findSQL(ctx context.Context, store skyorm.Store, condition skyorm.Cond) (string, []interface{}) {
+	return buildWhere(p.bindCond(ctx, condition),
 		"SELECT %s FROM %s",
 		nil,
-		buildQueryProperties(store.Props(), false),
-		store.Name(),
+		selectColumns(store, selectedProps(ctx, store)),
+		p.tableAs(ctx, store.Name()),
 	)
-	if limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
-	}
-	p.logLn("FIND QUERY: %s", query)
-	res, err := p.db.QueryContext(ctx, query, args...)
+}
+
+// findQuery runs query selecting all props of the store and scans the models.
+// The first models are returned with ErrRowsTruncated when there are more than
+// WithMaxRows allows and truncate is set.
+func (p *provider) findQuery(ctx context.Context, store skyorm.Store, query string, args ...interface{}) ([]skyorm.Model, error) {
+	res, err := p.query(forRead(ctx), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -95,88 +455,470 @@ func (p *provider) Find(ctx context.Context, store skyorm.Store, condition skyor
 	}()
 	l := make([]skyorm.Model, 0)
 	for res.Next() {
+		if p.maxRows > 0 && len(l) == p.maxRows {
+			if err = p.maxRowsError(store); !p.truncateRows {
+				return nil, err
+			}
+			return l, err
+		}
 		m := store.Model()
-		if err = res.Scan(m.OrmPointers()...); err != nil {
+		if err = res.Scan(p.scanPointers(selectedPointers(ctx, m))...); err != nil {
 			return nil, err
 		}
-		l = append(l, m)
+		if err = afterFind(ctx, m); err != nil {
+			return nil, err
+		}
+		l = append(l, identify(ctx, m))
 	}
-	return l, nil
+	// rows end early when the query is cancelled by context or statement timeout.
+	return l, res.Err()
+}
+
+// maxRowsError returns error of find of more models of the store than
+// WithMaxRows allows, ErrRowsTruncated when truncate is set and ErrTooManyRows
+// otherwise.
+func (p *provider) maxRowsError(store skyorm.Store) error {
+	if !p.truncateRows {
+		return fmt.Errorf("%w: more than %d models of %s", ErrTooManyRows, p.maxRows, store.Name())
+	}
+	p.logf(LevelWarn, "FIND TRUNCATED: more than %d models of %s", p.maxRows, store.Name())
+	return fmt.Errorf("%w: first %d models of %s", ErrRowsTruncated, p.maxRows, store.Name())
+}
+
+// ErrTooManyRows is returned by finds of more models than WithMaxRows allows.
+var ErrTooManyRows = errors.New("postgres: too many rows")
+
+// ErrRowsTruncated is returned along with the first models by finds of more
+// models than WithMaxRows allows when truncate is set.
+var ErrRowsTruncated = errors.New("postgres: rows truncated")
+
+func (p *provider) FindOne(ctx context.Context, store skyorm.Store, condition skyorm.Cond, order ...Order) (skyorm.Model, error) {
+	query, args := buildWhere(p.bindCond(ctx, condition),
+		"SELECT %s FROM %s",
+		nil,
+		selectColumns(store, selectedProps(ctx, store)),
+		p.tableAs(ctx, store.Name()),
+	)
+	query += buildOrder(order) + " LIMIT 1"
+	query, args = withCTEs(ctx, query, args)
+	ctx = withOp(ctx, OpFindOne)
+	v, err := p.readThrough(ctx, store, condition, query, args, func() (interface{}, error) {
+		m := store.Model()
+		if err := p.queryRow(forRead(ctx), query, args, selectedPointers(ctx, m)...); err != nil {
+			return nil, err
+		}
+		return m, afterFind(ctx, m)
+	})
+	if err == sql.ErrNoRows {
+		return nil, p.ErrNotFound()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return identify(ctx, v.(skyorm.Model)), nil
 }
 
 func (p *provider) Update(ctx context.Context, store skyorm.Store, condition skyorm.Cond, values ...skyorm.Val) error {
+	if err := checkWritable(store); err != nil {
+		return err
+	}
+	h := storeHooks(store)
+	if h.BeforeUpdate != nil {
+		var err error
+		if values, err = h.BeforeUpdate(ctx, condition, values); err != nil {
+			return err
+		}
+	}
+	if err := checkEnumVals(store, values); err != nil {
+		return err
+	}
+	if err := checkStoredVals(store, values); err != nil {
+		return err
+	}
 	cursor, updateString, updateValues := buildUpdateProps(values...)
-	p.logLn("%d %s", cursor, updateString)
+	p.logf(LevelDebug, "%d %s", cursor, updateString)
 	query, args := buildWhere(
-		condition,
+		p.bindCond(ctx, condition),
 		"UPDATE %s SET %s",
 		&cursor,
-		store.Name(),
+		p.tableAs(ctx, store.Name()),
 		updateString,
 	)
 	for _, arg := range args {
 		updateValues = append(updateValues, arg)
 	}
-	p.logLn("UPDATE QUERY: %s", query)
-	if _, err := p.db.ExecContext(ctx, query, updateValues...); err != nil {
+	query, updateValues = withCTEs(ctx, query, updateValues)
+	if _, err := p.exec(withOp(ctx, OpUpdate), query, updateValues...); err != nil {
 		return err
 	}
+	if dryRun(ctx) {
+		return nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	if h.AfterUpdate != nil {
+		return h.AfterUpdate(ctx, condition, values)
+	}
 	return nil
 }
 
 func (p *provider) Delete(ctx context.Context, store skyorm.Store, condition skyorm.Cond) error {
-	query, args := buildWhere(condition, "DELETE FROM %s", nil, store.Name())
-	p.logLn("DELETE QUERY: %s", query)
-	if _, err := p.db.ExecContext(ctx, query, args...); err != nil {
+	if err := checkWritable(store); err != nil {
 		return err
 	}
+	h := storeHooks(store)
+	if h.BeforeDelete != nil {
+		if err := h.BeforeDelete(ctx, condition); err != nil {
+			return err
+		}
+	}
+	query, args := buildWhere(p.bindCond(ctx, condition), "DELETE FROM %s", nil, p.tableAs(ctx, store.Name()))
+	query, args = withCTEs(ctx, query, args)
+	if _, err := p.exec(withOp(ctx, OpDelete), query, args...); err != nil {
+		return err
+	}
+	if dryRun(ctx) {
+		return nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	if h.AfterDelete != nil {
+		return h.AfterDelete(ctx, condition)
+	}
 	return nil
 }
 
+// scanModels scans models of the store from rows and closes them. Unlike
+// finds, models aren't limited, notified of finds or identified.
+func (p *provider) scanModels(store skyorm.Store, res *rows) ([]skyorm.Model, error) {
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]skyorm.Model, 0)
+	for res.Next() {
+		m := store.Model()
+		if err := res.Scan(p.scanPointers(m.OrmPointers())...); err != nil {
+			return nil, err
+		}
+		l = append(l, m)
+	}
+	return l, res.Err()
+}
+
+// DeleteReturning deletes models of the store matching condition and returns
+// them, e.g. for audit logging, without a preceding find. Deleted models are
+// forgotten by identity map of ctx. Dry-run deletes return no models.
+func (p *provider) DeleteReturning(ctx context.Context, store skyorm.Store, condition skyorm.Cond) ([]skyorm.Model, error) {
+	if err := checkWritable(store); err != nil {
+		return nil, err
+	}
+	h := storeHooks(store)
+	if h.BeforeDelete != nil {
+		if err := h.BeforeDelete(ctx, condition); err != nil {
+			return nil, err
+		}
+	}
+	query, args := buildWhere(p.bindCond(ctx, condition), "DELETE FROM %s", nil, p.tableAs(ctx, store.Name()))
+	query, args = withCTEs(ctx, query+" RETURNING "+selectColumns(store, store.Props()), args)
+	res, err := p.query(withOp(ctx, OpDelete), query, args...)
+	if dryRun(ctx) && errors.Is(err, ErrDryRun) {
+		// nothing is deleted, so nothing is returned.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l, err := p.scanModels(store, res)
+	if err != nil {
+		return nil, err
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	if h.AfterDelete != nil {
+		return l, h.AfterDelete(ctx, condition)
+	}
+	return l, nil
+}
+
+// Truncate removes all models of the store, e.g. for test fixtures and data
+// resets, resetting sequences of its serial and identity columns when
+// restartIdentity is set and truncating tables referencing it when cascade is.
+// Delete hooks run with nil condition, matching all models. Hooks of stores of
+// tables truncated by cascade don't run.
+func (p *provider) Truncate(ctx context.Context, store skyorm.Store, restartIdentity, cascade bool) error {
+	if err := checkWritable(store); err != nil {
+		return err
+	}
+	h := storeHooks(store)
+	if h.BeforeDelete != nil {
+		if err := h.BeforeDelete(ctx, nil); err != nil {
+			return err
+		}
+	}
+	query := "TRUNCATE " + p.table(ctx, store.Name())
+	if restartIdentity {
+		query += " RESTART IDENTITY"
+	}
+	if cascade {
+		query += " CASCADE"
+	}
+	if _, err := p.exec(withOp(ctx, OpDelete), query); err != nil {
+		return err
+	}
+	if dryRun(ctx) {
+		return nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	if h.AfterDelete != nil {
+		return h.AfterDelete(ctx, nil)
+	}
+	return nil
+}
+
+// DeleteInBatches deletes models of the store matching condition by statements
+// deleting up to batchSize rows each until there are none, so purges of many rows
+// don't hold locks for long or bloat tables all at once. Each batch commits on its
+// own outside of a transaction. Rows of partitions of a partitioned table may
+// share ctid, so batches may delete fewer rows of them, and deleting stops only
+// when a batch deletes none. It returns number of
+// deleted rows, including the ones of batches before an error.
+func (p *provider) DeleteInBatches(ctx context.Context, store skyorm.Store, condition skyorm.Cond, batchSize int) (int64, error) {
+	if err := checkWritable(store); err != nil {
+		return 0, err
+	}
+	if batchSize <= 0 {
+		return 0, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	h := storeHooks(store)
+	if h.BeforeDelete != nil {
+		if err := h.BeforeDelete(ctx, condition); err != nil {
+			return 0, err
+		}
+	}
+	table := p.tableAs(ctx, store.Name())
+	sub, args := buildWhere(p.bindCond(ctx, condition), "SELECT ctid FROM %s", nil, table)
+	query := fmt.Sprintf("DELETE FROM %s WHERE ctid IN (%s LIMIT %d)", table, sub, batchSize)
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		res, err := p.exec(withOp(ctx, OpDelete), query, args...)
+		if err != nil {
+			return total, err
+		}
+		if dryRun(ctx) {
+			return 0, nil
+		}
+		forgetStore(ctx, store)
+		p.invalidateCache(store)
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n == 0 {
+			break
+		}
+	}
+	if h.AfterDelete != nil {
+		return total, h.AfterDelete(ctx, condition)
+	}
+	return total, nil
+}
+
 func (p *provider) Count(ctx context.Context, store skyorm.Store, condition skyorm.Cond) (int64, error) {
 	query, args := buildWhere(
-		condition,
+		p.bindCond(ctx, condition),
 		"SELECT COUNT(%s) AS cnt FROM %s",
 		nil,
-		store.Pk().Name(),
-		store.Name(),
+		quoteColumn(store.Pk().Name()),
+		p.tableAs(ctx, store.Name()),
 	)
-	row := p.db.QueryRowContext(ctx, query, args...)
-	if err := row.Err(); err != nil {
+	query, args = withCTEs(ctx, query, args)
+	ctx = withOp(ctx, OpCount)
+	v, err := p.readThrough(ctx, store, condition, query, args, func() (interface{}, error) {
+		var cnt int64
+		err := p.queryRow(forRead(ctx), query, args, &cnt)
+		return cnt, err
+	})
+	if err != nil {
 		return 0, err
 	}
-	var cnt int64
-	if err := row.Scan(&cnt); err != nil {
-		return 0, err
+	return v.(int64), nil
+}
+
+func (p *provider) Exists(ctx context.Context, store skyorm.Store, condition skyorm.Cond) (bool, error) {
+	query, args := buildWhere(
+		p.bindCond(ctx, condition),
+		"SELECT 1 FROM %s",
+		nil,
+		p.tableAs(ctx, store.Name()),
+	)
+	query = "SELECT EXISTS(" + query + ")"
+	ctx = withOp(ctx, OpExists)
+	var exists bool
+	if err := p.queryRow(forRead(ctx), query, args, &exists); err != nil {
+		return false, err
 	}
-	return cnt, nil
+	return exists, nil
+}
+
+func (p *provider) Close() error {
+	if p.async != nil {
+		p.async.close()
+	}
+	if p.health != nil {
+		p.health.close()
+	}
+	if p.pool != nil {
+		p.pool.close()
+	}
+	if p.replicas != nil {
+		p.replicas.close()
+	}
+	return p.db.Close()
+}
+
+func (p *provider) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.exec(ctx, query, args...)
 }
 
 func (p *provider) ErrNotFound() error {
 	return sql.ErrNoRows
 }
 
-func (p *provider) logLn(format string, v ...interface{}) {
-	p.logger.Printf(format+"\n", v...)
+func (p *provider) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var res sql.Result = driver.RowsAffected(0)
+	err := p.run(ctx, query, args, true, func(ctx context.Context, c conn, query string, args []interface{}) (int64, error) {
+		var err error
+		if res, err = c.ExecContext(ctx, query, args...); err != nil {
+			return -1, err
+		}
+		if n, err := res.RowsAffected(); err == nil {
+			return n, nil
+		}
+		return -1, nil
+	})
+	return res, err
+}
+
+func (p *provider) query(ctx context.Context, query string, args ...interface{}) (*rows, error) {
+	var res *rows
+	err := p.run(ctx, query, args, false, func(ctx context.Context, c conn, query string, args []interface{}) (int64, error) {
+		r, err := c.QueryContext(ctx, query, args...)
+		if err != nil {
+			return -1, err
+		}
+		res = &rows{Rows: r}
+		if st, ok := c.(*sessionTx); ok {
+			st.kept = true
+			res.tx = st.Tx
+		}
+		// rows are read by the caller after the query is done.
+		return -1, nil
+	})
+	if err == nil && res == nil {
+		// query was captured in dry-run mode.
+		err = ErrDryRun
+	}
+	return res, err
+}
+
+// queryRow runs query expected to return a single row and scans it into dest.
+func (p *provider) queryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	return p.run(ctx, query, args, false, func(ctx context.Context, c conn, query string, args []interface{}) (int64, error) {
+		err := c.QueryRowContext(ctx, query, args...).Scan(p.scanPointers(dest)...)
+		switch err {
+		case nil:
+			return 1, nil
+		case sql.ErrNoRows:
+			return 0, err
+		}
+		return -1, err
+	})
+}
+
+// run runs query with do through interceptors, budget, circuit breaker, retries,
+// tracing and logging. do receives connection of the query, which is wrapped in
+// a transaction setting session variables of ctx, and annotated query with bound
+// arguments, runs it once and returns the number of rows, -1 when it's unknown.
+func (p *provider) run(ctx context.Context, query string, args []interface{}, write bool,
+	do func(ctx context.Context, c conn, query string, args []interface{}) (int64, error)) error {
+	args, columns := unbindColumns(args)
+	q := &QueryInfo{Op: queryOp(ctx, query), Query: query, Args: args}
+	ctx, n, err := p.interceptBefore(ctx, q)
+	if err != nil {
+		p.interceptAfter(ctx, q, n, 0, err)
+		return err
+	}
+	columns = p.rebindColumns(args, q.Args, columns)
+	query, args = q.Query, q.Args
+	if captureDryRun(ctx, q) {
+		if read, _ := ctx.Value(readKey{}).(bool); read {
+			err = ErrDryRun
+		}
+		p.interceptAfter(ctx, q, n, 0, err)
+		return err
+	}
+	if err = p.beforeQuery(ctx, query); err != nil {
+		p.interceptAfter(ctx, q, n, 0, err)
+		return err
+	}
+	ctx, span := p.startSpan(ctx, query)
+	start := time.Now()
+	rows := int64(-1)
+	var used conn
+	err = p.withRetry(ctx, write, func() error {
+		// retries don't reach the database once the breaker opens.
+		if p.breaker != nil {
+			if err := p.breaker.allow(); err != nil {
+				return err
+			}
+		}
+		attemptStart := time.Now()
+		c := p.conn
+		if !write {
+			c = p.connFor(ctx)
+		}
+		used = c
+		var err error
+		rows, err = inSession(ctx, c, func(c conn) (int64, error) {
+			return do(ctx, c, p.annotate(ctx, query), p.bindValues(args))
+		})
+		p.afterQuery(ctx, query, attemptStart, err)
+		return err
+	})
+	d := time.Since(start)
+	endSpan(span, rows, err)
+	p.logQuery(ctx, query, args, columns, d, rows, err)
+	if p.explains != nil && p.slowQuery > 0 && d >= p.slowQuery && err == nil {
+		// rows of queries are read by the caller after run, other statements report rows.
+		p.explainSlowQuery(ctx, used, !write && rows < 0, query, p.bindValues(args))
+	}
+	p.interceptAfter(ctx, q, n, d, err)
+	return err
+}
+
+func (p *provider) beforeQuery(ctx context.Context, query string) error {
+	p.detectNPlusOne(ctx, query)
+	return p.checkBudget(ctx)
+}
+
+func (p *provider) afterQuery(ctx context.Context, query string, start time.Time, err error) {
+	d := time.Since(start)
+	spendBudget(ctx, d)
+	p.recordBreaker(d, err)
+	p.recordMetrics(ctx, queryOp(ctx, query), d, err)
 }
 
 var (
 	emptyInterfaceSlice = make([]interface{}, 0, 1)
 )
 
-func isPkEmpty(pk interface{}) bool {
-	switch pk.(type) {
-	case string:
-		return pk.(string) == ""
-	case int, int8, int16, int32, int64:
-		return pk.(int64) == 0
-	case uint, uint8, uint16, uint32, uint64:
-		return pk.(uint64) == 0
-	case float32, float64:
-		return pk.(float64) == 0
-	}
-	return false
-}
+// maxBindParams is the maximum number of bind parameters of a statement.
+const maxBindParams = 65535
 
 func buildWhere(condition skyorm.Cond, query string, n *int, queryValues ...interface{}) (string, []interface{}) {
 	condWhere, condValues := parseCond(condition, n)
@@ -187,18 +929,51 @@ func buildWhere(condition skyorm.Cond, query string, n *int, queryValues ...inte
 	return fmt.Sprintf(query, queryValues...), condValues
 }
 
+func buildOrder(order []Order) string {
+	if len(order) == 0 {
+		return ""
+	}
+	l := make([]string, len(order))
+	for i, o := range order {
+		l[i] = quoteColumn(o.Prop.Name())
+		if o.Desc {
+			l[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(l, ", ")
+}
+
 func buildUpdateProps(values ...skyorm.Val) (int, string, []interface{}) {
 	var (
 		ls = make([]string, len(values))
-		lv = make([]interface{}, len(values))
-		i  = 0
-		v  skyorm.Val
+		lv = make([]interface{}, 0, len(values))
+		n  = 1
 	)
-	for i, v = range values {
-		ls[i] = v.Prop().Name() + " = $" + strconv.Itoa(i+1)
-		lv[i] = v.Val()
+	for i, v := range values {
+		expr, bound := buildValExpr(v, n)
+		ls[i] = quoteColumn(v.Prop().Name()) + " = " + expr
+		if bound {
+			lv = append(lv, bindColumn(v.Prop(), v.Val()))
+			n++
+		}
+	}
+	return n, strings.Join(ls, ", "), lv
+}
+
+// buildValExpr returns SQL expression of update value using placeholder n,
+// bound is false when the value has no placeholder.
+func buildValExpr(v skyorm.Val, n int) (expr string, bound bool) {
+	if e, ok := v.(*exprVal); ok && e.noArg {
+		return e.expr(""), false
+	}
+	ph := "$" + strconv.Itoa(n)
+	if e, ok := v.(*exprVal); ok {
+		return e.expr(ph), true
+	}
+	if e, ok := v.Val().(valueExpr); ok {
+		return e.expr(ph), true
 	}
-	return i + 2, strings.Join(ls, ", "), lv
+	return ph, true
 }
 
 func buildQueryProperties(properties []skyorm.Prop, isSerial bool) string {
@@ -212,7 +987,7 @@ func buildQueryProperties(properties []skyorm.Prop, isSerial bool) string {
 		if isSerial && p.IsPk() {
 			continue
 		}
-		l[c] = p.Name()
+		l[c] = quoteColumn(p.Name())
 		c++
 	}
 	return strings.Join(l, ", ")
@@ -226,6 +1001,18 @@ func buildInsertPlaceholders(n int) string {
 	return strings.Join(l, ", ")
 }
 
+// buildValuePlaceholders returns placeholders of values, wrapping the ones of value expressions.
+func buildValuePlaceholders(values []interface{}) string {
+	l := make([]string, len(values))
+	for i, v := range values {
+		l[i] = "$" + strconv.Itoa(i+1)
+		if e, ok := v.(valueExpr); ok {
+			l[i] = e.expr(l[i])
+		}
+	}
+	return strings.Join(l, ", ")
+}
+
 func newN() *int {
 	n := 1
 	return &n
@@ -238,6 +1025,12 @@ func parseCond(c skyorm.Cond, n *int) (string, []interface{}) {
 	if n == nil {
 		n = newN()
 	}
+	if e, ok := c.(*exprCond); ok {
+		return e.build(n)
+	}
+	if t, ok := c.(*tableCond); ok {
+		return t.build(n)
+	}
 	if c.Type() == skyorm.CondTypeAnd || c.Type() == skyorm.CondTypeOr {
 		sl := make([]string, 0)
 		vl := make([]interface{}, 0)
@@ -246,11 +1039,13 @@ func parseCond(c skyorm.Cond, n *int) (string, []interface{}) {
 			sep = " OR "
 		}
 		s, v := parseCondChildren(c.Children(), sep, n)
-		sl = append(sl, s)
 		vl = append(vl, v...)
-		if len(vl) == 0 {
+		// groups are dropped only when they render nothing, conditions without
+		// args, e.g. InCTE or Exists, still filter.
+		if s == "" {
 			return "", vl
 		}
+		sl = append(sl, s)
 		return strings.Join(sl, sep), vl
 	} else {
 		s, v := parseRegularCond(c, n)
@@ -262,28 +1057,33 @@ func parseRegularCond(c skyorm.Cond, n *int) (string, interface{}) {
 	*n++
 	switch c.Type() {
 	case skyorm.CondTypeEq:
-		return c.Prop().Name() + " = $" + strconv.Itoa(*n-1), c.Val()
+		return quoteColumn(c.Prop().Name()) + " = $" + strconv.Itoa(*n-1), bindColumn(c.Prop(), c.Val())
 	case skyorm.CondTypeNeq:
-		return c.Prop().Name() + " <> $" + strconv.Itoa(*n-1), c.Val()
+		return quoteColumn(c.Prop().Name()) + " <> $" + strconv.Itoa(*n-1), bindColumn(c.Prop(), c.Val())
 	case skyorm.CondTypeLt:
-		return c.Prop().Name() + " < $" + strconv.Itoa(*n-1), c.Val()
+		return quoteColumn(c.Prop().Name()) + " < $" + strconv.Itoa(*n-1), bindColumn(c.Prop(), c.Val())
 	case skyorm.CondTypeLte:
-		return c.Prop().Name() + " <= $" + strconv.Itoa(*n-1), c.Val()
+		return quoteColumn(c.Prop().Name()) + " <= $" + strconv.Itoa(*n-1), bindColumn(c.Prop(), c.Val())
 	case skyorm.CondTypeGt:
-		return c.Prop().Name() + " > $" + strconv.Itoa(*n-1), c.Val()
+		return quoteColumn(c.Prop().Name()) + " > $" + strconv.Itoa(*n-1), bindColumn(c.Prop(), c.Val())
 	case skyorm.CondTypeGte:
-		return c.Prop().Name() + " >= $" + strconv.Itoa(*n-1), c.Val()
+		return quoteColumn(c.Prop().Name()) + " >= $" + strconv.Itoa(*n-1), bindColumn(c.Prop(), c.Val())
 	}
 	return "", nil
 }
 
 func parseCondChildren(children []skyorm.Cond, sep string, n *int) (string, []interface{}) {
-	cl := make([]string, len(children))
+	cl := make([]string, 0, len(children))
 	vl := make([]interface{}, 0)
-	for i, child := range children {
+	for _, child := range children {
 		c, v := parseCond(child, n)
-		cl[i] = c
 		vl = append(vl, v...)
+		if c != "" {
+			cl = append(cl, c)
+		}
+	}
+	if len(cl) == 0 {
+		return "", vl
 	}
 	return "(" + strings.Join(cl, sep) + ")", vl
 }
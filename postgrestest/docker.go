@@ -0,0 +1,176 @@
+package postgrestest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// dockerClient runs containers through Docker Engine API, so harness needs
+// neither docker CLI nor client libraries.
+type dockerClient struct {
+	http *http.Client
+	base string
+}
+
+// newDockerClient returns client of the daemon of DOCKER_HOST, the local
+// socket by default.
+func newDockerClient() (*dockerClient, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = "unix:///var/run/docker.sock"
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKER_HOST %q: %w", host, err)
+	}
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		return &dockerClient{
+			http: &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", path)
+				},
+			}},
+			base: "http://docker",
+		}, nil
+	case "tcp", "http":
+		return &dockerClient{http: http.DefaultClient, base: "http://" + u.Host}, nil
+	}
+	return nil, fmt.Errorf("unsupported DOCKER_HOST %q", host)
+}
+
+// do sends request with JSON body to the API, decodes JSON response into out
+// and returns its status code. Responses of failed requests are errors, except
+// 404 which is returned for callers to handle.
+func (c *dockerClient) do(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return 0, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("docker %s %s: %w", method, path, err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode == http.StatusNotFound {
+		return res.StatusCode, nil
+	}
+	if res.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&e)
+		return res.StatusCode, fmt.Errorf("docker %s %s: %s: %s", method, path, res.Status, e.Message)
+	}
+	if out != nil {
+		return res.StatusCode, json.NewDecoder(res.Body).Decode(out)
+	}
+	// e.g. progress of pulls, which ends when the pull does.
+	dec := json.NewDecoder(res.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err = dec.Decode(&msg); err == io.EOF {
+			return res.StatusCode, nil
+		} else if err != nil {
+			return res.StatusCode, err
+		}
+		if msg.Error != "" {
+			return res.StatusCode, fmt.Errorf("docker %s %s: %s", method, path, msg.Error)
+		}
+	}
+}
+
+// run starts container of postgres image, pulling the image when it's missing,
+// and returns its id and address of its port published on localhost.
+func (c *dockerClient) run(ctx context.Context, image string) (id, addr string, err error) {
+	spec := map[string]interface{}{
+		"Image":        image,
+		"Env":          []string{"POSTGRES_PASSWORD=postgres"},
+		"ExposedPorts": map[string]interface{}{"5432/tcp": struct{}{}},
+		"HostConfig": map[string]interface{}{
+			"AutoRemove": true,
+			"PortBindings": map[string]interface{}{
+				"5432/tcp": []map[string]string{{"HostIp": "127.0.0.1", "HostPort": ""}},
+			},
+		},
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	status, err := c.do(ctx, http.MethodPost, "/containers/create", spec, &created)
+	if err != nil {
+		return "", "", err
+	}
+	if status == http.StatusNotFound {
+		if err = c.pull(ctx, image); err != nil {
+			return "", "", err
+		}
+		if _, err = c.do(ctx, http.MethodPost, "/containers/create", spec, &created); err != nil {
+			return "", "", err
+		}
+	}
+	id = created.ID
+	if _, err = c.do(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil); err != nil {
+		_ = c.remove(context.Background(), id)
+		return "", "", err
+	}
+	var inspected struct {
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string `json:"HostPort"`
+			}
+		}
+	}
+	if _, err = c.do(ctx, http.MethodGet, "/containers/"+id+"/json", nil, &inspected); err != nil {
+		_ = c.remove(context.Background(), id)
+		return "", "", err
+	}
+	bindings := inspected.NetworkSettings.Ports["5432/tcp"]
+	if len(bindings) == 0 {
+		_ = c.remove(context.Background(), id)
+		return "", "", fmt.Errorf("docker container %s has no published port", id)
+	}
+	return id, net.JoinHostPort(bindings[0].HostIP, bindings[0].HostPort), nil
+}
+
+// pull pulls the image, e.g. postgres:15-alpine.
+func (c *dockerClient) pull(ctx context.Context, image string) error {
+	ref, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		ref, tag = image[:i], image[i+1:]
+	}
+	_, err := c.do(ctx, http.MethodPost, "/images/create?fromImage="+url.QueryEscape(ref)+"&tag="+url.QueryEscape(tag), nil, nil)
+	return err
+}
+
+// remove removes the container, stopping it.
+func (c *dockerClient) remove(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/containers/"+id+"?force=true", nil, nil)
+	return err
+}
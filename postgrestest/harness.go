@@ -0,0 +1,194 @@
+package postgrestest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/skyorm"
+)
+
+// Config configures integration test harness.
+type Config struct {
+	// DSN of an existing server to use instead of a container, POSTGRES_TEST_DSN
+	// environment variable by default.
+	DSN string
+	// Image is the docker image of the container, postgres:15-alpine by default.
+	// Containers are run by the daemon of DOCKER_HOST, the local socket by default.
+	Image string
+	// Schema are stores which tables, views, enums, constraints and indexes are
+	// created with GenerateDDL after the server is up. DDL is skipped when all
+	// of them exist, e.g. on an existing server, and constraints and indexes of
+	// the stores are ensured instead.
+	Schema []skyorm.Store
+	// SQL are statements run after Schema, e.g. functions or seed data.
+	SQL []string
+	// Options are passed to postgres.New.
+	Options []postgres.Option
+	// StartTimeout limits waiting for the server, 1 minute by default.
+	StartTimeout time.Duration
+}
+
+// Harness is a disposable postgres server with provider connected to it.
+type Harness struct {
+	DSN      string
+	Provider postgres.Provider
+	// container is id of docker container, empty when existing server is used.
+	container string
+	docker    *dockerClient
+}
+
+// Start starts postgres in a docker container, unless DSN of an existing server
+// is configured, and applies schema. It's meant to be called from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		h, err := postgrestest.Start(context.Background(), postgrestest.Config{Schema: stores})
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		code := m.Run()
+//		_ = h.Close()
+//		os.Exit(code)
+//	}
+func Start(ctx context.Context, cfg Config) (*Harness, error) {
+	if cfg.DSN == "" {
+		cfg.DSN = os.Getenv("POSTGRES_TEST_DSN")
+	}
+	if cfg.Image == "" {
+		cfg.Image = "postgres:15-alpine"
+	}
+	if cfg.StartTimeout <= 0 {
+		cfg.StartTimeout = time.Minute
+	}
+	h := &Harness{DSN: cfg.DSN}
+	if h.DSN == "" {
+		if err := h.runContainer(ctx, cfg.Image); err != nil {
+			return nil, err
+		}
+	}
+	if err := h.connect(ctx, cfg); err != nil {
+		_ = h.Close()
+		return nil, err
+	}
+	if err := h.applySchema(ctx, cfg.Schema); err != nil {
+		_ = h.Close()
+		return nil, fmt.Errorf("apply schema: %w", err)
+	}
+	for _, stmt := range cfg.SQL {
+		if _, err := h.Provider.Exec(ctx, stmt); err != nil {
+			_ = h.Close()
+			return nil, fmt.Errorf("apply schema: %w", err)
+		}
+	}
+	return h, nil
+}
+
+func (h *Harness) runContainer(ctx context.Context, image string) error {
+	c, err := newDockerClient()
+	if err != nil {
+		return err
+	}
+	id, addr, err := c.run(ctx, image)
+	if err != nil {
+		return err
+	}
+	h.container, h.docker = id, c
+	h.DSN = fmt.Sprintf("postgres://postgres:postgres@%s/postgres?sslmode=disable", addr)
+	return nil
+}
+
+// applySchema creates tables of the stores with their DDL, unless all of them
+// exist, as foreign keys of the DDL can't be added twice, and ensures their
+// constraints and indexes.
+func (h *Harness) applySchema(ctx context.Context, stores []skyorm.Store) error {
+	if len(stores) == 0 {
+		return nil
+	}
+	exists := true
+	for _, s := range stores {
+		_, err := h.Provider.Exists(ctx, s, nil)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "42P01" {
+			// undefined_table.
+			exists = false
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if !exists {
+		ddl, err := postgres.GenerateDDL(stores...)
+		if err != nil {
+			return err
+		}
+		if _, err = h.Provider.Exec(ctx, ddl); err != nil {
+			return err
+		}
+	}
+	for _, s := range stores {
+		if err := h.Provider.EnsureConstraints(ctx, s); err != nil {
+			return err
+		}
+		if err := h.Provider.EnsureIndexes(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connect connects provider, waiting for the server to accept connections.
+func (h *Harness) connect(ctx context.Context, cfg Config) error {
+	p, err := postgres.New(h.DSN, nil, cfg.Options...)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(cfg.StartTimeout)
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, time.Second)
+		err = p.Ping(pingCtx)
+		cancel()
+		if err == nil {
+			h.Provider = p
+			return nil
+		}
+		if time.Now().After(deadline) || ctx.Err() != nil {
+			_ = p.Close()
+			return err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// Tx returns transaction of the provider which is rolled back when the test
+// finishes, so tests using their own transactions don't see each other's data.
+func (h *Harness) Tx(t testing.TB) postgres.Tx {
+	t.Helper()
+	tx, err := h.Provider.Begin(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("begin test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = tx.Rollback()
+	})
+	return tx
+}
+
+// Close closes provider and removes the container.
+func (h *Harness) Close() error {
+	var err error
+	if h.Provider != nil {
+		err = h.Provider.Close()
+	}
+	if h.container != "" {
+		if rmErr := h.docker.remove(context.Background(), h.container); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+package postgrestest_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+// freeAddr returns address nothing listens on.
+func freeAddr(t *testing.T) (string, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = l.Close()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	return host, port
+}
+
+func TestStartRunsContainerWithDockerAPI(t *testing.T) {
+	host, port := freeAddr(t)
+	var (
+		mu       sync.Mutex
+		requests []string
+		pulled   bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.URL.Path == "/containers/create" && !pulled:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"No such image: postgres:15-alpine"}`))
+		case r.URL.Path == "/images/create":
+			pulled = true
+			_, _ = w.Write([]byte(`{"status":"Pulling"}` + "\n" + `{"status":"Downloaded"}`))
+		case r.URL.Path == "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"Id":"c1"}`))
+		case r.URL.Path == "/containers/c1/start", r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/containers/c1/json":
+			_, _ = w.Write([]byte(`{"NetworkSettings":{"Ports":{"5432/tcp":[{"HostIp":"` + host + `","HostPort":"` + port + `"}]}}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	defer os.Setenv("DOCKER_HOST", os.Getenv("DOCKER_HOST"))
+	defer os.Setenv("POSTGRES_TEST_DSN", os.Getenv("POSTGRES_TEST_DSN"))
+	_ = os.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(srv.URL, "http://"))
+	_ = os.Unsetenv("POSTGRES_TEST_DSN")
+	// nothing listens on the published port, so the container is removed.
+	_, err := postgrestest.Start(context.Background(), postgrestest.Config{StartTimeout: time.Millisecond})
+	if err == nil {
+		t.Fatal("connected to unreachable server")
+	}
+	want := []string{
+		"POST /containers/create",
+		"POST /images/create?fromImage=postgres&tag=15-alpine",
+		"POST /containers/create",
+		"POST /containers/c1/start",
+		"GET /containers/c1/json",
+		"DELETE /containers/c1?force=true",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Fatalf("requests: %v, want %v", requests, want)
+	}
+}
+
+type widget struct {
+	ID   int64
+	Name string
+}
+
+var widgetStore = skyorm.NewStore("postgrestest_widgets", 0, func() skyorm.Model {
+	return &widget{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("name", "string", false),
+)
+
+func (m *widget) OrmStore() skyorm.Store     { return widgetStore }
+func (m *widget) OrmPk() interface{}         { return m.ID }
+func (m *widget) OrmPkProp() skyorm.Prop     { return widgetStore.Pk() }
+func (m *widget) OrmPkPointer() interface{}  { return &m.ID }
+func (m *widget) OrmProps() []skyorm.Prop    { return widgetStore.Props() }
+func (m *widget) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.Name} }
+func (m *widget) OrmVals() []interface{}     { return []interface{}{m.ID, m.Name} }
+
+func TestStartAppliesSchema(t *testing.T) {
+	if os.Getenv("POSTGRES_TEST_DSN") == "" {
+		t.Skip("POSTGRES_TEST_DSN isn't set")
+	}
+	postgres.RegisterIndex(widgetStore, postgres.IndexSpec{Props: []skyorm.Prop{widgetStore.Props()[1]}})
+	ctx := context.Background()
+	cfg := postgrestest.Config{Schema: []skyorm.Store{widgetStore}}
+	// the second start finds the schema applied by the first one.
+	for i := 0; i < 2; i++ {
+		h, err := postgrestest.Start(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = h.Provider.Put(ctx, &widget{Name: "a"})
+		_ = h.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err := postgrestest.Start(ctx, postgrestest.Config{SQL: []string{"DROP TABLE postgrestest_widgets"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = h.Close()
+}
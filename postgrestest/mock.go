@@ -0,0 +1,267 @@
+// Package postgrestest helps to test code using postgres provider.
+package postgrestest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/skyorm/postgres"
+)
+
+// Mock is a fake database of provider returned by NewMock. Queries of provider
+// must match expectations in order, each expectation is met by one query.
+type Mock struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+}
+
+// Expectation is an expected query and its canned result.
+type Expectation struct {
+	exec    bool
+	pattern *regexp.Regexp
+	args    []interface{}
+	hasArgs bool
+	columns []string
+	rows    [][]interface{}
+	result  int64
+	err     error
+	met     bool
+}
+
+// NewMock returns provider backed by a fake database instead of postgres, so
+// code using the provider can be unit-tested without a running server. Options
+// are passed to postgres.New.
+func NewMock(opts ...postgres.Option) (postgres.Provider, *Mock, error) {
+	m := &Mock{}
+	p, err := postgres.New("", nil, append(opts, postgres.WithConnector(mockConnector{m}))...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, m, nil
+}
+
+// NewMockDB returns database backed by a fake database like NewMock, e.g. for
+// IndexDB of sharded provider.
+func NewMockDB() (*sql.DB, *Mock) {
+	m := &Mock{}
+	return sql.OpenDB(mockConnector{m}), m
+}
+
+// ExpectQuery expects a query returning rows, such as SELECT or INSERT ... RETURNING,
+// which SQL matches regular expression pattern.
+func (m *Mock) ExpectQuery(pattern string) *Expectation {
+	return m.expect(false, pattern)
+}
+
+// ExpectExec expects a statement not returning rows, which SQL matches regular
+// expression pattern.
+func (m *Mock) ExpectExec(pattern string) *Expectation {
+	return m.expect(true, pattern)
+}
+
+func (m *Mock) expect(exec bool, pattern string) *Expectation {
+	e := &Expectation{exec: exec, pattern: regexp.MustCompile(pattern)}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// WithArgs expects query arguments, they are compared after conversion to driver
+// values, so e.g. int matches int64.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args, e.hasArgs = args, true
+	return e
+}
+
+// WillReturnRows makes the query return rows of columns.
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns, e.rows = columns, rows
+	return e
+}
+
+// WillReturnResult makes the statement report rowsAffected.
+func (e *Expectation) WillReturnResult(rowsAffected int64) *Expectation {
+	e.result = rowsAffected
+	return e
+}
+
+// WillReturnError makes the query fail with err.
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// ExpectationsWereMet returns error describing expectations which weren't met.
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var l []string
+	for _, e := range m.expectations {
+		if !e.met {
+			l = append(l, e.pattern.String())
+		}
+	}
+	if len(l) > 0 {
+		return fmt.Errorf("expected queries were not run: %s", strings.Join(l, "; "))
+	}
+	return nil
+}
+
+// match meets the next expectation with the query.
+func (m *Mock) match(exec bool, query string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var e *Expectation
+	for _, next := range m.expectations {
+		if !next.met {
+			e = next
+			break
+		}
+	}
+	if e == nil {
+		return nil, fmt.Errorf("unexpected query: %s", query)
+	}
+	if e.exec != exec {
+		return nil, fmt.Errorf("query %s: expected exec %v, got %v", query, e.exec, exec)
+	}
+	if !e.pattern.MatchString(query) {
+		return nil, fmt.Errorf("query %s doesn't match expected %s", query, e.pattern)
+	}
+	if e.hasArgs {
+		if len(args) != len(e.args) {
+			return nil, fmt.Errorf("query %s: expected %d args, got %d", query, len(e.args), len(args))
+		}
+		for i, a := range args {
+			want, err := driver.DefaultParameterConverter.ConvertValue(e.args[i])
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(normalize(want), normalize(a.Value)) {
+				return nil, fmt.Errorf("query %s: arg $%d expected %#v, got %#v", query, i+1, want, a.Value)
+			}
+		}
+	}
+	e.met = true
+	return e, nil
+}
+
+// normalize makes []byte and string values comparable.
+func normalize(v driver.Value) driver.Value {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+type mockConnector struct {
+	m *Mock
+}
+
+func (c mockConnector) Connect(context.Context) (driver.Conn, error) {
+	return &mockConn{c.m}, nil
+}
+
+func (c mockConnector) Driver() driver.Driver {
+	return mockDriver{}
+}
+
+type mockDriver struct{}
+
+func (mockDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("postgrestest: mock driver is opened by connector")
+}
+
+type mockConn struct {
+	m *Mock
+}
+
+func (c *mockConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("postgrestest: prepared statements are not supported: %s", query)
+}
+
+func (c *mockConn) Close() error {
+	return nil
+}
+
+func (c *mockConn) Begin() (driver.Tx, error) {
+	return mockTx{}, nil
+}
+
+func (c *mockConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return mockTx{}, nil
+}
+
+func (c *mockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.m.match(true, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return driver.RowsAffected(e.result), nil
+}
+
+func (c *mockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.m.match(false, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &mockRows{columns: e.columns, rows: e.rows}, nil
+}
+
+type mockTx struct{}
+
+func (mockTx) Commit() error {
+	return nil
+}
+
+func (mockTx) Rollback() error {
+	return nil
+}
+
+type mockRows struct {
+	columns []string
+	rows    [][]interface{}
+	i       int
+}
+
+func (r *mockRows) Columns() []string {
+	return r.columns
+}
+
+func (r *mockRows) Close() error {
+	return nil
+}
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.rows) {
+		return io.EOF
+	}
+	row := r.rows[r.i]
+	r.i++
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		v, err := driver.DefaultParameterConverter.ConvertValue(row[i])
+		if err != nil {
+			return err
+		}
+		dest[i] = v
+	}
+	return nil
+}
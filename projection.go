@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/skyorm/skyorm"
+)
+
+type propsKey struct{}
+
+// WithProps returns context which Populate, Find, FindOne and other finds of
+// the store select only the props with, e.g. to skip large text or jsonb
+// columns of wide tables. Pointers of other props are left zero. Pks are
+// always selected and other stores, e.g. of preloaded relations, are read
+// whole unless they are projected by WithProps too. Partial models are not
+// registered in identity map of the context.
+func WithProps(ctx context.Context, store skyorm.Store, props ...skyorm.Prop) context.Context {
+	parent, _ := ctx.Value(propsKey{}).(map[string]map[string]bool)
+	stores := make(map[string]map[string]bool, len(parent)+1)
+	for name, names := range parent {
+		stores[name] = names
+	}
+	names := make(map[string]bool, len(props))
+	for _, prop := range props {
+		names[prop.Name()] = true
+	}
+	stores[store.Name()] = names
+	return context.WithValue(ctx, propsKey{}, stores)
+}
+
+// projection returns names of props of the store selected by finds with ctx,
+// nil when finds select all of them.
+func projection(ctx context.Context, store skyorm.Store) map[string]bool {
+	stores, _ := ctx.Value(propsKey{}).(map[string]map[string]bool)
+	names := stores[store.Name()]
+	for _, prop := range store.Props() {
+		if !prop.IsPk() && names[prop.Name()] {
+			return names
+		}
+	}
+	return nil
+}
+
+// projected reports whether finds of the store with ctx select only some of its props.
+func projected(ctx context.Context, store skyorm.Store) bool {
+	return projection(ctx, store) != nil
+}
+
+// selectedProps returns props of the store selected by finds with ctx.
+func selectedProps(ctx context.Context, store skyorm.Store) []skyorm.Prop {
+	names := projection(ctx, store)
+	if names == nil {
+		return store.Props()
+	}
+	l := make([]skyorm.Prop, 0, len(names)+1)
+	for _, prop := range store.Props() {
+		if prop.IsPk() || names[prop.Name()] {
+			l = append(l, prop)
+		}
+	}
+	return l
+}
+
+// selectedPointers returns pointers of model props selected by finds with ctx.
+func selectedPointers(ctx context.Context, m skyorm.Model) []interface{} {
+	pointers := m.OrmPointers()
+	names := projection(ctx, m.OrmStore())
+	if names == nil {
+		return pointers
+	}
+	l := make([]interface{}, 0, len(names)+1)
+	for i, prop := range m.OrmProps() {
+		if prop.IsPk() || names[prop.Name()] {
+			l = append(l, pointers[i])
+		}
+	}
+	return l
+}
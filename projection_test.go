@@ -0,0 +1,35 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestWithPropsOfStore(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// accounts have name too, but they aren't projected.
+	ctx := postgres.WithProps(context.Background(), userStore, userStore.Props()[1])
+	mock.ExpectQuery(`^SELECT id, name FROM users$`).WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"})
+	mock.ExpectQuery(`^SELECT id, user_id, name, \(upper\(name\)\) AS label FROM accounts$`).
+		WillReturnRows([]string{"id", "user_id", "name", "label"}, []interface{}{1, 2, "a", "A"})
+	if _, err = p.Find(ctx, userStore, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Find(ctx, accountStore, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	ctx = postgres.WithProps(ctx, accountStore, accountStore.Props()[2])
+	mock.ExpectQuery(`^SELECT id, name FROM accounts$`).WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"})
+	if _, err = p.Find(ctx, accountStore, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Conflict is a conflict target of insert, either a constraint name or props
+// of unique index. Zero Conflict matches any unique violation.
+type Conflict struct {
+	Constraint string
+	Props      []skyorm.Prop
+}
+
+// target returns conflict target of ON CONFLICT clause, empty for any conflict.
+func (c Conflict) target() string {
+	switch {
+	case c.Constraint != "":
+		return " ON CONSTRAINT " + quoteIdent(c.Constraint)
+	case len(c.Props) > 0:
+		columns := make([]string, len(c.Props))
+		for i, prop := range c.Props {
+			columns[i] = quoteColumn(prop.Name())
+		}
+		return " (" + strings.Join(columns, ", ") + ")"
+	}
+	return ""
+}
+
+// PutIgnore inserts models skipping the ones conflicting with existing rows,
+// e.g. for idempotent event ingestion, and reports whether each model was
+// inserted. Skipped models keep their pks and aren't notified by AfterInsert
+// hooks. Writes are never buffered by WithAsyncStores, as their results are needed.
+func (p *provider) PutIgnore(ctx context.Context, models ...skyorm.Model) ([]bool, error) {
+	return p.PutIgnoreOn(ctx, Conflict{}, models...)
+}
+
+// PutIgnoreOn is PutIgnore skipping models only on conflicts of the target.
+func (p *provider) PutIgnoreOn(ctx context.Context, target Conflict, models ...skyorm.Model) ([]bool, error) {
+	inserted := make([]bool, len(models))
+	for i, m := range models {
+		if err := checkWritable(m.OrmStore()); err != nil {
+			return inserted, err
+		}
+		if err := beforeInsert(ctx, m); err != nil {
+			return inserted, err
+		}
+		if err := checkEnumModel(m); err != nil {
+			return inserted, err
+		}
+		err := p.insert(ctx, m, " ON CONFLICT"+target.target()+" DO NOTHING", "")
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return inserted, err
+		}
+		if dryRun(ctx) {
+			continue
+		}
+		inserted[i] = true
+		p.invalidateCache(m.OrmStore())
+		identify(ctx, m)
+		if err = afterInsert(ctx, m); err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+func TestPutIgnore(t *testing.T) {
+	var notified []int64
+	postgres.RegisterHooks(noteStore, postgres.StoreHooks{
+		AfterInsert: func(ctx context.Context, m skyorm.Model) error {
+			notified = append(notified, m.(*note).ID)
+			return nil
+		},
+	})
+	defer postgres.RegisterHooks(noteStore, postgres.StoreHooks{})
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, c := range []struct {
+		target postgres.Conflict
+		clause string
+	}{
+		{postgres.Conflict{}, `ON CONFLICT DO NOTHING`},
+		{postgres.Conflict{Constraint: "notes_text_key"}, `ON CONFLICT ON CONSTRAINT notes_text_key DO NOTHING`},
+		{postgres.Conflict{Props: noteStore.Props()[1:]}, `ON CONFLICT \(text, updated\) DO NOTHING`},
+	} {
+		notified = nil
+		query := `^INSERT INTO notes \(id, text, updated\) VALUES \(\$1, \$2, \$3\) ` + c.clause + ` RETURNING id$`
+		mock.ExpectQuery(query).WithArgs(int64(1), "a", int64(0)).WillReturnRows([]string{"id"}, []interface{}{int64(1)})
+		// a conflicting row isn't returned.
+		mock.ExpectQuery(query).WithArgs(int64(2), "b", int64(0)).WillReturnRows([]string{"id"})
+		inserted, err := p.PutIgnoreOn(ctx, c.target, &note{ID: 1, Text: "a"}, &note{ID: 2, Text: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(inserted, []bool{true, false}) {
+			t.Errorf("%s: inserted %v", c.clause, inserted)
+		}
+		if !reflect.DeepEqual(notified, []int64{1}) {
+			t.Errorf("%s: AfterInsert of %v", c.clause, notified)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
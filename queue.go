@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job statuses.
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDead    = "dead"
+)
+
+// Job is a job of the queue.
+type Job struct {
+	ID          int64
+	Queue       string
+	Payload     []byte
+	Priority    int
+	Attempts    int
+	ScheduledAt time.Time
+	LastError   string
+}
+
+// QueueConfig is a configuration of the job queue.
+type QueueConfig struct {
+	// Table is the jobs table, skyorm_jobs by default.
+	Table string
+	// MaxAttempts is the number of attempts after which a failed job becomes dead.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry, it doubles with every attempt.
+	BaseBackoff time.Duration
+	// MaxBackoff limits retry delay.
+	MaxBackoff time.Duration
+	// Visibility is the time a dequeued job stays locked before it is given to
+	// another worker, unless it is completed or failed.
+	Visibility time.Duration
+	// Concurrency limits the number of running jobs per queue, zero means no limit.
+	Concurrency map[string]int
+}
+
+// Queue is a job queue backed by postgres table, jobs are dequeued with
+// FOR UPDATE SKIP LOCKED by priority and scheduled time.
+type Queue struct {
+	p   *provider
+	cfg QueueConfig
+}
+
+// Queue returns job queue configured by cfg.
+func (p *provider) Queue(cfg QueueConfig) *Queue {
+	if cfg.Table == "" {
+		cfg.Table = "skyorm_jobs"
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 10
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Hour
+	}
+	if cfg.Visibility <= 0 {
+		cfg.Visibility = 5 * time.Minute
+	}
+	return &Queue{p, cfg}
+}
+
+// CreateTable creates jobs table and its dequeue index if they don't exist.
+func (q *Queue) CreateTable(ctx context.Context) error {
+	_, table := splitTable(q.cfg.Table)
+	queries := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id BIGSERIAL PRIMARY KEY,
+	queue TEXT NOT NULL,
+	payload BYTEA,
+	priority INT NOT NULL DEFAULT 0,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INT NOT NULL DEFAULT 0,
+	scheduled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	locked_until TIMESTAMPTZ,
+	last_error TEXT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, quoteTable(q.cfg.Table)),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (queue, status, priority DESC, scheduled_at)",
+			quoteIdent(table+"_dequeue_idx"), quoteTable(q.cfg.Table)),
+	}
+	for _, query := range queries {
+		if _, err := q.p.exec(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue adds job to the queue to be run not earlier than at, zero at means now.
+// Jobs with higher priority are dequeued first.
+func (q *Queue) Enqueue(ctx context.Context, queue string, payload []byte, priority int, at time.Time) (int64, error) {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	query := fmt.Sprintf("INSERT INTO %s (queue, payload, priority, scheduled_at) VALUES ($1, $2, $3, $4) RETURNING id", quoteTable(q.cfg.Table))
+	ctx = withOp(ctx, OpEnqueue)
+	var id int64
+	err := q.p.queryRow(ctx, query, []interface{}{queue, payload, priority, at}, &id)
+	return id, err
+}
+
+// Dequeue locks and returns the next due job of the queue, or nil when there is
+// no due job or the queue reached its concurrency limit. Running jobs which
+// visibility expired, e.g. because their worker died, are dequeued again, or
+// marked dead when they reached the maximum number of attempts.
+func (q *Queue) Dequeue(ctx context.Context, queue string) (*Job, error) {
+	tx, err := q.p.beginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Close()
+	}()
+	if limit := q.cfg.Concurrency[queue]; limit > 0 {
+		// the lock serializes dequeues of the queue, so the limit can't be exceeded.
+		if _, err = tx.exec(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", q.cfg.Table+":"+queue); err != nil {
+			return nil, err
+		}
+		var running int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE queue = $1 AND status = $2 AND locked_until > now()", quoteTable(q.cfg.Table))
+		if err = tx.queryRow(ctx, query, []interface{}{queue, JobRunning}, &running); err != nil {
+			return nil, err
+		}
+		if running >= limit {
+			return nil, nil
+		}
+	}
+	query := fmt.Sprintf(`SELECT id, payload, priority, attempts, scheduled_at, COALESCE(last_error, '') FROM %s
+WHERE queue = $1 AND scheduled_at <= now() AND (status = $2 OR status = $3 AND locked_until <= now())
+ORDER BY priority DESC, scheduled_at LIMIT 1 FOR UPDATE SKIP LOCKED`, quoteTable(q.cfg.Table))
+	j := &Job{Queue: queue}
+	for {
+		err = tx.queryRow(withOp(ctx, OpDequeue), query, []interface{}{queue, JobPending, JobRunning},
+			&j.ID, &j.Payload, &j.Priority, &j.Attempts, &j.ScheduledAt, &j.LastError)
+		if err == sql.ErrNoRows {
+			return nil, tx.Commit()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if j.Attempts < q.cfg.MaxAttempts {
+			break
+		}
+		// the last attempt expired without its worker completing or failing it.
+		kill := fmt.Sprintf("UPDATE %s SET status = $1, locked_until = NULL, last_error = $2 WHERE id = $3", quoteTable(q.cfg.Table))
+		if _, err = tx.exec(ctx, kill, JobDead, "visibility expired", j.ID); err != nil {
+			return nil, err
+		}
+	}
+	j.Attempts++
+	query = fmt.Sprintf("UPDATE %s SET status = $1, attempts = $2, locked_until = now() + $3 * interval '1 microsecond' WHERE id = $4", quoteTable(q.cfg.Table))
+	if _, err = tx.exec(ctx, query, JobRunning, j.Attempts, q.cfg.Visibility.Microseconds(), j.ID); err != nil {
+		return nil, err
+	}
+	return j, tx.Commit()
+}
+
+// ErrJobLost is returned by Complete and Fail of a job which visibility
+// expired and which was dequeued again by another worker since.
+var ErrJobLost = errors.New("postgres: job was dequeued again after its visibility expired")
+
+// Complete removes successfully run job.
+func (q *Queue) Complete(ctx context.Context, j *Job) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1 AND status = $2 AND attempts = $3", quoteTable(q.cfg.Table))
+	res, err := q.p.exec(ctx, query, j.ID, JobRunning, j.Attempts)
+	return leased(ctx, res, err)
+}
+
+// Fail records job failure and schedules retry with exponential backoff, or marks
+// the job dead when it reached the maximum number of attempts.
+func (q *Queue) Fail(ctx context.Context, j *Job, cause error) error {
+	j.LastError = cause.Error()
+	if j.Attempts >= q.cfg.MaxAttempts {
+		query := fmt.Sprintf("UPDATE %s SET status = $1, locked_until = NULL, last_error = $2 WHERE id = $3 AND status = $4 AND attempts = $5", quoteTable(q.cfg.Table))
+		res, err := q.p.exec(ctx, query, JobDead, j.LastError, j.ID, JobRunning, j.Attempts)
+		return leased(ctx, res, err)
+	}
+	j.ScheduledAt = time.Now().Add(q.backoff(j.Attempts))
+	query := fmt.Sprintf("UPDATE %s SET status = $1, locked_until = NULL, last_error = $2, scheduled_at = $3 WHERE id = $4 AND status = $5 AND attempts = $6", quoteTable(q.cfg.Table))
+	res, err := q.p.exec(ctx, query, JobPending, j.LastError, j.ScheduledAt, j.ID, JobRunning, j.Attempts)
+	return leased(ctx, res, err)
+}
+
+// leased returns ErrJobLost when statement of a dequeued job changed no row.
+// Attempts are incremented by every dequeue, so they are the lease of the
+// worker which dequeued the job.
+func leased(ctx context.Context, res sql.Result, err error) error {
+	if err != nil || dryRun(ctx) {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrJobLost
+	}
+	return nil
+}
+
+// DeadJobs returns dead jobs of the queue.
+func (q *Queue) DeadJobs(ctx context.Context, queue string, limit int) ([]*Job, error) {
+	query := fmt.Sprintf(`SELECT id, payload, priority, attempts, scheduled_at, COALESCE(last_error, '') FROM %s
+WHERE queue = $1 AND status = $2 ORDER BY id LIMIT $3`, quoteTable(q.cfg.Table))
+	res, err := q.p.query(ctx, query, queue, JobDead, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]*Job, 0)
+	for res.Next() {
+		j := &Job{Queue: queue}
+		if err = res.Scan(&j.ID, &j.Payload, &j.Priority, &j.Attempts, &j.ScheduledAt, &j.LastError); err != nil {
+			return nil, err
+		}
+		l = append(l, j)
+	}
+	return l, res.Err()
+}
+
+// Retry moves dead job back to the queue with reset attempts.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	query := fmt.Sprintf("UPDATE %s SET status = $1, attempts = 0, scheduled_at = now() WHERE id = $2 AND status = $3", quoteTable(q.cfg.Table))
+	_, err := q.p.exec(ctx, query, JobPending, id, JobDead)
+	return err
+}
+
+func (q *Queue) backoff(attempts int) time.Duration {
+	d := q.cfg.BaseBackoff
+	for i := 1; i < attempts && d < q.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > q.cfg.MaxBackoff {
+		d = q.cfg.MaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,76 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestQueueCompleteLostJob(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := p.Queue(postgres.QueueConfig{})
+	query := `^DELETE FROM skyorm_jobs WHERE id = \$1 AND status = \$2 AND attempts = \$3$`
+	mock.ExpectExec(query).WithArgs(1, postgres.JobRunning, 2).WillReturnResult(1)
+	mock.ExpectExec(query).WithArgs(1, postgres.JobRunning, 2).WillReturnResult(0)
+	j := &postgres.Job{ID: 1, Attempts: 2}
+	if err = q.Complete(context.Background(), j); err != nil {
+		t.Fatal(err)
+	}
+	if err = q.Complete(context.Background(), j); err != postgres.ErrJobLost {
+		t.Fatalf("Complete() error = %v, want ErrJobLost", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueDequeueKillsExhaustedJob(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := p.Queue(postgres.QueueConfig{MaxAttempts: 2})
+	cols := []string{"id", "payload", "priority", "attempts", "scheduled_at", "last_error"}
+	now := time.Now()
+	// job 1 expired on its last attempt.
+	mock.ExpectQuery(`^SELECT id, payload, priority, attempts, scheduled_at`).
+		WillReturnRows(cols, []interface{}{1, []byte("a"), 0, 2, now, ""})
+	mock.ExpectExec(`^UPDATE skyorm_jobs SET status = \$1, locked_until = NULL, last_error = \$2 WHERE id = \$3$`).
+		WithArgs(postgres.JobDead, "visibility expired", 1).WillReturnResult(1)
+	mock.ExpectQuery(`^SELECT id, payload, priority, attempts, scheduled_at`).
+		WillReturnRows(cols, []interface{}{2, []byte("b"), 0, 0, now, ""})
+	mock.ExpectExec(`^UPDATE skyorm_jobs SET status = \$1, attempts = \$2`).
+		WithArgs(postgres.JobRunning, 1, (5 * time.Minute).Microseconds(), 2).WillReturnResult(1)
+	j, err := q.Dequeue(context.Background(), "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j == nil || j.ID != 2 || j.Attempts != 1 {
+		t.Fatalf("dequeued %+v", j)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueueQuotesTable(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := p.Queue(postgres.QueueConfig{Table: "jobs.user"})
+	mock.ExpectExec(`^CREATE TABLE IF NOT EXISTS jobs\."user" \(`).WillReturnResult(0)
+	mock.ExpectExec(`^CREATE INDEX IF NOT EXISTS user_dequeue_idx ON jobs\."user" `).WillReturnResult(0)
+	if err = q.CreateTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
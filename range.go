@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// IntRange is a value of int4range or int8range property. Ranges are compared
+// by Overlaps, Contains and ContainedBy conditions.
+type IntRange struct {
+	Lower, Upper       int64
+	LowerInc, UpperInc bool
+	// LowerInf and UpperInf make the bounds unbounded, ignoring Lower and Upper.
+	LowerInf, UpperInf bool
+	Empty              bool
+}
+
+// NewIntRange returns range [lower, upper), the canonical form of integer ranges.
+func NewIntRange(lower, upper int64) IntRange {
+	return IntRange{Lower: lower, Upper: upper, LowerInc: true}
+}
+
+func (r IntRange) Value() (driver.Value, error) {
+	return rangeText{r.Empty, r.LowerInc, r.UpperInc, intBound(r.Lower, r.LowerInf), intBound(r.Upper, r.UpperInf)}.String(), nil
+}
+
+func (r *IntRange) Scan(src interface{}) error {
+	t, err := scanRange(src)
+	if err != nil {
+		return err
+	}
+	*r = IntRange{Empty: t.empty, LowerInc: t.lowerInc, UpperInc: t.upperInc, LowerInf: !t.empty && t.lower == nil, UpperInf: !t.empty && t.upper == nil}
+	if t.lower != nil {
+		if r.Lower, err = strconv.ParseInt(*t.lower, 10, 64); err != nil {
+			return err
+		}
+	}
+	if t.upper != nil {
+		if r.Upper, err = strconv.ParseInt(*t.upper, 10, 64); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func intBound(v int64, inf bool) *string {
+	if inf {
+		return nil
+	}
+	s := strconv.FormatInt(v, 10)
+	return &s
+}
+
+// TimeRange is a value of tstzrange or tsrange property, e.g. of a booking or
+// a validity interval. Zero bounds are unbounded.
+type TimeRange struct {
+	Lower, Upper       time.Time
+	LowerInc, UpperInc bool
+	Empty              bool
+}
+
+// NewTimeRange returns range [lower, upper).
+func NewTimeRange(lower, upper time.Time) TimeRange {
+	return TimeRange{Lower: lower, Upper: upper, LowerInc: true}
+}
+
+// Contains returns whether t is within the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	if r.Empty {
+		return false
+	}
+	if !r.Lower.IsZero() && (t.Before(r.Lower) || !r.LowerInc && t.Equal(r.Lower)) {
+		return false
+	}
+	return r.Upper.IsZero() || t.Before(r.Upper) || r.UpperInc && t.Equal(r.Upper)
+}
+
+func (r TimeRange) Value() (driver.Value, error) {
+	return rangeText{r.Empty, r.LowerInc, r.UpperInc, timeBound(r.Lower, timeLayout), timeBound(r.Upper, timeLayout)}.String(), nil
+}
+
+func (r *TimeRange) Scan(src interface{}) error {
+	t, err := scanRange(src)
+	if err != nil {
+		return err
+	}
+	*r = TimeRange{Empty: t.empty, LowerInc: t.lowerInc, UpperInc: t.upperInc}
+	if r.Lower, err = parseBoundTime(t.lower); err != nil {
+		return err
+	}
+	r.Upper, err = parseBoundTime(t.upper)
+	return err
+}
+
+// DateRange is a value of daterange property, bounds are dates of their times.
+// Zero bounds are unbounded.
+type DateRange TimeRange
+
+// NewDateRange returns range of dates [lower, upper), the canonical form of date ranges.
+func NewDateRange(lower, upper time.Time) DateRange {
+	return DateRange{Lower: lower, Upper: upper, LowerInc: true}
+}
+
+func (r DateRange) Value() (driver.Value, error) {
+	return rangeText{r.Empty, r.LowerInc, r.UpperInc, timeBound(r.Lower, dateLayout), timeBound(r.Upper, dateLayout)}.String(), nil
+}
+
+func (r *DateRange) Scan(src interface{}) error {
+	return (*TimeRange)(r).Scan(src)
+}
+
+const (
+	timeLayout = "2006-01-02 15:04:05.999999Z07:00"
+	dateLayout = "2006-01-02"
+)
+
+func timeBound(t time.Time, layout string) *string {
+	if t.IsZero() {
+		return nil
+	}
+	s := t.Format(layout)
+	return &s
+}
+
+// parseBoundTime parses bound of timestamp, timestamptz or date range.
+func parseBoundTime(s *string) (time.Time, error) {
+	if s == nil {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05.999999Z07", "2006-01-02 15:04:05.999999Z07:00", "2006-01-02 15:04:05.999999", dateLayout} {
+		if t, err := time.Parse(layout, *s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid range bound %q", *s)
+}
+
+// rangeText is a range in text format, nil bounds are unbounded.
+type rangeText struct {
+	empty              bool
+	lowerInc, upperInc bool
+	lower, upper       *string
+}
+
+func (t rangeText) String() string {
+	if t.empty {
+		return "empty"
+	}
+	var b strings.Builder
+	if t.lowerInc && t.lower != nil {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	if t.lower != nil {
+		b.WriteString(quoteBound(*t.lower))
+	}
+	b.WriteByte(',')
+	if t.upper != nil {
+		b.WriteString(quoteBound(*t.upper))
+	}
+	if t.upperInc && t.upper != nil {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+var boundReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+func quoteBound(s string) string {
+	return `"` + boundReplacer.Replace(s) + `"`
+}
+
+func scanRange(src interface{}) (rangeText, error) {
+	var s string
+	switch src := src.(type) {
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	default:
+		return rangeText{}, fmt.Errorf("can't scan %T into range", src)
+	}
+	return parseRange(s)
+}
+
+// parseRange parses range in text format, e.g. [1,5), ("2024-01-01 00:00:00+00",) or empty.
+func parseRange(s string) (rangeText, error) {
+	if s == "empty" {
+		return rangeText{empty: true}, nil
+	}
+	if len(s) < 3 || !strings.ContainsRune("[(", rune(s[0])) || !strings.ContainsRune("])", rune(s[len(s)-1])) {
+		return rangeText{}, fmt.Errorf("invalid range %q", s)
+	}
+	t := rangeText{lowerInc: s[0] == '[', upperInc: s[len(s)-1] == ']'}
+	body := s[1 : len(s)-1]
+	lower, rest, err := rangeBound(body)
+	if err != nil || !strings.HasPrefix(rest, ",") {
+		return rangeText{}, fmt.Errorf("invalid range %q", s)
+	}
+	upper, rest, err := rangeBound(rest[1:])
+	if err != nil || rest != "" {
+		return rangeText{}, fmt.Errorf("invalid range %q", s)
+	}
+	// unbounded bounds are exclusive, as the server prints them.
+	t.lower, t.upper = lower, upper
+	t.lowerInc = t.lowerInc && lower != nil
+	t.upperInc = t.upperInc && upper != nil
+	return t, nil
+}
+
+// rangeBound returns bound at start of s, nil when it's empty, i.e. unbounded,
+// and rest of s after it.
+func rangeBound(s string) (*string, string, error) {
+	if s == "" || s[0] == ',' {
+		return nil, s, nil
+	}
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == '"':
+			if quoted && i+1 < len(s) && s[i+1] == '"' {
+				i++
+				b.WriteByte('"')
+				continue
+			}
+			quoted = !quoted
+		case c == ',' && !quoted:
+			v := b.String()
+			return &v, s[i:], nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, "", fmt.Errorf("unterminated range bound %q", s)
+	}
+	v := b.String()
+	return &v, "", nil
+}
+
+// ContainedBy returns condition matching range or array property contained by val (<@).
+func ContainedBy(prop skyorm.Prop, val interface{}) skyorm.Cond {
+	return binaryCond(prop, "<@", val)
+}
@@ -0,0 +1,147 @@
+package postgres_test
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+)
+
+func TestIntRange(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		r    postgres.IntRange
+	}{
+		{"empty", postgres.IntRange{Empty: true}},
+		{"[1,5)", postgres.NewIntRange(1, 5)},
+		{"(1,5]", postgres.IntRange{Lower: 1, Upper: 5, UpperInc: true}},
+		{"(,5)", postgres.IntRange{Upper: 5, LowerInf: true}},
+		{"[-3,)", postgres.IntRange{Lower: -3, LowerInc: true, UpperInf: true}},
+		{"(,)", postgres.IntRange{LowerInf: true, UpperInf: true}},
+		{"[,5]", postgres.IntRange{Upper: 5, UpperInc: true, LowerInf: true}},
+		{`["1","5")`, postgres.NewIntRange(1, 5)},
+	} {
+		var r postgres.IntRange
+		if err := r.Scan([]byte(tt.text)); err != nil {
+			t.Fatalf("scan %s: %v", tt.text, err)
+		}
+		if r != tt.r {
+			t.Fatalf("scan %s: %+v, want %+v", tt.text, r, tt.r)
+		}
+		var back postgres.IntRange
+		if err := back.Scan(rangeValue(t, r)); err != nil {
+			t.Fatalf("scan value of %s: %v", tt.text, err)
+		}
+		if back != r {
+			t.Fatalf("round trip of %s: %+v", tt.text, back)
+		}
+	}
+	for r, want := range map[postgres.IntRange]string{
+		{Empty: true}:              "empty",
+		postgres.NewIntRange(1, 5): `["1","5")`,
+		{Upper: 5, UpperInc: true, LowerInf: true, LowerInc: true}: `(,"5"]`,
+		{LowerInf: true, UpperInf: true}:                           "(,)",
+	} {
+		if v := rangeValue(t, r); v != want {
+			t.Fatalf("value of %+v: %v, want %s", r, v, want)
+		}
+	}
+	for _, text := range []string{"", "1,5", "[1,5", "(1)", `["1,5)`, "[a,5)", "[1,5,6)"} {
+		var r postgres.IntRange
+		if err := r.Scan(text); err == nil {
+			t.Fatalf("scanned invalid range %q into %+v", text, r)
+		}
+	}
+	var r postgres.IntRange
+	if err := r.Scan(5); err == nil {
+		t.Fatal("scanned int into range")
+	}
+}
+
+func TestTimeRange(t *testing.T) {
+	lower := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	upper := time.Date(2024, 1, 2, 0, 0, 0, 500000000, time.UTC)
+	for _, tt := range []struct {
+		text string
+		r    postgres.TimeRange
+	}{
+		{"empty", postgres.TimeRange{Empty: true}},
+		{`["2024-01-01 10:00:00+00","2024-01-02 02:00:00.5+02")`, postgres.NewTimeRange(lower, upper)},
+		{`("2024-01-01 10:00:00",]`, postgres.TimeRange{Lower: lower}},
+		{`(,"2024-01-02 00:00:00.5+00")`, postgres.TimeRange{Upper: upper}},
+	} {
+		var r postgres.TimeRange
+		if err := r.Scan(tt.text); err != nil {
+			t.Fatalf("scan %s: %v", tt.text, err)
+		}
+		if !equalTimeRanges(r, tt.r) {
+			t.Fatalf("scan %s: %+v, want %+v", tt.text, r, tt.r)
+		}
+		var back postgres.TimeRange
+		if err := back.Scan(rangeValue(t, r)); err != nil {
+			t.Fatalf("scan value of %s: %v", tt.text, err)
+		}
+		if !equalTimeRanges(back, r) {
+			t.Fatalf("round trip of %s: %+v", tt.text, back)
+		}
+	}
+	if v, want := rangeValue(t, postgres.NewTimeRange(lower, upper)), `["2024-01-01 10:00:00Z","2024-01-02 00:00:00.5Z")`; v != want {
+		t.Fatalf("value %v, want %s", v, want)
+	}
+	r := postgres.NewTimeRange(lower, upper)
+	if !r.Contains(lower) || r.Contains(upper) || r.Contains(lower.Add(-time.Second)) || !r.Contains(upper.Add(-time.Second)) {
+		t.Fatalf("contains of %+v", r)
+	}
+	var bad postgres.TimeRange
+	if err := bad.Scan(`["yesterday",)`); err == nil {
+		t.Fatal("scanned invalid bound")
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	lower := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	upper := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		text string
+		r    postgres.DateRange
+	}{
+		{"empty", postgres.DateRange{Empty: true}},
+		{"[2024-01-01,2024-02-01)", postgres.NewDateRange(lower, upper)},
+		{"(,2024-02-01)", postgres.DateRange{Upper: upper}},
+		{"[2024-01-01,)", postgres.DateRange{Lower: lower, LowerInc: true}},
+	} {
+		var r postgres.DateRange
+		if err := r.Scan(tt.text); err != nil {
+			t.Fatalf("scan %s: %v", tt.text, err)
+		}
+		if !equalTimeRanges(postgres.TimeRange(r), postgres.TimeRange(tt.r)) {
+			t.Fatalf("scan %s: %+v, want %+v", tt.text, r, tt.r)
+		}
+		var back postgres.DateRange
+		if err := back.Scan(rangeValue(t, r)); err != nil {
+			t.Fatalf("scan value of %s: %v", tt.text, err)
+		}
+		if !equalTimeRanges(postgres.TimeRange(back), postgres.TimeRange(r)) {
+			t.Fatalf("round trip of %s: %+v", tt.text, back)
+		}
+	}
+	// times of bounds are dropped.
+	if v, want := rangeValue(t, postgres.NewDateRange(lower.Add(time.Hour), upper)), `["2024-01-01","2024-02-01")`; v != want {
+		t.Fatalf("value %v, want %s", v, want)
+	}
+}
+
+func rangeValue(t *testing.T, v driver.Valuer) driver.Value {
+	t.Helper()
+	dv, err := v.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dv
+}
+
+func equalTimeRanges(a, b postgres.TimeRange) bool {
+	return a.Lower.Equal(b.Lower) && a.Upper.Equal(b.Upper) &&
+		a.LowerInc == b.LowerInc && a.UpperInc == b.UpperInc && a.Empty == b.Empty
+}
@@ -0,0 +1,554 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// ConsistentHash resolves shard by position of the key hash on a ring of virtual
+// nodes, so adding a shard moves only a fraction of keys.
+type ConsistentHash struct {
+	ring  []uint32
+	nodes map[uint32]int
+}
+
+// NewConsistentHash returns ring of shards with vnodes virtual nodes per shard.
+func NewConsistentHash(shards, vnodes int) (*ConsistentHash, error) {
+	if shards <= 0 || vnodes <= 0 {
+		return nil, fmt.Errorf("invalid ring of %d shards with %d virtual nodes", shards, vnodes)
+	}
+	c := &ConsistentHash{
+		ring:  make([]uint32, 0, shards*vnodes),
+		nodes: make(map[uint32]int, shards*vnodes),
+	}
+	for i := 0; i < shards; i++ {
+		for v := 0; v < vnodes; v++ {
+			h := hashKey(fmt.Sprintf("%d#%d", i, v))
+			c.ring = append(c.ring, h)
+			c.nodes[h] = i
+		}
+	}
+	sort.Slice(c.ring, func(i, j int) bool {
+		return c.ring[i] < c.ring[j]
+	})
+	return c, nil
+}
+
+// Shard returns index of shard for the key.
+func (c *ConsistentHash) Shard(key interface{}) int {
+	h := hashKey(key)
+	i := sort.Search(len(c.ring), func(i int) bool {
+		return c.ring[i] >= h
+	})
+	if i == len(c.ring) {
+		i = 0
+	}
+	return c.nodes[c.ring[i]]
+}
+
+// SwitchResolver is a resolver which routing can be switched atomically.
+type SwitchResolver struct {
+	v atomic.Value
+}
+
+type resolverBox struct {
+	r ShardResolver
+}
+
+// NewSwitchResolver returns switchable resolver starting with r.
+func NewSwitchResolver(r ShardResolver) *SwitchResolver {
+	s := &SwitchResolver{}
+	s.Switch(r)
+	return s
+}
+
+// Shard returns index of shard for the key.
+func (s *SwitchResolver) Shard(key interface{}) int {
+	return s.v.Load().(resolverBox).r.Shard(key)
+}
+
+// Switch atomically replaces routing with r.
+func (s *SwitchResolver) Switch(r ShardResolver) {
+	s.v.Store(resolverBox{r})
+}
+
+// Move is a set of models which has to be moved between shards.
+type Move struct {
+	From int
+	To   int
+	Pks  []interface{}
+}
+
+// Rebalancer moves models of a store between shards when routing changes
+// from one resolver to another.
+type Rebalancer struct {
+	// DBs are databases of shards in the new layout, indexed by shard.
+	DBs   []*sql.DB
+	Store skyorm.Store
+	// Key returns shard key of the model.
+	Key  func(m skyorm.Model) interface{}
+	From ShardResolver
+	To   ShardResolver
+	// Router is switched from From to To once models are copied.
+	Router *SwitchResolver
+	Logger skyorm.Logger
+	// Provider binds and scans values of copied models with its converters and
+	// time zone, e.g. provider of any of the shards. Values are bound and
+	// scanned as models have them when it's nil.
+	Provider Provider
+}
+
+// valueBinder is implemented by providers binding and scanning values.
+type valueBinder interface {
+	bindValues(l []interface{}) []interface{}
+	scanPointers(l []interface{}) []interface{}
+}
+
+// binder returns binder of values of models, provider without options unless
+// Provider is set.
+func (r *Rebalancer) binder() valueBinder {
+	if b, ok := r.Provider.(valueBinder); ok {
+		return b
+	}
+	return &provider{}
+}
+
+const (
+	rebalanceLogTable = "skyorm_rebalance_log"
+	// rebalanceReplaySetting is set by replays, so capture triggers skip them.
+	rebalanceReplaySetting = "skyorm.rebalance_replay"
+	// rebalancePageSize is the number of rows read at once by plans and replays.
+	rebalancePageSize = 1000
+)
+
+// Plan scans every shard by pages and returns models which are routed to another
+// shard by To resolver. Only pks of moved models are kept in memory.
+func (r *Rebalancer) Plan(ctx context.Context) ([]Move, error) {
+	pl := newRebalancePlan(nil)
+	for from, db := range r.DBs {
+		var last interface{}
+		for {
+			where := " ORDER BY " + quoteColumn(r.Store.Pk().Name()) + " LIMIT " + strconv.Itoa(rebalancePageSize)
+			args := []interface{}(nil)
+			if last != nil {
+				where = " WHERE " + quoteColumn(r.Store.Pk().Name()) + " > $1" + where
+				args = append(args, last)
+			}
+			l, err := r.selectModels(ctx, db, where, args...)
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range l {
+				r.route(pl, from, m)
+			}
+			if len(l) < rebalancePageSize {
+				break
+			}
+			last = l[len(l)-1].OrmPk()
+		}
+	}
+	return pl.list(), nil
+}
+
+// route adds the model of shard from to the plan when To resolver routes it to
+// another shard, and reports whether it did.
+func (r *Rebalancer) route(pl *rebalancePlan, from int, m skyorm.Model) bool {
+	key := r.Key(m)
+	if r.From.Shard(key) != from {
+		return false
+	}
+	to := r.To.Shard(key)
+	if to == from {
+		return false
+	}
+	pl.add(from, to, fmt.Sprint(derefValue(m.OrmPk())), m.OrmPk())
+	return true
+}
+
+// rebalancePlan is a set of moves with pks of moved models by source shard.
+type rebalancePlan struct {
+	moves map[[2]int]*Move
+	moved map[int]map[string]*Move
+}
+
+func newRebalancePlan(moves []Move) *rebalancePlan {
+	pl := &rebalancePlan{moves: make(map[[2]int]*Move), moved: make(map[int]map[string]*Move)}
+	for _, mv := range moves {
+		for _, pk := range mv.Pks {
+			pl.add(mv.From, mv.To, fmt.Sprint(derefValue(pk)), pk)
+		}
+	}
+	return pl
+}
+
+func (pl *rebalancePlan) add(from, to int, key string, pk interface{}) {
+	if _, ok := pl.moved[from][key]; ok {
+		return
+	}
+	mv, ok := pl.moves[[2]int{from, to}]
+	if !ok {
+		mv = &Move{From: from, To: to}
+		pl.moves[[2]int{from, to}] = mv
+	}
+	mv.Pks = append(mv.Pks, pk)
+	if pl.moved[from] == nil {
+		pl.moved[from] = make(map[string]*Move)
+	}
+	pl.moved[from][key] = mv
+}
+
+func (pl *rebalancePlan) list() []Move {
+	l := make([]Move, 0, len(pl.moves))
+	for _, mv := range pl.moves {
+		l = append(l, *mv)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].From < l[j].From || l[i].From == l[j].From && l[i].To < l[j].To
+	})
+	return l
+}
+
+// Run executes planned moves: it captures changes of every shard with triggers,
+// plans again, so models written since moves were planned are moved too, copies
+// models with COPY, replays captured changes, switches routing, replays changes
+// made meanwhile, verifies counts and finally removes moved models from sources.
+// Captured models routed to another shard which weren't planned, e.g. inserted
+// during the copy, are moved by replays. Models written to their target after
+// routing is switched are never replayed over, as the target is authoritative
+// for them since then.
+func (r *Rebalancer) Run(ctx context.Context, moves []Move) error {
+	if r.Logger == nil {
+		r.Logger = skyorm.DefaultLogger
+	}
+	for shard, db := range r.DBs {
+		if err := r.installCapture(ctx, db); err != nil {
+			return err
+		}
+		defer func(shard int, db *sql.DB) {
+			if err := r.dropCapture(context.Background(), db); err != nil {
+				r.logLn("REBALANCE DROP CAPTURE ERROR: shard %d: %v", shard, err)
+			}
+		}(shard, db)
+	}
+	pl := newRebalancePlan(moves)
+	planned, err := r.Plan(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mv := range planned {
+		for _, pk := range mv.Pks {
+			pl.add(mv.From, mv.To, fmt.Sprint(derefValue(pk)), pk)
+		}
+	}
+	for _, mv := range pl.list() {
+		r.logLn("REBALANCE COPY: %s %d models from shard %d to shard %d", r.Store.Name(), len(mv.Pks), mv.From, mv.To)
+		if err := r.copy(ctx, mv); err != nil {
+			return err
+		}
+	}
+	cursors := make(map[int]int64)
+	if err := r.replay(ctx, pl, cursors, nil); err != nil {
+		return err
+	}
+	// changes captured after the fence are writes routed by the new resolver,
+	// as replays aren't captured.
+	fence := make(map[int]int64, len(r.DBs))
+	for shard, db := range r.DBs {
+		var id int64
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", rebalanceLogTable)).Scan(&id); err != nil {
+			return err
+		}
+		fence[shard] = id
+	}
+	r.Router.Switch(r.To)
+	// writes routed by the old resolver right before switching are replayed once more.
+	if err := r.replay(ctx, pl, cursors, fence); err != nil {
+		return err
+	}
+	for _, mv := range pl.list() {
+		if err := r.verify(ctx, mv, fence); err != nil {
+			return err
+		}
+	}
+	for _, mv := range pl.list() {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s::text = ANY($1)", quoteTable(r.Store.Name()), quoteColumn(r.Store.Pk().Name()))
+		if _, err := r.DBs[mv.From].ExecContext(ctx, query, pq.Array(pkStrings(mv.Pks))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rebalancer) installCapture(ctx context.Context, db *sql.DB) error {
+	queries := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id BIGSERIAL PRIMARY KEY, pk TEXT NOT NULL)", rebalanceLogTable),
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION %[1]s_capture() RETURNS TRIGGER AS $$
+BEGIN
+	IF current_setting('%[4]s', true) = 'on' THEN
+		RETURN NULL;
+	END IF;
+	IF TG_OP = 'DELETE' THEN
+		INSERT INTO %[2]s (pk) VALUES (OLD.%[3]s::text);
+	ELSE
+		INSERT INTO %[2]s (pk) VALUES (NEW.%[3]s::text);
+	END IF;
+	RETURN NULL;
+END $$ LANGUAGE plpgsql`, r.captureName(), rebalanceLogTable, quoteColumn(r.Store.Pk().Name()), rebalanceReplaySetting),
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %[1]s ON %[2]s", r.captureName(), quoteTable(r.Store.Name())),
+		fmt.Sprintf("CREATE TRIGGER %[1]s AFTER INSERT OR UPDATE OR DELETE ON %[2]s FOR EACH ROW EXECUTE FUNCTION %[1]s_capture()",
+			r.captureName(), quoteTable(r.Store.Name())),
+	}
+	for _, query := range queries {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rebalancer) dropCapture(ctx context.Context, db *sql.DB) error {
+	queries := []string{
+		fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", r.captureName(), quoteTable(r.Store.Name())),
+		fmt.Sprintf("DROP FUNCTION IF EXISTS %s_capture()", r.captureName()),
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", rebalanceLogTable),
+	}
+	for _, query := range queries {
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Rebalancer) captureName() string {
+	return "skyorm_rebalance_" + strings.Replace(r.Store.Name(), ".", "_", -1)
+}
+
+func (r *Rebalancer) copy(ctx context.Context, mv Move) error {
+	l, err := r.selectModels(ctx, r.DBs[mv.From], fmt.Sprintf(" WHERE %s::text = ANY($1)", quoteColumn(r.Store.Pk().Name())), pq.Array(pkStrings(mv.Pks)))
+	if err != nil {
+		return err
+	}
+	tx, err := r.DBs[mv.To].BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	props := storedProps(r.Store, r.Store.Props())
+	columns := make([]string, len(props))
+	for i, p := range props {
+		columns[i] = p.Name()
+	}
+	stmt, err := tx.PrepareContext(ctx, copyIn(r.Store.Name(), columns...))
+	if err != nil {
+		return err
+	}
+	for _, m := range l {
+		_, values := storedVals(m, false)
+		if _, err = stmt.ExecContext(ctx, r.binder().bindValues(values)...); err != nil {
+			return err
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return err
+	}
+	if err = stmt.Close(); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replay applies changes captured on shards after cursors to targets, models
+// changed on their sources which are routed elsewhere are added to the plan.
+// Models changed on targets after the fence are skipped unless it's nil.
+func (r *Rebalancer) replay(ctx context.Context, pl *rebalancePlan, cursors, fence map[int]int64) error {
+	for from, src := range r.DBs {
+		for {
+			rows, err := src.QueryContext(ctx,
+				fmt.Sprintf("SELECT id, pk FROM %s WHERE id > $1 ORDER BY id LIMIT %d", rebalanceLogTable, rebalancePageSize),
+				cursors[from],
+			)
+			if err != nil {
+				return err
+			}
+			changed := make([]string, 0)
+			last := cursors[from]
+			for rows.Next() {
+				var pk string
+				if err = rows.Scan(&last, &pk); err != nil {
+					_ = rows.Close()
+					return err
+				}
+				changed = append(changed, pk)
+			}
+			_ = rows.Close()
+			if err = rows.Err(); err != nil {
+				return err
+			}
+			for _, pk := range changed {
+				if err = r.replayChange(ctx, pl, from, pk, fence); err != nil {
+					return err
+				}
+			}
+			cursors[from] = last
+			if len(changed) < rebalancePageSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// replayChange replays change of the model with pk on shard from, moving it
+// when it's routed to another shard and wasn't planned yet.
+func (r *Rebalancer) replayChange(ctx context.Context, pl *rebalancePlan, from int, pk string, fence map[int]int64) error {
+	if mv, ok := pl.moved[from][pk]; ok {
+		return r.replayModel(ctx, *mv, pk, fence)
+	}
+	l, err := r.selectModels(ctx, r.DBs[from], fmt.Sprintf(" WHERE %s::text = $1", quoteColumn(r.Store.Pk().Name())), pk)
+	if err != nil || len(l) == 0 || !r.route(pl, from, l[0]) {
+		// models deleted before they were planned were never copied.
+		return err
+	}
+	return r.replayModel(ctx, *pl.moved[from][pk], pk, fence)
+}
+
+// replayModel replaces the model on the target with its source copy, unless
+// it was written on the target after the fence.
+func (r *Rebalancer) replayModel(ctx context.Context, mv Move, pk string, fence map[int]int64) error {
+	tx, err := r.DBs[mv.To].BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err = tx.ExecContext(ctx, "SELECT set_config($1, 'on', true)", rebalanceReplaySetting); err != nil {
+		return err
+	}
+	if fence != nil {
+		var written bool
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id > $1 AND pk = $2)", rebalanceLogTable)
+		if err = tx.QueryRowContext(ctx, query, fence[mv.To], pk).Scan(&written); err != nil {
+			return err
+		}
+		if written {
+			r.logLn("REBALANCE SKIP: %s %s written to shard %d after switch", r.Store.Name(), pk, mv.To)
+			return nil
+		}
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = $1", quoteTable(r.Store.Name()), quoteColumn(r.Store.Pk().Name()))
+	if _, err = tx.ExecContext(ctx, query, pk); err != nil {
+		return err
+	}
+	l, err := r.selectModels(ctx, r.DBs[mv.From], fmt.Sprintf(" WHERE %s = $1", quoteColumn(r.Store.Pk().Name())), pk)
+	if err != nil {
+		return err
+	}
+	for _, m := range l {
+		props, values := storedVals(m, false)
+		query = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			quoteTable(r.Store.Name()),
+			buildQueryProperties(props, false),
+			buildInsertPlaceholders(len(props)),
+		)
+		if _, err = tx.ExecContext(ctx, query, r.binder().bindValues(values)...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// verify compares counts of moved models on the source and the target, except
+// models written to the target after the fence.
+func (r *Rebalancer) verify(ctx context.Context, mv Move, fence map[int]int64) error {
+	written := make(map[string]bool)
+	rows, err := r.DBs[mv.To].QueryContext(ctx,
+		fmt.Sprintf("SELECT DISTINCT pk FROM %s WHERE id > $1 AND pk = ANY($2)", rebalanceLogTable),
+		fence[mv.To], pq.Array(pkStrings(mv.Pks)),
+	)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var pk string
+		if err = rows.Scan(&pk); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		written[pk] = true
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	l := make([]string, 0, len(mv.Pks))
+	for _, pk := range pkStrings(mv.Pks) {
+		if !written[pk] {
+			l = append(l, pk)
+		}
+	}
+	query := fmt.Sprintf("SELECT COUNT(%[1]s) FROM %[2]s WHERE %[1]s::text = ANY($1)", quoteColumn(r.Store.Pk().Name()), quoteTable(r.Store.Name()))
+	pks := pq.Array(l)
+	var src, dst int64
+	if err := r.DBs[mv.From].QueryRowContext(ctx, query, pks).Scan(&src); err != nil {
+		return err
+	}
+	if err := r.DBs[mv.To].QueryRowContext(ctx, query, pks).Scan(&dst); err != nil {
+		return err
+	}
+	if src != dst {
+		return fmt.Errorf("rebalance of %s from shard %d to shard %d: %d source models, %d target models",
+			r.Store.Name(), mv.From, mv.To, src, dst)
+	}
+	return nil
+}
+
+func (r *Rebalancer) selectModels(ctx context.Context, db *sql.DB, where string, args ...interface{}) ([]skyorm.Model, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", selectColumns(r.Store, r.Store.Props()), quoteTable(r.Store.Name())) + where
+	res, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]skyorm.Model, 0)
+	for res.Next() {
+		m := r.Store.Model()
+		if err = res.Scan(r.binder().scanPointers(m.OrmPointers())...); err != nil {
+			return nil, err
+		}
+		l = append(l, m)
+	}
+	return l, res.Err()
+}
+
+func (r *Rebalancer) logLn(format string, v ...interface{}) {
+	r.Logger.Printf(format+"\n", v...)
+}
+
+func pkStrings(pks []interface{}) []string {
+	l := make([]string, len(pks))
+	for i, pk := range pks {
+		l[i] = fmt.Sprint(derefValue(pk))
+	}
+	return l
+}
+
+func hashKey(key interface{}) uint32 {
+	h := fnv.New32a()
+	_, _ = fmt.Fprint(h, key)
+	return h.Sum32()
+}
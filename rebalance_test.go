@@ -0,0 +1,104 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+type session struct {
+	ID  int64
+	TTL time.Duration
+}
+
+var sessionStore = skyorm.NewStore("sessions", 0, func() skyorm.Model {
+	return &session{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("ttl", "time.Duration", false),
+)
+
+func (m *session) OrmStore() skyorm.Store     { return sessionStore }
+func (m *session) OrmPk() interface{}         { return m.ID }
+func (m *session) OrmPkProp() skyorm.Prop     { return sessionStore.Pk() }
+func (m *session) OrmPkPointer() interface{}  { return &m.ID }
+func (m *session) OrmProps() []skyorm.Prop    { return sessionStore.Props() }
+func (m *session) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.TTL} }
+func (m *session) OrmVals() []interface{}     { return []interface{}{m.ID, m.TTL} }
+
+func TestRebalancerScansWithProvider(t *testing.T) {
+	p, _, err := postgrestest.NewMock(postgres.WithIntervals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, mock := postgrestest.NewMockDB()
+	mock.ExpectQuery(`^SELECT id, ttl FROM sessions ORDER BY id LIMIT 1000$`).
+		WillReturnRows([]string{"id", "ttl"}, []interface{}{1, "00:01:30"})
+	var ttl time.Duration
+	r := &postgres.Rebalancer{
+		DBs:   []*sql.DB{db},
+		Store: sessionStore,
+		Key: func(m skyorm.Model) interface{} {
+			ttl = m.(*session).TTL
+			return m.OrmPk()
+		},
+		From:     postgres.HashResolver(1),
+		To:       postgres.HashResolver(1),
+		Provider: p,
+	}
+	if _, err = r.Plan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if ttl != 90*time.Second {
+		t.Fatalf("ttl %s", ttl)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRebalancerCopiesPointerPks(t *testing.T) {
+	dbs := make([]*sql.DB, 2)
+	mocks := make([]*postgrestest.Mock, 2)
+	for i := range dbs {
+		dbs[i], mocks[i] = postgrestest.NewMockDB()
+		for _, pattern := range []string{`^CREATE TABLE IF NOT EXISTS`, `^CREATE OR REPLACE FUNCTION`, `^DROP TRIGGER`, `^CREATE TRIGGER`} {
+			mocks[i].ExpectExec(pattern)
+		}
+		mocks[i].ExpectQuery(`^SELECT id, ttl FROM sessions ORDER BY id LIMIT 1000$`).WillReturnRows([]string{"id", "ttl"})
+	}
+	// pks of moves are matched by their values rather than by pointers.
+	mocks[0].ExpectQuery(`^SELECT id, ttl FROM sessions WHERE id::text = ANY\(\$1\)$`).
+		WithArgs(pq.Array([]string{"7"})).WillReturnRows([]string{"id", "ttl"}, []interface{}{7, 0})
+	for i := range dbs {
+		for _, pattern := range []string{`^DROP TRIGGER`, `^DROP FUNCTION`, `^DROP TABLE`} {
+			mocks[i].ExpectExec(pattern)
+		}
+	}
+	r := &postgres.Rebalancer{
+		DBs:    dbs,
+		Store:  sessionStore,
+		Key:    skyorm.Model.OrmPk,
+		From:   postgres.HashResolver(1),
+		To:     postgres.HashResolver(2),
+		Router: postgres.NewSwitchResolver(postgres.HashResolver(1)),
+	}
+	pk := int64(7)
+	// the mock doesn't support COPY, so the run stops once the models are read.
+	err := r.Run(context.Background(), []postgres.Move{{From: 0, To: 1, Pks: []interface{}{&pk}}})
+	if err == nil || !strings.Contains(err.Error(), "prepared statements are not supported") {
+		t.Fatalf("error %v", err)
+	}
+	for _, mock := range mocks {
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
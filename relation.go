@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// RelationKind is a kind of relation between stores.
+type RelationKind int
+
+const (
+	// BelongsTo relation is defined by a foreign key prop of the store referencing target pk.
+	BelongsTo RelationKind = iota
+	// HasMany relation is defined by a foreign key prop of target referencing the store pk.
+	HasMany
+)
+
+// Relation is a named relation of the store to target store.
+type Relation struct {
+	Name   string
+	Kind   RelationKind
+	Store  skyorm.Store
+	Target skyorm.Store
+	// Prop is the foreign key prop, of Store for BelongsTo and of Target for HasMany.
+	Prop skyorm.Prop
+	// OnDelete and OnUpdate are actions of the foreign key constraint created
+	// by EnsureConstraints and GenerateDDL.
+	OnDelete FKAction
+	OnUpdate FKAction
+}
+
+// RelationSetter is implemented by models which receive preloaded related models.
+type RelationSetter interface {
+	OrmSetRelation(name string, models []skyorm.Model)
+}
+
+var (
+	relationsMu sync.RWMutex
+	relations   = make(map[string]map[string]Relation)
+)
+
+// RegisterRelation registers relation, so it can be looked up by store and name.
+func RegisterRelation(r Relation) {
+	relationsMu.Lock()
+	defer relationsMu.Unlock()
+	if relations[r.Store.Name()] == nil {
+		relations[r.Store.Name()] = make(map[string]Relation)
+	}
+	relations[r.Store.Name()][r.Name] = r
+}
+
+// LookupRelation returns registered relation of the store by name.
+func LookupRelation(store skyorm.Store, name string) (Relation, bool) {
+	relationsMu.RLock()
+	defer relationsMu.RUnlock()
+	r, ok := relations[store.Name()][name]
+	return r, ok
+}
+
+// FindWith finds models like Find does and preloads their relations with one
+// IN query per relation. Models have to implement RelationSetter.
+func (p *provider) FindWith(ctx context.Context, store skyorm.Store, condition skyorm.Cond, preload ...Relation) ([]skyorm.Model, error) {
+	l, err := p.Find(ctx, store, condition, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range preload {
+		if err = p.preload(ctx, l, r); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (p *provider) preload(ctx context.Context, l []skyorm.Model, r Relation) error {
+	if len(l) == 0 {
+		return nil
+	}
+	var (
+		keys     = make([]interface{}, 0, len(l))
+		seen     = make(map[string]bool, len(l))
+		keyOf    func(m skyorm.Model) interface{}
+		targetOf func(m skyorm.Model) interface{}
+		matchBy  skyorm.Prop
+	)
+	switch r.Kind {
+	case BelongsTo:
+		keyOf = func(m skyorm.Model) interface{} {
+			return propVal(m, r.Prop)
+		}
+		targetOf = func(m skyorm.Model) interface{} {
+			return m.OrmPk()
+		}
+		matchBy = r.Target.Pk()
+	case HasMany:
+		keyOf = func(m skyorm.Model) interface{} {
+			return m.OrmPk()
+		}
+		targetOf = func(m skyorm.Model) interface{} {
+			return propVal(m, r.Prop)
+		}
+		matchBy = r.Prop
+	default:
+		return fmt.Errorf("unknown kind of relation %s", r.Name)
+	}
+	for _, m := range l {
+		key := keyOf(m)
+		if key == nil || seen[fmt.Sprint(key)] {
+			continue
+		}
+		seen[fmt.Sprint(key)] = true
+		keys = append(keys, key)
+	}
+	targets, err := p.Find(ctx, r.Target, In(matchBy, keys...), 0, 0)
+	if err != nil {
+		return err
+	}
+	grouped := make(map[string][]skyorm.Model, len(keys))
+	for _, t := range targets {
+		key := fmt.Sprint(targetOf(t))
+		grouped[key] = append(grouped[key], t)
+	}
+	for _, m := range l {
+		s, ok := m.(RelationSetter)
+		if !ok {
+			return fmt.Errorf("model of %s does not implement RelationSetter", r.Store.Name())
+		}
+		s.OrmSetRelation(r.Name, grouped[fmt.Sprint(keyOf(m))])
+	}
+	return nil
+}
+
+// propVal returns value of the model prop dereferenced like derefValue, values
+// of driver.Valuer props, e.g. sql.NullInt64, are resolved so they compare
+// equal to pks they reference.
+func propVal(m skyorm.Model, prop skyorm.Prop) interface{} {
+	vals := m.OrmVals()
+	for i, mp := range m.OrmProps() {
+		if mp.Name() != prop.Name() {
+			continue
+		}
+		v := derefValue(vals[i])
+		if valuer, ok := v.(driver.Valuer); ok {
+			if dv, err := valuer.Value(); err == nil {
+				return dv
+			}
+		}
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type comment struct {
+	ID     int64
+	UserID *int64
+	User   []skyorm.Model
+}
+
+var commentStore = skyorm.NewStore("comments", 0, func() skyorm.Model {
+	return &comment{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("user_id", "*int64", false),
+)
+
+func (m *comment) OrmStore() skyorm.Store     { return commentStore }
+func (m *comment) OrmPk() interface{}         { return m.ID }
+func (m *comment) OrmPkProp() skyorm.Prop     { return commentStore.Pk() }
+func (m *comment) OrmPkPointer() interface{}  { return &m.ID }
+func (m *comment) OrmProps() []skyorm.Prop    { return commentStore.Props() }
+func (m *comment) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.UserID} }
+func (m *comment) OrmVals() []interface{}     { return []interface{}{m.ID, m.UserID} }
+
+func (m *comment) OrmSetRelation(name string, models []skyorm.Model) { m.User = models }
+
+func TestFindWithPointerKeys(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, user_id FROM comments$`).
+		WillReturnRows([]string{"id", "user_id"}, []interface{}{1, 7}, []interface{}{2, nil})
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE id IN \(\$1\)$`).WithArgs(7).
+		WillReturnRows([]string{"id", "name"}, []interface{}{7, "a"})
+	r := postgres.Relation{Name: "user", Kind: postgres.BelongsTo, Store: commentStore, Target: userStore, Prop: commentStore.Props()[1]}
+	l, err := p.FindWith(context.Background(), commentStore, nil, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || len(l[0].(*comment).User) != 1 || len(l[1].(*comment).User) != 0 {
+		t.Fatalf("preloaded %v", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+)
+
+// ReplicaPolicy selects a replica for read queries.
+type ReplicaPolicy int
+
+const (
+	// RoundRobin uses replicas in turn.
+	RoundRobin ReplicaPolicy = iota
+	// LeastConn uses the replica with the fewest connections in use.
+	LeastConn
+)
+
+// WithReplicas makes Populate, Find, FindOne, Count, Exists and other read-only
+// queries run on read replicas selected by policy, while writes and transactions
+// use the primary database of New. Replicas share pool options of the primary.
+func WithReplicas(policy ReplicaPolicy, dsns ...string) Option {
+	return func(o *options) {
+		o.replicaPolicy = policy
+		o.replicas = append(o.replicas, dsns...)
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary makes reads of ctx use the primary, e.g. to read own writes
+// which may not be replicated yet.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+type readKey struct{}
+
+// forRead marks ctx of read-only query, which may run on a replica.
+func forRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readKey{}, true)
+}
+
+type replicaSet struct {
+	dbs    []*sql.DB
+	policy ReplicaPolicy
+	next   uint32
+	// down flags replicas which failed health check.
+	down []int32
+}
+
+// pick returns replica for a read or nil when every replica is down.
+func (r *replicaSet) pick() *sql.DB {
+	if r.policy == LeastConn {
+		var (
+			best  *sql.DB
+			inUse int
+		)
+		for i, db := range r.dbs {
+			if atomic.LoadInt32(&r.down[i]) == 1 {
+				continue
+			}
+			if n := db.Stats().InUse; best == nil || n < inUse {
+				best, inUse = db, n
+			}
+		}
+		return best
+	}
+	for range r.dbs {
+		i := int(atomic.AddUint32(&r.next, 1)-1) % len(r.dbs)
+		if atomic.LoadInt32(&r.down[i]) == 0 {
+			return r.dbs[i]
+		}
+	}
+	return nil
+}
+
+// setHealthy updates health of replica and reports whether it changed.
+func (r *replicaSet) setHealthy(i int, healthy bool) bool {
+	var down int32
+	if !healthy {
+		down = 1
+	}
+	return atomic.SwapInt32(&r.down[i], down) != down
+}
+
+func (r *replicaSet) close() {
+	for _, db := range r.dbs {
+		_ = db.Close()
+	}
+}
+
+// connFor returns connection query of ctx runs on.
+func (p *provider) connFor(ctx context.Context) conn {
+	if p.replicas == nil || p.conn != conn(p.db) {
+		return p.conn
+	}
+	if read, _ := ctx.Value(readKey{}).(bool); !read {
+		return p.conn
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary {
+		return p.conn
+	}
+	if db := p.replicas.pick(); db != nil {
+		return db
+	}
+	return p.conn
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// RetryPolicy configures retries of queries failed by transient errors.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one, 3 by default.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry, it doubles with every
+	// retry up to MaxBackoff. It defaults to 50ms and 2s.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Jitter is the fraction of backoff randomized, from 0 to 1.
+	Jitter float64
+	// Retryable reports whether error is transient, IsTransient by default.
+	Retryable func(err error) bool
+}
+
+// WithRetry makes read queries retry transient errors, such as connection resets
+// and failover blips, by policy. Writes are retried only when their ctx is marked
+// by Idempotent. Queries of transactions are never retried.
+func WithRetry(policy RetryPolicy) Option {
+	return func(o *options) {
+		if policy.MaxAttempts <= 0 {
+			policy.MaxAttempts = 3
+		}
+		if policy.BaseBackoff <= 0 {
+			policy.BaseBackoff = 50 * time.Millisecond
+		}
+		if policy.MaxBackoff <= 0 {
+			policy.MaxBackoff = 2 * time.Second
+		}
+		if policy.Retryable == nil {
+			policy.Retryable = IsTransient
+		}
+		o.retry = &policy
+	}
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks writes of ctx safe to retry, e.g. updates setting absolute values.
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// IsTransient reports whether err is a connection failure or server shutdown,
+// after which the query may succeed on a new connection.
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var (
+		pqErr  *pq.Error
+		netErr net.Error
+	)
+	switch {
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	case errors.As(err, &pqErr):
+		// connection exception class, admin shutdown, crash shutdown and cannot connect now.
+		return pqErr.Code.Class() == "08" || pqErr.Code == "57P01" || pqErr.Code == "57P02" || pqErr.Code == "57P03"
+	case errors.As(err, &netErr):
+		return true
+	}
+	// lib/pq reports some connection failures as plain errors.
+	return strings.Contains(err.Error(), "connection reset by peer")
+}
+
+// withRetry runs fn and retries it by retry policy of provider. Reads are
+// retried, writes, including the ones returning rows, only with Idempotent ctx.
+func (p *provider) withRetry(ctx context.Context, write bool, fn func() error) error {
+	err := fn()
+	if err == nil || p.retry == nil || p.conn != conn(p.db) {
+		return err
+	}
+	if read, _ := ctx.Value(readKey{}).(bool); write || !read {
+		if idempotent, _ := ctx.Value(idempotentKey{}).(bool); !idempotent {
+			return err
+		}
+	}
+	backoff := p.retry.BaseBackoff
+	for attempt := 1; attempt < p.retry.MaxAttempts && p.retry.Retryable(err); attempt++ {
+		d := backoff
+		if p.retry.Jitter > 0 {
+			d -= time.Duration(rand.Float64() * p.retry.Jitter * float64(backoff))
+		}
+		p.logf(LevelWarn, "RETRY %d in %s: %v", attempt, d, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(d):
+		}
+		if backoff *= 2; backoff > p.retry.MaxBackoff {
+			backoff = p.retry.MaxBackoff
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestRetryIdempotentPut(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithRetry(postgres.RetryPolicy{BaseBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^INSERT INTO users`).WillReturnError(errors.New("read: connection reset by peer"))
+	mock.ExpectQuery(`^INSERT INTO users`).WillReturnRows([]string{"id"}, []interface{}{1})
+	u := &user{Name: "a"}
+	if err = p.Put(postgres.Idempotent(context.Background()), u); err != nil {
+		t.Fatal(err)
+	}
+	if u.ID != 1 {
+		t.Fatalf("pk %d", u.ID)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRetrySkipsPut(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithRetry(postgres.RetryPolicy{BaseBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^INSERT INTO users`).WillReturnError(errors.New("read: connection reset by peer"))
+	// the insert may have been applied before the connection failed, so it isn't retried.
+	if err = p.Put(context.Background(), &user{Name: "a"}); err == nil || !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("error %v", err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
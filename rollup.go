@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Aggregate is an aggregated column of rollup table.
+type Aggregate struct {
+	// Func is one of COUNT, SUM, MIN and MAX.
+	Func string
+	// Prop is the aggregated prop of source store, it is ignored by COUNT.
+	Prop skyorm.Prop
+	// Column is the column of rollup table.
+	Column string
+}
+
+// Rollup is an aggregate table derived from append-only source store, maintained
+// incrementally by reading source models after a change cursor.
+type Rollup struct {
+	Name   string
+	Source skyorm.Store
+	// Table is the rollup table, it must have a unique constraint on group columns.
+	Table string
+	// GroupBy are the grouping props of source store, rollup table columns have the same names.
+	GroupBy    []skyorm.Prop
+	Aggregates []Aggregate
+	// Cursor is a monotonically increasing integer prop of source, e.g. serial pk.
+	Cursor skyorm.Prop
+	// Lag is the number of the newest cursor values left for the next catch-up,
+	// so models of transactions which are still in flight are not skipped.
+	Lag int64
+}
+
+const rollupCursorsTable = "skyorm_rollup_cursors"
+
+// CatchUpRollup aggregates source models inserted since the last catch-up into rollup
+// table and advances its cursor in the same transaction. It returns the new cursor.
+// Updates and deletes of already aggregated source models are not reflected.
+func (p *provider) CatchUpRollup(ctx context.Context, r Rollup) (int64, error) {
+	sets, err := rollupSets(r)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := p.beginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = tx.Close()
+	}()
+	queries := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name TEXT PRIMARY KEY, position BIGINT NOT NULL)", rollupCursorsTable),
+		fmt.Sprintf("INSERT INTO %s (name, position) VALUES ($1, 0) ON CONFLICT (name) DO NOTHING", rollupCursorsTable),
+	}
+	for i, query := range queries {
+		args := []interface{}{r.Name}
+		if i == 0 {
+			args = nil
+		}
+		if _, err = tx.exec(ctx, query, args...); err != nil {
+			return 0, err
+		}
+	}
+	var from, to int64
+	query := fmt.Sprintf("SELECT position FROM %s WHERE name = $1 FOR UPDATE", rollupCursorsTable)
+	if err = tx.queryRow(ctx, query, []interface{}{r.Name}, &from); err != nil {
+		return 0, err
+	}
+	query = fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) - $1 FROM %s", quoteColumn(r.Cursor.Name()), tx.table(ctx, r.Source.Name()))
+	if err = tx.queryRow(ctx, query, []interface{}{r.Lag}, &to); err != nil {
+		return 0, err
+	}
+	if to <= from {
+		return from, nil
+	}
+	groups := make([]string, len(r.GroupBy))
+	for i, g := range r.GroupBy {
+		groups[i] = quoteColumn(g.Name())
+	}
+	columns := append(append([]string(nil), groups...), make([]string, len(r.Aggregates))...)
+	selects := append(append([]string(nil), groups...), make([]string, len(r.Aggregates))...)
+	for i, a := range r.Aggregates {
+		columns[len(groups)+i] = a.Column
+		selects[len(groups)+i] = aggregateExpr(a)
+	}
+	query = fmt.Sprintf(
+		"INSERT INTO %[1]s (%[2]s) SELECT %[3]s FROM %[4]s WHERE %[5]s > $1 AND %[5]s <= $2 GROUP BY %[6]s ON CONFLICT (%[6]s) DO UPDATE SET %[7]s",
+		tx.tableAs(ctx, r.Table),
+		strings.Join(columns, ", "),
+		strings.Join(selects, ", "),
+		tx.table(ctx, r.Source.Name()),
+		quoteColumn(r.Cursor.Name()),
+		strings.Join(groups, ", "),
+		strings.Join(sets, ", "),
+	)
+	if _, err = tx.exec(withOp(ctx, OpRollup), query, from, to); err != nil {
+		return 0, err
+	}
+	query = fmt.Sprintf("UPDATE %s SET position = $1 WHERE name = $2", rollupCursorsTable)
+	if _, err = tx.exec(ctx, query, to, r.Name); err != nil {
+		return 0, err
+	}
+	return to, tx.Commit()
+}
+
+// RunRollups catches up rollups every interval until ctx is done, errors are
+// logged. It returns error of ctx once it's done, or at once when interval
+// isn't positive.
+func (p *provider) RunRollups(ctx context.Context, interval time.Duration, rollups ...Rollup) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid rollup interval %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, r := range rollups {
+			if _, err := p.CatchUpRollup(ctx, r); err != nil && ctx.Err() == nil {
+				p.logf(LevelError, "ROLLUP %s ERROR: %v", r.Name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func aggregateExpr(a Aggregate) string {
+	if a.Func == "COUNT" {
+		return "COUNT(*)"
+	}
+	return a.Func + "(" + quoteColumn(a.Prop.Name()) + ")"
+}
+
+// rollupSets returns SET clauses merging aggregated delta into existing rollup rows.
+func rollupSets(r Rollup) ([]string, error) {
+	l := make([]string, len(r.Aggregates))
+	for i, a := range r.Aggregates {
+		c := a.Column
+		switch a.Func {
+		case "COUNT", "SUM":
+			l[i] = fmt.Sprintf("%[1]s = %[2]s.%[1]s + EXCLUDED.%[1]s", c, quoteTable(r.Table))
+		case "MIN":
+			l[i] = fmt.Sprintf("%[1]s = LEAST(%[2]s.%[1]s, EXCLUDED.%[1]s)", c, quoteTable(r.Table))
+		case "MAX":
+			l[i] = fmt.Sprintf("%[1]s = GREATEST(%[2]s.%[1]s, EXCLUDED.%[1]s)", c, quoteTable(r.Table))
+		default:
+			return nil, fmt.Errorf("rollup %s: aggregate %s can't be maintained incrementally", r.Name, a.Func)
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,18 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestRunRollupsRejectsNonPositiveInterval(t *testing.T) {
+	p, _, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = p.RunRollups(context.Background(), 0); err == nil {
+		t.Fatal("ran rollups without interval")
+	}
+}
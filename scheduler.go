@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// MisfirePolicy defines what scheduler does with runs missed while no scheduler was running.
+type MisfirePolicy string
+
+const (
+	// MisfireSkip skips missed runs and waits for the next scheduled time.
+	MisfireSkip MisfirePolicy = "skip"
+	// MisfireRunOnce runs a schedule once for all missed runs.
+	MisfireRunOnce MisfirePolicy = "run-once"
+	// MisfireCatchUp runs a schedule for every missed run.
+	MisfireCatchUp MisfirePolicy = "catch-up"
+)
+
+// Schedule is a named recurring job of the scheduler.
+type Schedule struct {
+	Name string
+	// Cron is a standard cron expression evaluated in Location.
+	Cron     string
+	Location *time.Location
+	// Jitter is the maximum random delay added to every run.
+	Jitter  time.Duration
+	Misfire MisfirePolicy
+}
+
+// ScheduleHandler runs a schedule due at the given time.
+type ScheduleHandler func(ctx context.Context, name string, due time.Time) error
+
+// Scheduler runs cron schedules persisted in postgres table, so every run is
+// claimed by exactly one of the schedulers sharing the table.
+type Scheduler struct {
+	p     *provider
+	table string
+}
+
+// Scheduler returns scheduler persisting schedules into the table, skyorm_schedules by default.
+func (p *provider) Scheduler(table string) *Scheduler {
+	if table == "" {
+		table = "skyorm_schedules"
+	}
+	return &Scheduler{p, table}
+}
+
+// CreateTable creates schedules table if it doesn't exist.
+func (s *Scheduler) CreateTable(ctx context.Context) error {
+	_, err := s.p.exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	name TEXT PRIMARY KEY,
+	cron TEXT NOT NULL,
+	timezone TEXT NOT NULL,
+	jitter BIGINT NOT NULL,
+	misfire TEXT NOT NULL,
+	due_at TIMESTAMPTZ NOT NULL,
+	run_at TIMESTAMPTZ NOT NULL,
+	last_run_at TIMESTAMPTZ
+)`, quoteTable(s.table)))
+	return err
+}
+
+// Register creates or updates the schedule. Next run is recomputed only when
+// cron expression or time zone changed, or the schedule was disabled.
+func (s *Scheduler) Register(ctx context.Context, sc Schedule) error {
+	c, err := ParseCron(sc.Cron)
+	if err != nil {
+		return err
+	}
+	if sc.Location == nil {
+		sc.Location = time.UTC
+	}
+	if sc.Misfire == "" {
+		sc.Misfire = MisfireSkip
+	}
+	due := c.Next(time.Now().In(sc.Location))
+	if due.IsZero() {
+		return fmt.Errorf("schedule %s: cron %q never fires", sc.Name, sc.Cron)
+	}
+	query := fmt.Sprintf(`INSERT INTO %[1]s (name, cron, timezone, jitter, misfire, due_at, run_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (name) DO UPDATE SET jitter = EXCLUDED.jitter, misfire = EXCLUDED.misfire,
+	cron = EXCLUDED.cron, timezone = EXCLUDED.timezone,
+	due_at = CASE WHEN %[1]s.cron = EXCLUDED.cron AND %[1]s.timezone = EXCLUDED.timezone AND %[1]s.run_at <> 'infinity' THEN %[1]s.due_at ELSE EXCLUDED.due_at END,
+	run_at = CASE WHEN %[1]s.cron = EXCLUDED.cron AND %[1]s.timezone = EXCLUDED.timezone AND %[1]s.run_at <> 'infinity' THEN %[1]s.run_at ELSE EXCLUDED.run_at END`, quoteTable(s.table))
+	_, err = s.p.exec(ctx, query, sc.Name, sc.Cron, sc.Location.String(), int64(sc.Jitter), string(sc.Misfire), due, withJitter(due, sc.Jitter))
+	return err
+}
+
+// Run claims and runs due schedules every interval until ctx is done. A run is
+// claimed before the handler is called, so runs are executed at most once. It
+// returns error of ctx once it's done, or at once when interval isn't positive.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration, handler ScheduleHandler) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid scheduler interval %s", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for {
+			name, due, ok, err := s.claim(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.p.logf(LevelError, "SCHEDULER CLAIM ERROR: %v", err)
+				}
+				break
+			}
+			if !ok {
+				break
+			}
+			if err = handler(ctx, name, due); err != nil {
+				s.p.logf(LevelError, "SCHEDULE %s ERROR: %v", name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim locks a due schedule, advances it according to its misfire policy and
+// returns whether it has to be run. Schedules which next run can't be computed,
+// e.g. of unknown time zone, are disabled until they are registered again.
+func (s *Scheduler) claim(ctx context.Context) (string, time.Time, bool, error) {
+	tx, err := s.p.beginTx(ctx, nil)
+	if err != nil {
+		return "", time.Time{}, false, err
+	}
+	defer func() {
+		_ = tx.Close()
+	}()
+	var (
+		name, expr, tz, misfire string
+		jitter                  int64
+		due                     time.Time
+	)
+	query := fmt.Sprintf(`SELECT name, cron, timezone, jitter, misfire, due_at FROM %s
+WHERE run_at <= now() ORDER BY run_at LIMIT 1 FOR UPDATE SKIP LOCKED`, quoteTable(s.table))
+	err = tx.queryRow(ctx, query, nil, &name, &expr, &tz, &jitter, &misfire, &due)
+	if err == sql.ErrNoRows {
+		return "", time.Time{}, false, nil
+	}
+	if err != nil {
+		return "", time.Time{}, false, err
+	}
+	c, err := ParseCron(expr)
+	if err != nil {
+		return s.disable(ctx, tx, name, err)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return s.disable(ctx, tx, name, err)
+	}
+	now := time.Now().In(loc)
+	due = due.In(loc)
+	next := c.Next(due)
+	missed := !next.IsZero() && !next.After(now)
+	run := true
+	switch MisfirePolicy(misfire) {
+	case MisfireSkip:
+		run = !missed
+		next = c.Next(now)
+	case MisfireRunOnce:
+		next = c.Next(now)
+	case MisfireCatchUp:
+		// the next missed run becomes due immediately.
+	}
+	if next.IsZero() {
+		return s.disable(ctx, tx, name, fmt.Errorf("cron %q never fires", expr))
+	}
+	query = fmt.Sprintf("UPDATE %s SET due_at = $1, run_at = $2, last_run_at = CASE WHEN $3 THEN now() ELSE last_run_at END WHERE name = $4", quoteTable(s.table))
+	if _, err = tx.exec(ctx, query, next, withJitter(next, time.Duration(jitter)), run, name); err != nil {
+		return "", time.Time{}, false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return "", time.Time{}, false, err
+	}
+	if !run {
+		s.p.logf(LevelInfo, "SCHEDULE %s MISFIRE: skipped run due at %s", name, due)
+		// another schedule may be due, so the caller keeps claiming.
+		return s.claim(ctx)
+	}
+	return name, due, true, nil
+}
+
+// disable disables the schedule claimed by tx, so it doesn't block other
+// schedules, and claims the next one.
+func (s *Scheduler) disable(ctx context.Context, t *tx, name string, cause error) (string, time.Time, bool, error) {
+	s.p.logf(LevelError, "SCHEDULE %s DISABLED: %v", name, cause)
+	query := fmt.Sprintf("UPDATE %s SET run_at = 'infinity' WHERE name = $1", quoteTable(s.table))
+	if _, err := t.exec(ctx, query, name); err != nil {
+		return "", time.Time{}, false, err
+	}
+	if err := t.Commit(); err != nil {
+		return "", time.Time{}, false, err
+	}
+	return s.claim(ctx)
+}
+
+func withJitter(t time.Time, jitter time.Duration) time.Time {
+	if jitter <= 0 {
+		return t
+	}
+	return t.Add(time.Duration(rand.Int63n(int64(jitter))))
+}
@@ -0,0 +1,44 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestSchedulerDisablesBrokenSchedule(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := p.Scheduler("")
+	cols := []string{"name", "cron", "timezone", "jitter", "misfire", "due_at"}
+	due := time.Now().Add(-time.Second).UTC().Truncate(time.Minute)
+	claim := `^SELECT name, cron, timezone, jitter, misfire, due_at FROM skyorm_schedules`
+	mock.ExpectQuery(claim).WillReturnRows(cols, []interface{}{"broken", "* * * * *", "Nowhere/Nothing", 0, string(postgres.MisfireSkip), due})
+	mock.ExpectExec(`^UPDATE skyorm_schedules SET run_at = 'infinity' WHERE name = \$1$`).WithArgs("broken").WillReturnResult(1)
+	mock.ExpectQuery(claim).WillReturnRows(cols, []interface{}{"report", "* * * * *", "UTC", 0, string(postgres.MisfireRunOnce), due})
+	mock.ExpectExec(`^UPDATE skyorm_schedules SET due_at = \$1`).WillReturnResult(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran []string
+	err = s.Run(ctx, time.Hour, func(ctx context.Context, name string, due time.Time) error {
+		ran = append(ran, name)
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "report" {
+		t.Fatalf("ran %v", ran)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Run(ctx, 0, nil); err == nil {
+		t.Fatal("ran with zero interval")
+	}
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"database/sql/driver"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// DefaultSearchConfig is the text search configuration used when config is empty.
+const DefaultSearchConfig = "simple"
+
+// Search returns full text search condition matching text property against
+// plain query: to_tsvector(config, prop) @@ plainto_tsquery(config, query).
+func Search(prop skyorm.Prop, query, config string) skyorm.Cond {
+	config = searchConfig(config)
+	return &exprCond{skyorm.Eq(prop, query), func(n *int) (string, []interface{}) {
+		s := "to_tsvector(" + pq.QuoteLiteral(config) + ", " + quoteColumn(prop.Name()) + ") @@ plainto_tsquery(" +
+			pq.QuoteLiteral(config) + ", " + placeholder(n) + ")"
+		return s, []interface{}{query}
+	}}
+}
+
+// SearchVector returns full text search condition matching tsvector property
+// against plain query: prop @@ plainto_tsquery(config, query).
+func SearchVector(prop skyorm.Prop, query, config string) skyorm.Cond {
+	config = searchConfig(config)
+	return &exprCond{skyorm.Eq(prop, query), func(n *int) (string, []interface{}) {
+		return quoteColumn(prop.Name()) + " @@ plainto_tsquery(" + pq.QuoteLiteral(config) + ", " + placeholder(n) + ")", []interface{}{query}
+	}}
+}
+
+// SearchRank returns property of search rank of text property for plain query,
+// to be used in order, e.g. Desc(SearchRank(prop, query, "english")).
+func SearchRank(prop skyorm.Prop, query, config string) skyorm.Prop {
+	config = pq.QuoteLiteral(searchConfig(config))
+	return &exprProp{prop, "ts_rank(to_tsvector(" + config + ", " + quoteColumn(prop.Name()) + "), plainto_tsquery(" +
+		config + ", " + pq.QuoteLiteral(query) + "))"}
+}
+
+// TSVector returns value of tsvector property computed from text by the database on
+// Put and Update, so tsvector columns are maintained along with the source text.
+func TSVector(text, config string) interface{} {
+	return &tsvector{text, searchConfig(config)}
+}
+
+type tsvector struct {
+	text   string
+	config string
+}
+
+func (v *tsvector) Value() (driver.Value, error) {
+	return v.text, nil
+}
+
+func (v *tsvector) expr(ph string) string {
+	return "to_tsvector(" + pq.QuoteLiteral(v.config) + ", " + ph + ")"
+}
+
+func searchConfig(config string) string {
+	if config == "" {
+		return DefaultSearchConfig
+	}
+	return config
+}
@@ -0,0 +1,42 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestSearch(t *testing.T) {
+	name := userStore.Props()[1]
+	expectFind(t, postgres.Search(name, "ann lee", ""),
+		`^SELECT id, name FROM users WHERE to_tsvector\('simple', name\) @@ plainto_tsquery\('simple', \$1\)$`, "ann lee")
+	doc := skyorm.NewProp("doc", "string", false)
+	expectFind(t, postgres.SearchVector(doc, "ann", "english"),
+		`^SELECT id, name FROM users WHERE doc @@ plainto_tsquery\('english', \$1\)$`, "ann")
+}
+
+func TestSearchRankAndVector(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	name := userStore.Props()[1]
+	mock.ExpectQuery(`^SELECT id, name FROM users ORDER BY ts_rank\(to_tsvector\('english', name\), plainto_tsquery\('english', 'ann'\)\) DESC LIMIT 1$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{1, "ann"})
+	if _, err = p.FindOne(ctx, userStore, nil, postgres.Desc(postgres.SearchRank(name, "ann", "english"))); err != nil {
+		t.Fatal(err)
+	}
+	doc := skyorm.NewProp("doc", "string", false)
+	mock.ExpectExec(`^UPDATE users SET doc = to_tsvector\('simple', \$1\) WHERE id = \$2$`).WithArgs("ann", 1).WillReturnResult(1)
+	if err = p.Update(ctx, userStore, skyorm.Eq(userStore.Pk(), 1), skyorm.NewVal(doc, postgres.TSVector("ann", ""))); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/skyorm/skyorm"
+)
+
+// sequence returns name of the sequence backing serial or identity pk of the store.
+func (p *provider) sequence(ctx context.Context, store skyorm.Store) (string, error) {
+	var name sql.NullString
+	// the column name is taken literally, unlike the table name.
+	err := p.queryRow(ctx, "SELECT pg_get_serial_sequence($1, $2)", []interface{}{p.table(ctx, store.Name()), foldIdent(store.Pk().Name())}, &name)
+	if err != nil {
+		return "", err
+	}
+	if !name.Valid {
+		return "", fmt.Errorf("pk %s of %s has no sequence", store.Pk().Name(), store.Name())
+	}
+	return name.String, nil
+}
+
+// CurrentSequenceValue returns the last value of the sequence backing pk of the
+// store, zero when it wasn't used yet.
+func (p *provider) CurrentSequenceValue(ctx context.Context, store skyorm.Store) (int64, error) {
+	seq, err := p.sequence(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	err = p.queryRow(ctx, "SELECT COALESCE(pg_sequence_last_value($1::regclass), 0)", []interface{}{seq}, &v)
+	return v, err
+}
+
+// NextSequenceValue advances the sequence backing pk of the store and returns
+// its next value, e.g. to assign pk before insert.
+func (p *provider) NextSequenceValue(ctx context.Context, store skyorm.Store) (int64, error) {
+	seq, err := p.sequence(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	err = p.queryRow(ctx, "SELECT nextval($1::regclass)", []interface{}{seq}, &v)
+	return v, err
+}
+
+// ResetSequence sets the sequence backing pk of the store so the next pk it
+// generates is to.
+func (p *provider) ResetSequence(ctx context.Context, store skyorm.Store, to int64) error {
+	seq, err := p.sequence(ctx, store)
+	if err != nil {
+		return err
+	}
+	var v int64
+	return p.queryRow(ctx, "SELECT setval($1::regclass, $2, false)", []interface{}{seq, to}, &v)
+}
+
+// SyncSequence sets the sequence backing pk of the store past the greatest pk,
+// e.g. after bulk imports of models with explicit pks, so inserts don't conflict
+// with them.
+func (p *provider) SyncSequence(ctx context.Context, store skyorm.Store) error {
+	seq, err := p.sequence(ctx, store)
+	if err != nil {
+		return err
+	}
+	var v int64
+	query := fmt.Sprintf("SELECT setval($1::regclass, COALESCE((SELECT max(%s) FROM %s), 0) + 1, false)",
+		quoteColumn(store.Pk().Name()), p.table(ctx, store.Name()))
+	return p.queryRow(ctx, query, []interface{}{seq}, &v)
+}
@@ -0,0 +1,120 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type invoice struct {
+	Order int64
+}
+
+var invoiceStore = skyorm.NewStore("invoices", 0, func() skyorm.Model {
+	return &invoice{}
+},
+	skyorm.NewProp("order", "int64", true),
+)
+
+func (m *invoice) OrmStore() skyorm.Store     { return invoiceStore }
+func (m *invoice) OrmPk() interface{}         { return m.Order }
+func (m *invoice) OrmPkProp() skyorm.Prop     { return invoiceStore.Pk() }
+func (m *invoice) OrmPkPointer() interface{}  { return &m.Order }
+func (m *invoice) OrmProps() []skyorm.Prop    { return invoiceStore.Props() }
+func (m *invoice) OrmPointers() []interface{} { return []interface{}{&m.Order} }
+func (m *invoice) OrmVals() []interface{}     { return []interface{}{m.Order} }
+
+func expectSequence(mock *postgrestest.Mock) {
+	mock.ExpectQuery(`^SELECT pg_get_serial_sequence\(\$1, \$2\)$`).WithArgs("invoices", "order").
+		WillReturnRows([]string{"seq"}, []interface{}{"public.invoices_order_seq"})
+}
+
+func TestSequences(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	expectSequence(mock)
+	mock.ExpectQuery(`^SELECT COALESCE\(pg_sequence_last_value\(\$1::regclass\), 0\)$`).WithArgs("public.invoices_order_seq").
+		WillReturnRows([]string{"v"}, []interface{}{41})
+	v, err := p.CurrentSequenceValue(ctx, invoiceStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 41 {
+		t.Fatalf("current value %d", v)
+	}
+	expectSequence(mock)
+	mock.ExpectQuery(`^SELECT setval\(\$1::regclass, \$2, false\)$`).WithArgs("public.invoices_order_seq", 100).
+		WillReturnRows([]string{"setval"}, []interface{}{100})
+	if err = p.ResetSequence(ctx, invoiceStore, 100); err != nil {
+		t.Fatal(err)
+	}
+	expectSequence(mock)
+	mock.ExpectQuery(`^SELECT setval\(\$1::regclass, COALESCE\(\(SELECT max\("order"\) FROM invoices\), 0\) \+ 1, false\)$`).
+		WithArgs("public.invoices_order_seq").WillReturnRows([]string{"setval"}, []interface{}{42})
+	if err = p.SyncSequence(ctx, invoiceStore); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSequenceMissing(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT pg_get_serial_sequence`).WillReturnRows([]string{"seq"}, []interface{}{nil})
+	if _, err = p.CurrentSequenceValue(context.Background(), invoiceStore); err == nil {
+		t.Fatal("sequence of pk without sequence")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type ledger struct {
+	ID int64
+}
+
+var ledgerStore = skyorm.NewStore("Ledgers", 0, func() skyorm.Model {
+	return &ledger{}
+},
+	skyorm.NewProp("ID", "int64", true),
+)
+
+func (m *ledger) OrmStore() skyorm.Store     { return ledgerStore }
+func (m *ledger) OrmPk() interface{}         { return m.ID }
+func (m *ledger) OrmPkProp() skyorm.Prop     { return ledgerStore.Pk() }
+func (m *ledger) OrmPkPointer() interface{}  { return &m.ID }
+func (m *ledger) OrmProps() []skyorm.Prop    { return ledgerStore.Props() }
+func (m *ledger) OrmPointers() []interface{} { return []interface{}{&m.ID} }
+func (m *ledger) OrmVals() []interface{}     { return []interface{}{m.ID} }
+
+func TestSequenceOfFoldedPk(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unquoted ID column is created as id, which pg_get_serial_sequence doesn't fold.
+	mock.ExpectQuery(`^SELECT pg_get_serial_sequence\(\$1, \$2\)$`).WithArgs("Ledgers", "id").
+		WillReturnRows([]string{"seq"}, []interface{}{"public.ledgers_id_seq"})
+	mock.ExpectQuery(`^SELECT nextval\(\$1::regclass\)$`).WithArgs("public.ledgers_id_seq").
+		WillReturnRows([]string{"nextval"}, []interface{}{int64(7)})
+	v, err := p.NextSequenceValue(context.Background(), ledgerStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 7 {
+		t.Fatalf("next value %d", v)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
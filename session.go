@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type sessionVarsKey struct{}
+
+// WithSessionVars returns context which operations run with the session
+// variables set locally, e.g. app.current_user_id read by row-level security
+// policies with current_setting('app.current_user_id', true). Operations outside
+// of a transaction are wrapped in one setting the variables, transactions begun
+// with the context set them once for the whole transaction, so they never leak
+// to other users of pooled connections. Variables of parent context are
+// inherited and overridden by vars. Writes buffered by WithAsyncStores are
+// flushed without context, so they ignore the variables.
+func WithSessionVars(ctx context.Context, vars map[string]string) context.Context {
+	merged := make(map[string]string, len(vars))
+	for k, v := range SessionVars(ctx) {
+		merged[k] = v
+	}
+	for k, v := range vars {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, sessionVarsKey{}, merged)
+}
+
+// SessionVars returns session variables of the context.
+func SessionVars(ctx context.Context) map[string]string {
+	vars, _ := ctx.Value(sessionVarsKey{}).(map[string]string)
+	return vars
+}
+
+// localSettings returns settings of ctx set locally to transactions of
+// operations, session variables and statement timeout.
+func localSettings(ctx context.Context) map[string]string {
+	vars := SessionVars(ctx)
+	d, ok := ctx.Value(statementTimeoutKey{}).(time.Duration)
+	if !ok {
+		return vars
+	}
+	m := make(map[string]string, len(vars)+1)
+	for k, v := range vars {
+		m[k] = v
+	}
+	m["statement_timeout"] = timeoutMillis(d)
+	return m
+}
+
+// setSessionVars sets local settings of ctx locally to the transaction c.
+func setSessionVars(ctx context.Context, c conn) error {
+	vars := localSettings(ctx)
+	if len(vars) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	calls := make([]string, len(names))
+	args := make([]interface{}, 0, len(names)*2)
+	for i, name := range names {
+		calls[i] = "set_config($" + strconv.Itoa(i*2+1) + ", $" + strconv.Itoa(i*2+2) + ", true)"
+		args = append(args, name, vars[name])
+	}
+	_, err := c.ExecContext(ctx, "SELECT "+strings.Join(calls, ", "), args...)
+	return err
+}
+
+// sessionTx is a transaction wrapping a single operation to set session
+// variables of its context.
+type sessionTx struct {
+	*sql.Tx
+	// kept is set by queries which rows are read after the operation,
+	// transaction is committed when they are closed.
+	kept bool
+}
+
+// bareKey marks context of statements which can't run inside of a
+// transaction, e.g. concurrent index builds.
+type bareKey struct{}
+
+// inSession runs do on c, in a transaction setting local settings of ctx
+// when there are any, c is not a transaction already and ctx isn't bare.
+func inSession(ctx context.Context, c conn, do func(c conn) (int64, error)) (int64, error) {
+	db, ok := c.(*sql.DB)
+	if bare, _ := ctx.Value(bareKey{}).(bool); !ok || bare || len(localSettings(ctx)) == 0 {
+		return do(c)
+	}
+	t, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return -1, err
+	}
+	if err = setSessionVars(ctx, t); err != nil {
+		_ = t.Rollback()
+		return -1, err
+	}
+	st := &sessionTx{Tx: t}
+	n, err := do(st)
+	if err != nil && err != sql.ErrNoRows {
+		_ = t.Rollback()
+		return n, err
+	}
+	if !st.kept {
+		if cerr := t.Commit(); cerr != nil {
+			return n, cerr
+		}
+	}
+	return n, err
+}
+
+// rows are rows of query, closing them commits the transaction the query ran in
+// to set session variables.
+type rows struct {
+	*sql.Rows
+	tx *sql.Tx
+}
+
+func (r *rows) Close() error {
+	err := r.Rows.Close()
+	if r.tx != nil {
+		if cerr := r.tx.Commit(); err == nil {
+			err = cerr
+		}
+		r.tx = nil
+	}
+	return err
+}
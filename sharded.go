@@ -0,0 +1,434 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/skyorm/skyorm"
+)
+
+// ShardResolver maps a shard key to an index of shard.
+type ShardResolver interface {
+	Shard(key interface{}) int
+}
+
+// HashResolver resolves shard by hash of the key modulo number of shards.
+type HashResolver int
+
+// Shard returns index of shard for the key.
+func (r HashResolver) Shard(key interface{}) int {
+	return int(hashKey(key) % uint32(r))
+}
+
+// ShardKey tells how models of a store are routed to shards.
+type ShardKey struct {
+	// Prop is the shard key property. Find, Update, Delete and Count which
+	// conditions pin it with equality run on a single shard. Without Prop,
+	// models are routed by Key and these operations are scattered.
+	Prop skyorm.Prop
+	// Key returns shard key of the model, value of Prop is used by default.
+	Key func(m skyorm.Model) interface{}
+}
+
+// ShardedConfig is a configuration of sharded provider.
+type ShardedConfig struct {
+	// Key returns shard key of the model, model pk is used by default.
+	// It's used for stores without shard key in Stores.
+	Key func(m skyorm.Model) interface{}
+	// Stores are shard keys by store name.
+	Stores map[string]ShardKey
+	// Resolver maps shard key to shard, HashResolver is used by default.
+	Resolver ShardResolver
+	// PkRouted tells that shard key is the pk, so Populate is routed
+	// to a single shard instead of being tried on every shard.
+	PkRouted bool
+	// Indexes are global secondary indexes maintained by Put, Update and
+	// Delete, which write models of indexed stores in transactions of shards,
+	// so shards have to be Providers. New mappings are committed before the
+	// shard and old ones are deleted after it.
+	Indexes []GlobalIndex
+	// IndexDB is the database of the designated shard holding index mapping tables.
+	IndexDB *sql.DB
+	Logger  skyorm.Logger
+}
+
+// ShardStats holds metrics of a shard.
+type ShardStats struct {
+	Queries     int64
+	Errors      int64
+	Duration    time.Duration
+	LastError   error
+	LastErrorAt time.Time
+}
+
+// Healthy reports whether the last operation on the shard succeeded.
+func (s ShardStats) Healthy() bool {
+	return s.LastError == nil
+}
+
+// ErrShardKeyUnassigned is returned by Put of models routed by pk which pks are
+// assigned by the database, e.g. serial ones, as their shard isn't known before
+// they are inserted. Such stores need client assigned pks or a non-pk shard key.
+var ErrShardKeyUnassigned = errors.New("postgres: shard key of model is not assigned")
+
+// NewSharded returns provider which distributes models across shards by shard key.
+// Reads which can't be routed by key are scattered to every shard and merged.
+func NewSharded(shards []skyorm.Provider, cfg ShardedConfig) (*Sharded, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("no shards")
+	}
+	if r, ok := cfg.Resolver.(HashResolver); ok && int(r) != len(shards) {
+		return nil, fmt.Errorf("hash resolver of %d shards over %d shards", r, len(shards))
+	}
+	pkKeyed := make(map[string]bool, len(cfg.Stores))
+	defaultKeyed := cfg.PkRouted || cfg.Key == nil
+	if cfg.Key == nil {
+		cfg.Key = func(m skyorm.Model) interface{} {
+			return m.OrmPk()
+		}
+	}
+	if cfg.Resolver == nil {
+		cfg.Resolver = HashResolver(len(shards))
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = skyorm.DefaultLogger
+	}
+	stores := make(map[string]ShardKey, len(cfg.Stores))
+	for name, k := range cfg.Stores {
+		if k.Prop == nil && k.Key == nil {
+			return nil, fmt.Errorf("shard key of %s has neither prop nor key", name)
+		}
+		if k.Key == nil {
+			k.Key = propValue(k.Prop)
+			pkKeyed[name] = k.Prop.IsPk()
+		}
+		stores[name] = k
+	}
+	cfg.Stores = stores
+	return &Sharded{
+		shards:       shards,
+		cfg:          cfg,
+		stats:        make([]ShardStats, len(shards)),
+		pkKeyed:      pkKeyed,
+		defaultKeyed: defaultKeyed,
+	}, nil
+}
+
+// OpenSharded opens provider of every shard, dsns[i] being the DSN of shard i,
+// and returns sharded provider over them. Opened shards are closed on error.
+func OpenSharded(dsns []string, log skyorm.Logger, cfg ShardedConfig, opts ...Option) (*Sharded, error) {
+	shards := make([]skyorm.Provider, 0, len(dsns))
+	for i, dsn := range dsns {
+		p, err := New(dsn, log, opts...)
+		if err != nil {
+			for _, shard := range shards {
+				_ = shard.(Provider).Close()
+			}
+			return nil, fmt.Errorf("shard %d: %w", i, err)
+		}
+		shards = append(shards, p)
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log
+	}
+	s, err := NewSharded(shards, cfg)
+	if err != nil {
+		for _, shard := range shards {
+			_ = shard.(Provider).Close()
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// Sharded is a provider over several shard providers.
+type Sharded struct {
+	shards []skyorm.Provider
+	cfg    ShardedConfig
+	mu     sync.Mutex
+	stats  []ShardStats
+	// pkKeyed tells stores of Stores which shard key is their pk, defaultKeyed
+	// whether Key of the config returns pk.
+	pkKeyed      map[string]bool
+	defaultKeyed bool
+}
+
+func (s *Sharded) Put(ctx context.Context, models ...skyorm.Model) error {
+	groups := make(map[int][]skyorm.Model)
+	for _, m := range models {
+		if s.keyedByPk(m.OrmStore()) && pkOmitted(ctx, m) {
+			return fmt.Errorf("%w: pk of %s is assigned by the database", ErrShardKeyUnassigned, m.OrmStore().Name())
+		}
+		i, err := s.shardOf(s.keyOf(m))
+		if err != nil {
+			return err
+		}
+		groups[i] = append(groups[i], m)
+	}
+	for i, l := range groups {
+		indexes := false
+		for _, m := range l {
+			indexes = indexes || len(s.storeIndexes(m.OrmStore())) > 0
+		}
+		start := time.Now()
+		// mappings are written after models, since serial pks are known only then.
+		err := s.indexed(ctx, i, indexes, func(shard skyorm.Provider) error {
+			return shard.Put(ctx, l...)
+		}, func(_ skyorm.Provider, tx *sql.Tx) error {
+			return s.putIndexes(ctx, tx, i, l)
+		}, nil)
+		if err = s.track(i, start, err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sharded) Populate(ctx context.Context, model skyorm.Model, pk interface{}) error {
+	if s.keyedByPk(model.OrmStore()) {
+		i, err := s.shardOf(pk)
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		return s.track(i, start, s.shards[i].Populate(ctx, model, pk))
+	}
+	for i, shard := range s.shards {
+		start := time.Now()
+		err := shard.Populate(ctx, model, pk)
+		if err == shard.ErrNotFound() {
+			_ = s.track(i, start, nil)
+			continue
+		}
+		return s.track(i, start, err)
+	}
+	return s.ErrNotFound()
+}
+
+func (s *Sharded) Find(ctx context.Context, store skyorm.Store, condition skyorm.Cond, limit, offset int) ([]skyorm.Model, error) {
+	i, err := s.pinned(store, condition)
+	if err != nil {
+		return nil, err
+	}
+	if i >= 0 {
+		start := time.Now()
+		l, err := s.shards[i].Find(ctx, store, condition, limit, offset)
+		return l, s.track(i, start, err)
+	}
+	// every shard has to return limit+offset rows, offset is applied to merged result.
+	shardLimit := 0
+	if limit > 0 {
+		shardLimit = limit + offset
+	}
+	results := make([][]skyorm.Model, len(s.shards))
+	err = s.scatter(func(i int, shard skyorm.Provider) error {
+		l, err := shard.Find(ctx, store, condition, shardLimit, 0)
+		results[i] = l
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	l := make([]skyorm.Model, 0)
+	for _, r := range results {
+		l = append(l, r...)
+	}
+	if offset > 0 {
+		if offset >= len(l) {
+			return l[:0], nil
+		}
+		l = l[offset:]
+	}
+	if limit > 0 && limit < len(l) {
+		l = l[:limit]
+	}
+	return l, nil
+}
+
+func (s *Sharded) Update(ctx context.Context, store skyorm.Store, condition skyorm.Cond, values ...skyorm.Val) error {
+	i, err := s.pinned(store, condition)
+	if err != nil {
+		return err
+	}
+	if i >= 0 {
+		start := time.Now()
+		return s.track(i, start, s.updateIndexed(ctx, i, store, condition, values...))
+	}
+	return s.scatter(func(i int, _ skyorm.Provider) error {
+		return s.updateIndexed(ctx, i, store, condition, values...)
+	})
+}
+
+func (s *Sharded) Delete(ctx context.Context, store skyorm.Store, condition skyorm.Cond) error {
+	i, err := s.pinned(store, condition)
+	if err != nil {
+		return err
+	}
+	if i >= 0 {
+		start := time.Now()
+		return s.track(i, start, s.deleteIndexed(ctx, i, store, condition))
+	}
+	return s.scatter(func(i int, _ skyorm.Provider) error {
+		return s.deleteIndexed(ctx, i, store, condition)
+	})
+}
+
+func (s *Sharded) Count(ctx context.Context, store skyorm.Store, condition skyorm.Cond) (int64, error) {
+	i, err := s.pinned(store, condition)
+	if err != nil {
+		return 0, err
+	}
+	if i >= 0 {
+		start := time.Now()
+		cnt, err := s.shards[i].Count(ctx, store, condition)
+		return cnt, s.track(i, start, err)
+	}
+	counts := make([]int64, len(s.shards))
+	err = s.scatter(func(i int, shard skyorm.Provider) error {
+		cnt, err := shard.Count(ctx, store, condition)
+		counts[i] = cnt
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	var cnt int64
+	for _, c := range counts {
+		cnt += c
+	}
+	return cnt, nil
+}
+
+func (s *Sharded) ErrNotFound() error {
+	return s.shards[0].ErrNotFound()
+}
+
+// Close closes shards which can be closed, e.g. the ones opened by OpenSharded.
+func (s *Sharded) Close() error {
+	var err error
+	for i, shard := range s.shards {
+		if c, ok := shard.(interface{ Close() error }); ok {
+			if cerr := c.Close(); cerr != nil && err == nil {
+				err = fmt.Errorf("shard %d: %w", i, cerr)
+			}
+		}
+	}
+	return err
+}
+
+// Stats returns metrics of every shard.
+func (s *Sharded) Stats() []ShardStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := make([]ShardStats, len(s.stats))
+	copy(l, s.stats)
+	return l
+}
+
+// scatter runs fn on every shard concurrently and returns the first error.
+func (s *Sharded) scatter(fn func(i int, shard skyorm.Provider) error) error {
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, len(s.shards))
+	)
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard skyorm.Provider) {
+			defer wg.Done()
+			start := time.Now()
+			errs[i] = s.track(i, start, fn(i, shard))
+		}(i, shard)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("shard %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// track records the result of shard operation started at start and returns err.
+func (s *Sharded) track(i int, start time.Time, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := &s.stats[i]
+	st.Queries++
+	st.Duration += time.Since(start)
+	st.LastError = err
+	if err != nil {
+		st.Errors++
+		st.LastErrorAt = time.Now()
+		s.cfg.Logger.Printf("SHARD %d ERROR: %v\n", i, err)
+	}
+	return err
+}
+
+// keyedByPk reports whether models of the store are routed by their pks.
+func (s *Sharded) keyedByPk(store skyorm.Store) bool {
+	if _, ok := s.cfg.Stores[store.Name()]; ok {
+		return s.pkKeyed[store.Name()]
+	}
+	return s.defaultKeyed
+}
+
+// keyOf returns shard key of the model.
+func (s *Sharded) keyOf(m skyorm.Model) interface{} {
+	if k, ok := s.cfg.Stores[m.OrmStore().Name()]; ok {
+		return k.Key(m)
+	}
+	return s.cfg.Key(m)
+}
+
+// shardOf returns shard of the key, resolvers returning no shard are an error
+// rather than a panic.
+func (s *Sharded) shardOf(key interface{}) (int, error) {
+	i := s.cfg.Resolver.Shard(key)
+	if i < 0 || i >= len(s.shards) {
+		return -1, fmt.Errorf("resolver returned shard %d of key %v out of %d shards", i, key, len(s.shards))
+	}
+	return i, nil
+}
+
+// pinned returns shard which the condition pins by equality of shard key of
+// the store, -1 when the condition has to be scattered.
+func (s *Sharded) pinned(store skyorm.Store, condition skyorm.Cond) (int, error) {
+	k, ok := s.cfg.Stores[store.Name()]
+	if !ok || k.Prop == nil || condition == nil {
+		return -1, nil
+	}
+	if key, ok := pinnedKey(condition, k.Prop.Name()); ok {
+		return s.shardOf(key)
+	}
+	return -1, nil
+}
+
+// pinnedKey returns value which condition requires prop to be equal to.
+func pinnedKey(c skyorm.Cond, prop string) (interface{}, bool) {
+	switch c.(type) {
+	case *exprCond, *tableCond:
+		return nil, false
+	}
+	switch c.Type() {
+	case skyorm.CondTypeEq:
+		return c.Val(), c.Prop().Name() == prop
+	case skyorm.CondTypeAnd:
+		for _, child := range c.Children() {
+			if key, ok := pinnedKey(child, prop); ok {
+				return key, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// propValue returns function returning value of prop of a model.
+func propValue(prop skyorm.Prop) func(m skyorm.Model) interface{} {
+	return func(m skyorm.Model) interface{} {
+		return propVal(m, prop)
+	}
+}
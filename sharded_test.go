@@ -0,0 +1,211 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestShardedPutUnassignedPk(t *testing.T) {
+	shards := make([]skyorm.Provider, 2)
+	mocks := make([]*postgrestest.Mock, 2)
+	for i := range shards {
+		p, mock, err := postgrestest.NewMock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards[i], mocks[i] = p, mock
+	}
+	s, err := postgres.NewSharded(shards, postgres.ShardedConfig{PkRouted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(context.Background(), &user{Name: "a"})
+	if !errors.Is(err, postgres.ErrShardKeyUnassigned) {
+		t.Fatalf("Put() error = %v, want ErrShardKeyUnassigned", err)
+	}
+	u := &user{ID: 7, Name: "a"}
+	i := postgres.HashResolver(2).Shard(u.ID)
+	mocks[i].ExpectQuery(`^INSERT INTO users \(id, name\) VALUES \(\$1, \$2\) RETURNING id$`).
+		WithArgs(7, "a").WillReturnRows([]string{"id"}, []interface{}{7})
+	if err = s.Put(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range mocks {
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestShardedPopulateByStoreKey(t *testing.T) {
+	shards := make([]skyorm.Provider, 2)
+	mocks := make([]*postgrestest.Mock, 2)
+	for i := range shards {
+		p, mock, err := postgrestest.NewMock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards[i], mocks[i] = p, mock
+	}
+	s, err := postgres.NewSharded(shards, postgres.ShardedConfig{
+		PkRouted: true,
+		Stores:   map[string]postgres.ShardKey{orderStore.Name(): {Prop: orderStore.Props()[1]}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the order isn't on the shard its pk hashes to, as orders are keyed by user.
+	j := 1 - postgres.HashResolver(2).Shard(int64(7))
+	for i := 0; i <= j; i++ {
+		e := mocks[i].ExpectQuery(`^SELECT id, user_id, total FROM orders WHERE id = \$1$`).WithArgs(int64(7))
+		if i == j {
+			e.WillReturnRows([]string{"id", "user_id", "total"}, []interface{}{7, 1, 10})
+		} else {
+			e.WillReturnRows([]string{"id", "user_id", "total"})
+		}
+	}
+	o := &order{}
+	if err := s.Populate(context.Background(), o, int64(7)); err != nil {
+		t.Fatal(err)
+	}
+	if o.Total != 10 {
+		t.Fatalf("populated %+v", o)
+	}
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestShardedRejectsNoShards(t *testing.T) {
+	if _, err := postgres.NewSharded(nil, postgres.ShardedConfig{}); err == nil {
+		t.Fatal("sharded provider without shards")
+	}
+	p, _, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = postgres.NewSharded([]skyorm.Provider{p}, postgres.ShardedConfig{Resolver: postgres.HashResolver(0)}); err == nil {
+		t.Fatal("hash resolver of no shards")
+	}
+	if _, err = postgres.NewSharded([]skyorm.Provider{p}, postgres.ShardedConfig{Resolver: postgres.HashResolver(2)}); err == nil {
+		t.Fatal("hash resolver of more shards")
+	}
+	if _, err = postgres.NewConsistentHash(2, 0); err == nil {
+		t.Fatal("ring without virtual nodes")
+	}
+}
+
+func TestShardedKeyWithoutProp(t *testing.T) {
+	p1, mock1, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, mock2, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := []skyorm.Provider{p1, p2}
+	if _, err = postgres.NewSharded(shards, postgres.ShardedConfig{
+		Stores: map[string]postgres.ShardKey{orderStore.Name(): {}},
+	}); err == nil {
+		t.Fatal("shard key without prop and key")
+	}
+	s, err := postgres.NewSharded(shards, postgres.ShardedConfig{
+		Stores: map[string]postgres.ShardKey{orderStore.Name(): {Key: func(m skyorm.Model) interface{} {
+			return m.(*order).UserID
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// conditions can't pin a shard without the prop, so finds are scattered.
+	for _, mock := range []*postgrestest.Mock{mock1, mock2} {
+		mock.ExpectQuery(`^SELECT id, user_id, total FROM orders WHERE user_id = \$1$`).WithArgs(int64(1)).
+			WillReturnRows([]string{"id", "user_id", "total"})
+	}
+	if _, err = s.Find(context.Background(), orderStore, skyorm.Eq(orderStore.Props()[1], int64(1)), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, mock := range []*postgrestest.Mock{mock1, mock2} {
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// shardResolver routes every key to the same shard.
+type shardResolver int
+
+func (r shardResolver) Shard(interface{}) int {
+	return int(r)
+}
+
+func TestShardedResolverOutOfRange(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := postgres.NewSharded([]skyorm.Provider{p}, postgres.ShardedConfig{
+		Resolver: shardResolver(1),
+		Stores:   map[string]postgres.ShardKey{orderStore.Name(): {Prop: orderStore.Props()[1]}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err = s.Put(ctx, &order{ID: 1, UserID: 1}); err == nil {
+		t.Fatal("put to shard out of range")
+	}
+	if _, err = s.Find(ctx, orderStore, skyorm.Eq(orderStore.Props()[1], int64(1)), 0, 0); err == nil {
+		t.Fatal("find on shard out of range")
+	}
+	if _, err = s.Count(ctx, orderStore, skyorm.Eq(orderStore.Props()[1], int64(1))); err == nil {
+		t.Fatal("count on shard out of range")
+	}
+	if err = s.Delete(ctx, orderStore, skyorm.Eq(orderStore.Props()[1], int64(1))); err == nil {
+		t.Fatal("delete on shard out of range")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShardedFindOffsetWithoutLimit(t *testing.T) {
+	p1, mock1, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, mock2, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := postgres.NewSharded([]skyorm.Provider{p1, p2}, postgres.ShardedConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// offset is applied to the merged result, so shards return every row.
+	mock1.ExpectQuery(`^SELECT id, name FROM users$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{int64(1), "a"}, []interface{}{int64(3), "c"})
+	mock2.ExpectQuery(`^SELECT id, name FROM users$`).
+		WillReturnRows([]string{"id", "name"}, []interface{}{int64(2), "b"})
+	l, err := s.Find(context.Background(), userStore, nil, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 1 {
+		t.Fatalf("found %d users, want 1", len(l))
+	}
+	for _, mock := range []*postgrestest.Mock{mock1, mock2} {
+		if err = mock.ExpectationsWereMet(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
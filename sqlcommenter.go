@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+)
+
+// SQLCommenter configures sqlcommenter-style annotations of queries.
+type SQLCommenter struct {
+	// Application is added as application key.
+	Application string
+	// Traceparent returns W3C traceparent of ctx, e.g. of OpenTelemetry span,
+	// which is added as traceparent key when it's not empty.
+	Traceparent func(ctx context.Context) string
+}
+
+// WithSQLCommenter makes provider append application and traceparent of the
+// context to queries as SQL comment, together with operation meta such as
+// route or tag set by WithOpMeta:
+//
+//	SELECT ... /*application='api',route='%2Fusers',traceparent='00-...-01'*/
+//
+// so DBAs can correlate pg_stat_activity entries with application traces.
+func WithSQLCommenter(c SQLCommenter) Option {
+	return func(o *options) {
+		o.commenter = &c
+	}
+}
+
+// annotate appends operation meta of the context, and sqlcommenter keys when
+// configured, to query as SQL comment.
+func (p *provider) annotate(ctx context.Context, query string) string {
+	if p.commenter == nil {
+		return annotate(ctx, query)
+	}
+	m := make(map[string]string, len(OpMeta(ctx))+2)
+	for k, v := range OpMeta(ctx) {
+		m[k] = v
+	}
+	if p.commenter.Application != "" {
+		m["application"] = p.commenter.Application
+	}
+	if p.commenter.Traceparent != nil {
+		if tp := p.commenter.Traceparent(ctx); tp != "" {
+			m["traceparent"] = tp
+		}
+	}
+	if len(m) == 0 {
+		return query
+	}
+	return query + " /*" + formatMeta(m) + "*/"
+}
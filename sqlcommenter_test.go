@@ -0,0 +1,41 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestSQLCommenter(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithSQLCommenter(postgres.SQLCommenter{
+		Application: "api",
+		Traceparent: func(ctx context.Context) string {
+			return "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the comment follows the whole statement, so placeholders and args are kept.
+	ctx := postgres.WithOpMeta(context.Background(), map[string]string{"route": "/users/{id}"})
+	mock.ExpectQuery(`^SELECT id, name FROM users WHERE name = \$1 LIMIT 5 OFFSET 0 ` +
+		`/\*application='api',route='%2Fusers%2F%7Bid%7D',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'\*/$`).
+		WithArgs("a").WillReturnRows([]string{"id", "name"})
+	if _, err = p.Find(ctx, userStore, skyorm.Eq(userStore.Props()[1], "a"), 5, 0); err != nil {
+		t.Fatal(err)
+	}
+	// values can't close the comment or quote.
+	ctx = postgres.WithOpMeta(context.Background(), map[string]string{"tag": "x'*/; DROP TABLE users; --"})
+	mock.ExpectQuery(`^SELECT id, name FROM users /\*application='api',tag='x%27%2A%2F%3B\+DROP\+TABLE\+users%3B\+--',traceparent='[^']+'\*/$`).
+		WillReturnRows([]string{"id", "name"})
+	if _, err = p.Find(ctx, userStore, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/skyorm/skyorm"
+)
+
+// subqueryAlias is the alias of table of correlated subqueries, so props of
+// their conditions refer to it even when it's the table of outer query.
+const subqueryAlias = "orm_sub"
+
+// tableCond is a condition reading tables of other stores. It addresses them
+// by store names until bindTables resolves them by tenant and table resolver
+// of the query context.
+type tableCond struct {
+	*exprCond
+	// stores are names of the stores read by the condition.
+	stores []string
+	bind   func(table func(name string) string) skyorm.Cond
+}
+
+// bindTables returns condition c with tables of other stores addressed by
+// table, and whether c has such tables.
+func bindTables(c skyorm.Cond, table func(name string) string) (skyorm.Cond, bool) {
+	switch t := c.(type) {
+	case nil:
+		return c, false
+	case *tableCond:
+		return t.bind(table), true
+	case *exprCond:
+		return c, false
+	}
+	if c.Type() != skyorm.CondTypeAnd && c.Type() != skyorm.CondTypeOr {
+		return c, false
+	}
+	children := make([]skyorm.Cond, len(c.Children()))
+	bound := false
+	for i, child := range c.Children() {
+		var ok bool
+		children[i], ok = bindTables(child, table)
+		bound = bound || ok
+	}
+	if !bound {
+		return c, false
+	}
+	if c.Type() == skyorm.CondTypeOr {
+		return skyorm.Or(children...), true
+	}
+	return skyorm.And(children...), true
+}
+
+// condStores returns names of other stores read by condition c.
+func condStores(c skyorm.Cond) []string {
+	switch t := c.(type) {
+	case nil:
+		return nil
+	case *tableCond:
+		return t.stores
+	case *exprCond:
+		return nil
+	}
+	var l []string
+	if c.Type() == skyorm.CondTypeAnd || c.Type() == skyorm.CondTypeOr {
+		for _, child := range c.Children() {
+			l = append(l, condStores(child)...)
+		}
+	}
+	return l
+}
+
+// bindCond returns condition with tables of other stores resolved by ctx, like
+// the table of the store queried.
+func (p *provider) bindCond(ctx context.Context, c skyorm.Cond) skyorm.Cond {
+	c, _ = bindTables(c, func(name string) string {
+		return p.table(ctx, name)
+	})
+	return c
+}
+
+// Exists returns condition matching models of store of the relation having at
+// least one related model of its target matching cond, e.g. users having at
+// least one order, as correlated EXISTS subquery. The subquery refers to the
+// outer table by the table name of the store, which finds alias resolved tables
+// with, see WithTableResolver. The target table is resolved like the outer one.
+func Exists(r Relation, cond skyorm.Cond) skyorm.Cond {
+	return exists(r, cond, quoteTable)
+}
+
+func exists(r Relation, cond skyorm.Cond, table func(name string) string) skyorm.Cond {
+	return &tableCond{&exprCond{skyorm.Eq(r.Store.Pk(), nil), func(n *int) (string, []interface{}) {
+		// the alias of tableAs.
+		_, outer := splitTable(r.Store.Name())
+		var correlation string
+		if r.Kind == HasMany {
+			correlation = subqueryAlias + "." + quoteColumn(r.Prop.Name()) + " = " + quoteIdent(outer) + "." + quoteColumn(r.Store.Pk().Name())
+		} else {
+			correlation = subqueryAlias + "." + quoteColumn(r.Target.Pk().Name()) + " = " + quoteIdent(outer) + "." + quoteColumn(r.Prop.Name())
+		}
+		query := "EXISTS (SELECT 1 FROM " + table(r.Target.Name()) + " AS " + subqueryAlias + " WHERE " + correlation
+		s, v := parseCond(cond, n)
+		if s != "" {
+			query += " AND (" + s + ")"
+		}
+		return query + ")", v
+	}}, append([]string{r.Target.Name()}, condStores(cond)...), func(table func(name string) string) skyorm.Cond {
+		cond, _ := bindTables(cond, table)
+		return exists(r, cond, table)
+	}}
+}
+
+// InSubquery returns condition matching property values in values of column
+// prop of models of the store matching cond, as IN (SELECT ...) subquery.
+func InSubquery(prop skyorm.Prop, store skyorm.Store, column skyorm.Prop, cond skyorm.Cond) skyorm.Cond {
+	return inSubquery(prop, store, column, cond, quoteTable)
+}
+
+func inSubquery(prop skyorm.Prop, store skyorm.Store, column skyorm.Prop, cond skyorm.Cond, table func(name string) string) skyorm.Cond {
+	return &tableCond{&exprCond{skyorm.Eq(prop, nil), func(n *int) (string, []interface{}) {
+		query, v := buildWhere(cond, "SELECT %s FROM %s", n, quoteColumn(column.Name()), table(store.Name()))
+		return quoteColumn(prop.Name()) + " IN (" + query + ")", v
+	}}, append([]string{store.Name()}, condStores(cond)...), func(table func(name string) string) skyorm.Cond {
+		cond, _ := bindTables(cond, table)
+		return inSubquery(prop, store, column, cond, table)
+	}}
+}
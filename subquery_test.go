@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestFindExists(t *testing.T) {
+	orders := postgres.Relation{Name: "orders", Kind: postgres.HasMany, Store: userStore, Target: orderStore, Prop: orderStore.Props()[1]}
+	large := skyorm.Gt(orderStore.Props()[2], 100)
+	tests := []struct {
+		name  string
+		cond  skyorm.Cond
+		query string
+	}{
+		{
+			name: "and",
+			cond: skyorm.And(postgres.Exists(orders, nil)),
+			query: `^SELECT id, name FROM users_2024 AS users WHERE ` +
+				`\(EXISTS \(SELECT 1 FROM orders AS orm_sub WHERE orm_sub\.user_id = users\.id\)\)$`,
+		},
+		{
+			name: "or",
+			cond: skyorm.Or(postgres.Exists(orders, nil), postgres.Exists(orders, large)),
+			query: `^SELECT id, name FROM users_2024 AS users WHERE ` +
+				`\(EXISTS \(SELECT 1 FROM orders AS orm_sub WHERE orm_sub\.user_id = users\.id\) OR ` +
+				`EXISTS \(SELECT 1 FROM orders AS orm_sub WHERE orm_sub\.user_id = users\.id AND \(total > \$1\)\)\)$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, mock, err := postgrestest.NewMock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectQuery(tt.query).WillReturnRows([]string{"id", "name"}, []interface{}{1, "a"})
+			ctx := postgres.WithTableResolver(context.Background(), postgres.TableSuffix("_2024", "users"))
+			l, err := p.Find(ctx, userStore, tt.cond, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(l) != 1 {
+				t.Fatalf("found %d users, want 1", len(l))
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestInSubquery(t *testing.T) {
+	order := invoiceStore.Pk()
+	expectFind(t, postgres.InSubquery(userStore.Pk(), invoiceStore, order, skyorm.Gt(order, 1)),
+		`^SELECT id, name FROM users WHERE id IN \(SELECT "order" FROM invoices WHERE "order" > \$1\)$`, 1)
+}
+
+func TestSubqueryTablesOfContext(t *testing.T) {
+	orders := postgres.Relation{Name: "orders", Kind: postgres.HasMany, Store: userStore, Target: orderStore, Prop: orderStore.Props()[1]}
+	userID := orderStore.Props()[1]
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		cond  skyorm.Cond
+		query string
+	}{
+		{
+			name: "tenant",
+			ctx:  postgres.WithTenant(context.Background(), "acme"),
+			cond: skyorm.And(postgres.Exists(orders, nil)),
+			query: `^SELECT id, name FROM acme\.users WHERE ` +
+				`\(EXISTS \(SELECT 1 FROM acme\.orders AS orm_sub WHERE orm_sub\.user_id = users\.id\)\)$`,
+		},
+		{
+			name: "resolver",
+			ctx:  postgres.WithTableResolver(context.Background(), postgres.TableSuffix("_2024")),
+			cond: postgres.Not(postgres.InSubquery(userStore.Pk(), orderStore, userID, nil)),
+			query: `^SELECT id, name FROM users_2024 AS users WHERE ` +
+				`NOT \(id IN \(SELECT user_id FROM orders_2024\)\)$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, mock, err := postgrestest.NewMock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			mock.ExpectQuery(tt.query).WillReturnRows([]string{"id", "name"})
+			if _, err = p.Find(tt.ctx, userStore, tt.cond, 0, 0); err != nil {
+				t.Fatal(err)
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
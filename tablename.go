@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+)
+
+type tableResolverKey struct{}
+
+// WithTableResolver returns context which operations address physical tables
+// returned by resolve for store names, e.g. events_2024_06 for events, so stores
+// can be partitioned by time or tenant manually. resolve receives names of
+// stores and tables of helpers such as associations and returns them unchanged
+// when they are not partitioned. Resolved names are qualified with tenant schema.
+func WithTableResolver(ctx context.Context, resolve func(name string) string) context.Context {
+	return context.WithValue(ctx, tableResolverKey{}, resolve)
+}
+
+// TableSuffix returns table resolver appending suffix to names of the stores,
+// or to every name when no stores are given.
+func TableSuffix(suffix string, stores ...string) func(name string) string {
+	partitioned := make(map[string]bool, len(stores))
+	for _, s := range stores {
+		partitioned[s] = true
+	}
+	return func(name string) string {
+		if len(stores) == 0 || partitioned[name] {
+			return name + suffix
+		}
+		return name
+	}
+}
+
+// resolveTable returns physical table name of ctx for the name.
+func resolveTable(ctx context.Context, name string) string {
+	if resolve, _ := ctx.Value(tableResolverKey{}).(func(string) string); resolve != nil {
+		return resolve(name)
+	}
+	return name
+}
+
+// tableAs returns table of ctx for the name aliased with the name when it's
+// resolved to another table, so qualified props keep referencing it.
+func (p *provider) tableAs(ctx context.Context, name string) string {
+	table := p.table(ctx, name)
+	if resolveTable(ctx, name) != name {
+		_, alias := splitTable(name)
+		return table + " AS " + quoteIdent(alias)
+	}
+	return table
+}
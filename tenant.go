@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns context which operations address tables of the tenant
+// schema. Tables of operations are qualified with the schema, so it's safe with
+// pooled connections, and transactions begun with the context also set
+// search_path locally to the schema followed by public, so raw statements and
+// helpers resolve tenant tables too, along with shared tables and extensions.
+// Writes buffered by WithAsyncStores are flushed without context, so they
+// ignore the tenant.
+func WithTenant(ctx context.Context, schema string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, schema)
+}
+
+// Tenant returns tenant schema of the context, empty when it's not set.
+func Tenant(ctx context.Context) string {
+	schema, _ := ctx.Value(tenantKey{}).(string)
+	return schema
+}
+
+// table returns quoted table name resolved by ctx and qualified with tenant
+// schema of ctx. Names already qualified with a schema, e.g. "billing.invoices",
+// keep their schema.
+func (p *provider) table(ctx context.Context, name string) string {
+	schema, table := splitTable(resolveTable(ctx, name))
+	if schema == "" {
+		schema = Tenant(ctx)
+	}
+	if schema != "" {
+		return quoteIdent(schema) + "." + quoteIdent(table)
+	}
+	return quoteIdent(table)
+}
+
+// quoteTable returns table name with schema and table parts quoted separately.
+func quoteTable(name string) string {
+	schema, table := splitTable(name)
+	if schema != "" {
+		return quoteIdent(schema) + "." + quoteIdent(table)
+	}
+	return quoteIdent(table)
+}
+
+// splitTable splits "schema.table" name into its unquoted parts, dots inside
+// quoted parts don't split.
+func splitTable(name string) (schema, table string) {
+	quoted := false
+	for i, r := range name {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == '.' && !quoted:
+			return unquoteIdent(name[:i]), unquoteIdent(name[i+1:])
+		}
+	}
+	return "", unquoteIdent(name)
+}
+
+func unquoteIdent(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		return strings.Replace(s[1:len(s)-1], `""`, `"`, -1)
+	}
+	return s
+}
+
+// quoteIdent returns identifier as PostgreSQL resolves it unquoted: plain
+// identifiers are kept as they are and fold to lower case, reserved words are
+// quoted in lower case and the rest, e.g. with spaces, are quoted verbatim.
+func quoteIdent(s string) string {
+	if s == "" {
+		return s
+	}
+	if !isPlainIdent(s) {
+		return pq.QuoteIdentifier(s)
+	}
+	if lower := strings.ToLower(s); reservedWords[lower] {
+		return pq.QuoteIdentifier(lower)
+	}
+	return s
+}
+
+// quoteColumn returns column of prop name quoted like quoteIdent. Names which
+// aren't plain identifiers are qualified columns or expressions of computed
+// props, so they are kept as they are.
+func quoteColumn(name string) string {
+	if !isPlainIdent(name) {
+		return name
+	}
+	return quoteIdent(name)
+}
+
+// foldIdent returns name of identifier s in catalogs, as created by quoteIdent:
+// plain identifiers are folded to lower case, others are kept as they are.
+func foldIdent(s string) string {
+	if isPlainIdent(s) {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+func isPlainIdent(s string) bool {
+	for i, r := range s {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || i > 0 && (r >= '0' && r <= '9' || r == '$') {
+			continue
+		}
+		return false
+	}
+	return s != ""
+}
+
+// reservedWords are keywords of PostgreSQL which can't be used as unquoted
+// table or column names.
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true, "as": true,
+	"asc": true, "asymmetric": true, "authorization": true, "binary": true, "both": true, "case": true,
+	"cast": true, "check": true, "collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true, "for": true, "foreign": true,
+	"freeze": true, "from": true, "full": true, "grant": true, "group": true, "having": true, "ilike": true,
+	"in": true, "initially": true, "inner": true, "intersect": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "left": true, "like": true,
+	"limit": true, "localtime": true, "localtimestamp": true, "natural": true, "not": true,
+	"notnull": true, "null": true, "offset": true, "on": true, "only": true, "or": true, "order": true,
+	"outer": true, "overlaps": true, "placing": true, "primary": true, "references": true,
+	"returning": true, "right": true, "select": true, "session_user": true, "similar": true,
+	"some": true, "symmetric": true, "system_user": true, "table": true, "tablesample": true,
+	"then": true, "to": true, "trailing": true, "true": true, "union": true, "unique": true,
+	"user": true, "using": true, "variadic": true, "verbose": true, "when": true, "where": true,
+	"window": true, "with": true,
+}
+
+// copyIn returns COPY statement of the table, which may be schema-qualified,
+// with the table and the columns quoted like quoteIdent.
+func copyIn(table string, columns ...string) string {
+	l := make([]string, len(columns))
+	for i, column := range columns {
+		l[i] = quoteColumn(column)
+	}
+	return "COPY " + quoteTable(table) + " (" + strings.Join(l, ", ") + ") FROM STDIN"
+}
+
+// setTenantPath sets search_path of transaction to tenant schema of ctx,
+// followed by public schema, so extensions and tables shared by tenants,
+// which are installed there, still resolve.
+func (t *tx) setTenantPath(ctx context.Context) error {
+	schema := Tenant(ctx)
+	if schema == "" {
+		return nil
+	}
+	_, err := t.exec(ctx, "SELECT set_config('search_path', $1, true)", quoteIdent(schema)+", public")
+	return err
+}
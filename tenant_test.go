@@ -0,0 +1,73 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type grant struct {
+	ID   int64
+	User string
+}
+
+var grantStore = skyorm.NewStore("Billing.Order", 0, func() skyorm.Model {
+	return &grant{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("User", "string", false),
+)
+
+func (m *grant) OrmStore() skyorm.Store     { return grantStore }
+func (m *grant) OrmPk() interface{}         { return m.ID }
+func (m *grant) OrmPkProp() skyorm.Prop     { return grantStore.Pk() }
+func (m *grant) OrmPkPointer() interface{}  { return &m.ID }
+func (m *grant) OrmProps() []skyorm.Prop    { return grantStore.Props() }
+func (m *grant) OrmPointers() []interface{} { return []interface{}{&m.ID, &m.User} }
+func (m *grant) OrmVals() []interface{}     { return []interface{}{m.ID, m.User} }
+
+func TestQuoteReservedNames(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, "user" FROM Billing\."order" WHERE "user" = \$1$`).WithArgs("a").
+		WillReturnRows([]string{"id", "user"}, []interface{}{1, "a"})
+	if _, err = p.Find(context.Background(), grantStore, skyorm.Eq(grantStore.Props()[1], "a"), 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+	ddl, err := postgres.GenerateDDL(grantStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "CREATE TABLE IF NOT EXISTS Billing.\"order\" (\n\tid BIGSERIAL NOT NULL,\n\t\"user\" TEXT NOT NULL,\n\tPRIMARY KEY (id)\n);\n"
+	if ddl != want {
+		t.Fatalf("ddl %q, want %q", ddl, want)
+	}
+}
+
+func TestTenantPathKeepsPublic(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the schema is unquoted like tables of the tenant, so both fold to acme.
+	mock.ExpectExec(`^SELECT set_config\('search_path', \$1, true\)$`).WithArgs(`Acme, public`).WillReturnResult(1)
+	tx, err := p.Begin(postgres.WithTenant(context.Background(), "Acme"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type statementTimeoutKey struct{}
+
+// WithStatementTimeout returns context which statements are cancelled by the
+// server when they run longer than d, overriding WithDefaultStatementTimeout.
+// Operations outside of a transaction are wrapped in one setting statement_timeout
+// locally, transactions begun with the context apply it to every statement.
+// Unlike context deadline, which cancels the query from the client, the timeout
+// is enforced even when the client is gone. The timeout is rounded up to whole
+// milliseconds, ctx is returned as is when d isn't positive.
+func WithStatementTimeout(ctx context.Context, d time.Duration) context.Context {
+	if d <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, statementTimeoutKey{}, d)
+}
+
+// timeoutMillis returns statement_timeout of positive d, rounded up to whole
+// milliseconds, as zero disables the timeout.
+func timeoutMillis(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Millisecond-1)/time.Millisecond), 10)
+}
+
+// IsTimeout reports whether err is a statement cancelled by statement timeout
+// or by context deadline.
+func IsTimeout(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "57014"
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,40 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestStatementTimeout(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for d, want := range map[time.Duration]string{
+		500 * time.Microsecond:  "1",
+		time.Millisecond:        "1",
+		1500 * time.Microsecond: "2",
+		time.Second:             "1000",
+	} {
+		mock.ExpectExec(`^SELECT set_config\(\$1, \$2, true\)$`).WithArgs("statement_timeout", want).WillReturnResult(1)
+		mock.ExpectQuery(`^SELECT id, name FROM users$`).WillReturnRows([]string{"id", "name"})
+		if _, err = p.Find(postgres.WithStatementTimeout(ctx, d), userStore, nil, 0, 0); err != nil {
+			t.Fatalf("timeout %s: %v", d, err)
+		}
+	}
+	// non-positive timeouts are ignored rather than disabling the timeout or failing statements.
+	for _, d := range []time.Duration{0, -time.Second} {
+		mock.ExpectQuery(`^SELECT id, name FROM users$`).WillReturnRows([]string{"id", "name"})
+		if _, err = p.Find(postgres.WithStatementTimeout(ctx, d), userStore, nil, 0, 0); err != nil {
+			t.Fatalf("timeout %s: %v", d, err)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// Hypertable is a store converted to TimescaleDB hypertable, chunked by a time
+// prop. Timescale extension has to be installed, see Capabilities.
+type Hypertable struct {
+	p        *provider
+	store    skyorm.Store
+	timeProp skyorm.Prop
+}
+
+// Hypertable returns hypertable of the store partitioned by timeProp.
+func (p *provider) Hypertable(store skyorm.Store, timeProp skyorm.Prop) *Hypertable {
+	return &Hypertable{p, store, timeProp}
+}
+
+// Create converts table of the store to hypertable with chunks spanning
+// chunkInterval, zero keeps Timescale default. Existing rows are migrated to
+// chunks, it's a no-op for hypertables.
+func (h *Hypertable) Create(ctx context.Context, chunkInterval time.Duration) error {
+	query := "SELECT create_hypertable($1, $2, if_not_exists => TRUE, migrate_data => TRUE"
+	args := []interface{}{h.p.table(ctx, h.store.Name()), h.timeProp.Name()}
+	if chunkInterval > 0 {
+		query += ", chunk_time_interval => $3 * interval '1 microsecond'"
+		args = append(args, chunkInterval.Microseconds())
+	}
+	_, err := h.p.exec(ctx, query+")", args...)
+	return err
+}
+
+// SetRetention adds policy dropping chunks older than dropAfter, replacing the
+// existing one.
+func (h *Hypertable) SetRetention(ctx context.Context, dropAfter time.Duration) error {
+	if err := h.RemoveRetention(ctx); err != nil {
+		return err
+	}
+	_, err := h.p.exec(ctx, "SELECT add_retention_policy($1, $2 * interval '1 microsecond')",
+		h.p.table(ctx, h.store.Name()), dropAfter.Microseconds())
+	return err
+}
+
+// RemoveRetention removes retention policy if there is one.
+func (h *Hypertable) RemoveRetention(ctx context.Context) error {
+	_, err := h.p.exec(ctx, "SELECT remove_retention_policy($1, if_exists => TRUE)", h.p.table(ctx, h.store.Name()))
+	return err
+}
+
+// SetCompression enables compression of chunks segmented by segmentBy props and
+// adds policy compressing chunks older than compressAfter, replacing the existing one.
+func (h *Hypertable) SetCompression(ctx context.Context, compressAfter time.Duration, segmentBy ...skyorm.Prop) error {
+	settings := "timescaledb.compress"
+	if len(segmentBy) > 0 {
+		names := make([]string, len(segmentBy))
+		for i, prop := range segmentBy {
+			names[i] = prop.Name()
+		}
+		settings += ", timescaledb.compress_segmentby = " + pq.QuoteLiteral(strings.Join(names, ", "))
+	}
+	table := h.p.table(ctx, h.store.Name())
+	if _, err := h.p.exec(ctx, fmt.Sprintf("ALTER TABLE %s SET (%s)", table, settings)); err != nil {
+		return err
+	}
+	if err := h.RemoveCompression(ctx); err != nil {
+		return err
+	}
+	_, err := h.p.exec(ctx, "SELECT add_compression_policy($1, $2 * interval '1 microsecond')", table, compressAfter.Microseconds())
+	return err
+}
+
+// RemoveCompression removes compression policy if there is one, already
+// compressed chunks stay compressed.
+func (h *Hypertable) RemoveCompression(ctx context.Context) error {
+	_, err := h.p.exec(ctx, "SELECT remove_compression_policy($1, if_exists => TRUE)", h.p.table(ctx, h.store.Name()))
+	return err
+}
+
+// TimeBucket returns prop of start of the width bucket timeProp falls into,
+// e.g. to order or group by it.
+func TimeBucket(width time.Duration, timeProp skyorm.Prop) skyorm.Prop {
+	return &exprProp{timeProp, "time_bucket(" + formatInterval(width) + ", " + quoteColumn(timeProp.Name()) + ")"}
+}
+
+// Bucket is a time bucket of aggregated models.
+type Bucket struct {
+	// Time is the start of bucket.
+	Time time.Time
+	// Values are values of aggregates in their order, NULL aggregates of
+	// empty sets are zero.
+	Values []float64
+}
+
+// Buckets aggregates models matching condition into time buckets of width,
+// ordered by time. Column of aggregates is ignored.
+func (h *Hypertable) Buckets(ctx context.Context, width time.Duration, condition skyorm.Cond, aggregates ...Aggregate) ([]Bucket, error) {
+	selects := make([]string, len(aggregates))
+	for i, a := range aggregates {
+		selects[i] = ", " + aggregateExpr(a)
+	}
+	bucket := TimeBucket(width, h.timeProp).Name()
+	query, args := buildWhere(h.p.bindCond(ctx, condition), fmt.Sprintf("SELECT %s%s FROM %s",
+		bucket, strings.Replace(strings.Join(selects, ""), "%", "%%", -1), h.p.table(ctx, h.store.Name())), nil)
+	query += " GROUP BY 1 ORDER BY 1"
+	res, err := h.p.query(withOp(ctx, OpTimeBuckets), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]Bucket, 0)
+	for res.Next() {
+		b := Bucket{Values: make([]float64, len(aggregates))}
+		values := make([]sql.NullFloat64, len(aggregates))
+		dest := []interface{}{&b.Time}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err = res.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			b.Values[i] = v.Float64
+		}
+		l = append(l, b)
+	}
+	return l, res.Err()
+}
+
+// formatInterval returns interval literal of d.
+func formatInterval(d time.Duration) string {
+	return "interval '" + strconv.FormatInt(d.Microseconds(), 10) + " microseconds'"
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+func TestHypertablePolicies(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	h := p.Hypertable(noteStore, noteStore.Props()[2])
+	mock.ExpectExec(`^SELECT create_hypertable\(\$1, \$2, if_not_exists => TRUE, migrate_data => TRUE\)$`).
+		WithArgs("notes", "updated").WillReturnResult(0)
+	mock.ExpectExec(`^SELECT create_hypertable\(\$1, \$2, if_not_exists => TRUE, migrate_data => TRUE, `+
+		`chunk_time_interval => \$3 \* interval '1 microsecond'\)$`).
+		WithArgs("notes", "updated", int64(86400000000)).WillReturnResult(0)
+	// retention and compression policies are replaced.
+	mock.ExpectExec(`^SELECT remove_retention_policy\(\$1, if_exists => TRUE\)$`).WithArgs("notes").WillReturnResult(0)
+	mock.ExpectExec(`^SELECT add_retention_policy\(\$1, \$2 \* interval '1 microsecond'\)$`).
+		WithArgs("notes", int64(3600000000)).WillReturnResult(0)
+	mock.ExpectExec(`^ALTER TABLE notes SET \(timescaledb\.compress, timescaledb\.compress_segmentby = 'id, text'\)$`).WillReturnResult(0)
+	mock.ExpectExec(`^SELECT remove_compression_policy\(\$1, if_exists => TRUE\)$`).WithArgs("notes").WillReturnResult(0)
+	mock.ExpectExec(`^SELECT add_compression_policy\(\$1, \$2 \* interval '1 microsecond'\)$`).
+		WithArgs("notes", int64(60000000)).WillReturnResult(0)
+	if err = h.Create(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Create(ctx, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.SetRetention(ctx, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err = h.SetCompression(ctx, time.Minute, noteStore.Props()[:2]...); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHypertableBuckets(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := postgres.WithTenant(context.Background(), "acme")
+	h := p.Hypertable(noteStore, noteStore.Props()[2])
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`^SELECT time_bucket\(interval '3600000000 microseconds', updated\), COUNT\(\*\), MAX\(id\) `+
+		`FROM acme\.notes WHERE text = \$1 GROUP BY 1 ORDER BY 1$`).WithArgs("a").
+		WillReturnRows([]string{"time_bucket", "count", "max"},
+			[]interface{}{start, int64(2), int64(5)},
+			// NULL aggregates of empty sets are zero.
+			[]interface{}{start.Add(time.Hour), int64(0), nil})
+	l, err := h.Buckets(ctx, time.Hour, skyorm.Eq(noteStore.Props()[1], "a"),
+		postgres.Aggregate{Func: "COUNT"}, postgres.Aggregate{Func: "MAX", Prop: noteStore.Pk()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []postgres.Bucket{{Time: start, Values: []float64{2, 5}}, {Time: start.Add(time.Hour), Values: []float64{0, 0}}}
+	if !reflect.DeepEqual(l, want) {
+		t.Errorf("buckets %v, want %v", l, want)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WithTimeZone makes provider scan timestamps into time.Time, *time.Time and
+// sql.NullTime props in loc, rather than in fixed zones of offsets returned
+// by the server, so scanned times compare and format consistently.
+func WithTimeZone(loc *time.Location) Option {
+	return func(o *options) {
+		o.location = loc
+	}
+}
+
+// zonedTime scans timestamp into pointer to time.Time, pointer to pointer to it
+// or sql.NullTime, in location.
+type zonedTime struct {
+	p   interface{}
+	loc *time.Location
+}
+
+func (z zonedTime) Scan(src interface{}) error {
+	if nt, ok := z.p.(*sql.NullTime); ok {
+		if err := nt.Scan(src); err != nil {
+			return err
+		}
+		if nt.Valid {
+			nt.Time = nt.Time.In(z.loc)
+		}
+		return nil
+	}
+	if pp, ok := z.p.(**time.Time); ok && src == nil {
+		*pp = nil
+		return nil
+	}
+	t, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("can't scan %T into time.Time", src)
+	}
+	t = t.In(z.loc)
+	switch p := z.p.(type) {
+	case *time.Time:
+		*p = t
+	case **time.Time:
+		*p = &t
+	}
+	return nil
+}
+
+// zonePointers wraps pointers to times of l with zonedTime.
+func zonePointers(l []interface{}, loc *time.Location) []interface{} {
+	for i, p := range l {
+		switch p.(type) {
+		case *time.Time, **time.Time, *sql.NullTime:
+			l[i] = zonedTime{p, loc}
+		}
+	}
+	return l
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// Attr is a span attribute.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a tracing span of a query.
+type Span interface {
+	SetAttributes(attrs ...Attr)
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans of queries. It's implemented by a thin adapter of
+// OpenTelemetry tracer, so the package doesn't depend on OpenTelemetry:
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...postgres.Attr) (context.Context, postgres.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		s := otelSpan{span}
+//		s.SetAttributes(attrs...)
+//		return ctx, s
+//	}
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attr) (context.Context, Span)
+}
+
+// Span attribute keys following OpenTelemetry database semantic conventions.
+const (
+	AttrDBSystem       = "db.system"
+	AttrDBStatement    = "db.statement"
+	AttrDBOperation    = "db.operation"
+	AttrDBRowsAffected = "db.rows_affected"
+)
+
+// WithTracer makes provider wrap every query in a span started by t from ctx of
+// the caller. Spans carry db.system, db.operation, db.statement with string
+// literals removed, the number of affected or returned rows when it is known
+// and operation meta of ctx as skyorm.meta.* attributes.
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+var sqlStringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+
+// sanitizeStatement replaces string literals of query with '?', values of
+// queries are bound as parameters, but JSON paths and similar are inlined.
+func sanitizeStatement(query string) string {
+	return sqlStringLiteral.ReplaceAllString(query, "'?'")
+}
+
+// sqlOperation returns the command of query, e.g. SELECT.
+func sqlOperation(query string) string {
+	query = strings.TrimLeft(query, " \t\n(")
+	if i := strings.IndexAny(query, " \t\n("); i >= 0 {
+		query = query[:i]
+	}
+	return strings.ToUpper(query)
+}
+
+func (p *provider) startSpan(ctx context.Context, query string) (context.Context, Span) {
+	if p.tracer == nil {
+		return ctx, nil
+	}
+	op := sqlOperation(query)
+	attrs := []Attr{
+		{AttrDBSystem, "postgresql"},
+		{AttrDBOperation, op},
+		{AttrDBStatement, sanitizeStatement(query)},
+	}
+	for k, v := range OpMeta(ctx) {
+		attrs = append(attrs, Attr{"skyorm.meta." + k, v})
+	}
+	return p.tracer.Start(ctx, op, attrs...)
+}
+
+// endSpan ends span of the query, rows is negative when it's unknown.
+func endSpan(span Span, rows int64, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+	}
+	if rows >= 0 {
+		span.SetAttributes(Attr{AttrDBRowsAffected, rows})
+	}
+	span.End()
+}
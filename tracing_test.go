@@ -0,0 +1,110 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+type testSpan struct {
+	name  string
+	attrs map[string]interface{}
+	errs  []error
+	ended bool
+}
+
+func (s *testSpan) SetAttributes(attrs ...postgres.Attr) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.errs = append(s.errs, err)
+}
+
+func (s *testSpan) End() {
+	s.ended = true
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs ...postgres.Attr) (context.Context, postgres.Span) {
+	s := &testSpan{name: name, attrs: make(map[string]interface{})}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	p, mock, err := postgrestest.NewMock(postgres.WithTracer(tracer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := postgres.WithOpMeta(context.Background(), map[string]string{"route": "/users"})
+	name := userStore.Props()[1]
+	mock.ExpectQuery(`^SELECT COUNT`).WillReturnRows([]string{"cnt"}, []interface{}{int64(4)})
+	if _, err = p.Count(ctx, userStore, skyorm.Eq(name, "a")); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^UPDATE users SET name = 'b'`).WillReturnResult(3)
+	if _, err = p.Exec(ctx, "UPDATE users SET name = 'b' WHERE name = 'it''s'"); err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	mock.ExpectExec(`^DELETE FROM users`).WillReturnError(boom)
+	if err = p.Delete(ctx, userStore, skyorm.Eq(name, "a")); !errors.Is(err, boom) {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	// missing rows aren't errors of spans.
+	mock.ExpectQuery(`^SELECT id, name FROM users`).WillReturnRows([]string{"id", "name"})
+	if _, err = p.FindOne(ctx, userStore, skyorm.Eq(name, "a")); err == nil {
+		t.Fatal("found missing user")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 4 {
+		t.Fatalf("started %d spans, want 4", len(tracer.spans))
+	}
+	count := tracer.spans[0]
+	want := map[string]interface{}{
+		postgres.AttrDBSystem:       "postgresql",
+		postgres.AttrDBOperation:    "SELECT",
+		postgres.AttrDBStatement:    "SELECT COUNT(id) AS cnt FROM users WHERE name = $1",
+		postgres.AttrDBRowsAffected: int64(1),
+		"skyorm.meta.route":         "/users",
+	}
+	if count.name != "SELECT" || !reflect.DeepEqual(count.attrs, want) {
+		t.Errorf("span %s with %v, want SELECT with %v", count.name, count.attrs, want)
+	}
+	// string literals are removed from statements.
+	update := tracer.spans[1]
+	if s := update.attrs[postgres.AttrDBStatement]; s != "UPDATE users SET name = '?' WHERE name = '?'" {
+		t.Errorf("statement %v", s)
+	}
+	if rows := update.attrs[postgres.AttrDBRowsAffected]; rows != int64(3) {
+		t.Errorf("rows affected %v, want 3", rows)
+	}
+	if del := tracer.spans[2]; len(del.errs) != 1 || !errors.Is(del.errs[0], boom) || del.name != "DELETE" {
+		t.Errorf("span %s recorded %v, want boom", del.name, del.errs)
+	}
+	if find := tracer.spans[3]; len(find.errs) != 0 {
+		t.Errorf("span of missing row recorded %v", find.errs)
+	}
+	for _, s := range tracer.spans {
+		if !s.ended {
+			t.Errorf("span %s isn't ended", s.name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// DefaultMaxTreeDepth limits hierarchy queries when max depth is not positive,
+// so cycles in parent references can't make them endless.
+const DefaultMaxTreeDepth = 100
+
+// TreeNode is a model of hierarchy with its depth relative to the starting model.
+type TreeNode struct {
+	Model skyorm.Model
+	Depth int
+}
+
+// FindDescendants returns descendants of the model with pk in self-referencing store,
+// where parentProp references parent pk, ordered by depth starting with children at 1.
+func (p *provider) FindDescendants(ctx context.Context, store skyorm.Store, parentProp skyorm.Prop, pk interface{}, maxDepth int) ([]TreeNode, error) {
+	return p.findTree(ctx, OpFindDescendants, store, maxDepth,
+		fmt.Sprintf("%s = $1", quoteColumn(parentProp.Name())),
+		fmt.Sprintf("t.%s = tree.%s", quoteColumn(parentProp.Name()), quoteColumn(store.Pk().Name())),
+		pk,
+	)
+}
+
+// FindAncestors returns ancestors of the model with pk in self-referencing store,
+// where parentProp references parent pk, ordered by depth starting with parent at 1.
+func (p *provider) FindAncestors(ctx context.Context, store skyorm.Store, parentProp skyorm.Prop, pk interface{}, maxDepth int) ([]TreeNode, error) {
+	return p.findTree(ctx, OpFindAncestors, store, maxDepth,
+		fmt.Sprintf("%[1]s = (SELECT %[2]s FROM %[3]s WHERE %[1]s = $1)", quoteColumn(store.Pk().Name()), quoteColumn(parentProp.Name()), p.table(ctx, store.Name())),
+		fmt.Sprintf("t.%s = tree.%s", quoteColumn(store.Pk().Name()), quoteColumn(parentProp.Name())),
+		pk,
+	)
+}
+
+func (p *provider) findTree(ctx context.Context, op string, store skyorm.Store, maxDepth int, start, join string, pk interface{}) ([]TreeNode, error) {
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxTreeDepth
+	}
+	// computed props are selected from stored columns of the tree.
+	props := storedProps(store, store.Props())
+	qualified := make([]string, len(props))
+	for i, prop := range props {
+		qualified[i] = "t." + quoteColumn(prop.Name())
+	}
+	columns := buildQueryProperties(props, false)
+	query := fmt.Sprintf(`WITH RECURSIVE tree AS (
+	SELECT %[1]s, 1 AS depth FROM %[2]s WHERE %[3]s
+	UNION ALL
+	SELECT %[4]s, tree.depth + 1 FROM %[2]s t INNER JOIN tree ON %[5]s WHERE tree.depth < $2
+) SELECT %[6]s, depth FROM tree ORDER BY depth`,
+		columns, p.table(ctx, store.Name()), start, strings.Join(qualified, ", "), join, selectColumns(store, store.Props()))
+	ctx = withOp(ctx, op)
+	res, err := p.query(ctx, query, pk, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]TreeNode, 0)
+	for res.Next() {
+		n := TreeNode{Model: store.Model()}
+		if err = res.Scan(append(p.scanPointers(n.Model.OrmPointers()), &n.Depth)...); err != nil {
+			return nil, err
+		}
+		l = append(l, n)
+	}
+	return l, res.Err()
+}
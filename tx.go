@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/skyorm/skyorm"
+)
+
+// Tx is a provider bound to a database transaction. Helpers which manage their
+// own transactions, such as queues, schedulers and rollups, run outside of it.
+type Tx interface {
+	Provider
+	Commit() error
+	Rollback() error
+	// Savepoint establishes a savepoint inside the transaction.
+	Savepoint(ctx context.Context, name string) error
+	// RollbackTo rolls back changes made after the savepoint, keeping the transaction usable.
+	RollbackTo(ctx context.Context, name string) error
+	// ReleaseSavepoint forgets the savepoint, keeping changes made after it.
+	ReleaseSavepoint(ctx context.Context, name string) error
+}
+
+// TxOptions are options of a transaction, nil options mean the server defaults.
+type TxOptions struct {
+	// Isolation is sql.LevelReadCommitted, sql.LevelRepeatableRead or sql.LevelSerializable.
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// Deferrable makes serializable read-only transaction wait for a snapshot
+	// which can't fail with serialization failure, it's ignored otherwise.
+	Deferrable bool
+}
+
+// SnapshotTx returns options of read-only serializable transaction suitable for
+// long reporting queries, which never fail with serialization failure.
+func SnapshotTx() *TxOptions {
+	return &TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true, Deferrable: true}
+}
+
+// ErrNestedTx is returned by Begin of a transaction, savepoints are used instead.
+var ErrNestedTx = errors.New("postgres: nested transactions are not supported, use savepoints")
+
+type tx struct {
+	*provider
+	tx *sql.Tx
+}
+
+func (p *provider) Begin(ctx context.Context, opts *TxOptions) (Tx, error) {
+	t, err := p.beginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// beginTx starts a transaction on the database, also for transactional helpers
+// of provider, so their queries are logged and instrumented like any other.
+func (p *provider) beginTx(ctx context.Context, opts *TxOptions) (*tx, error) {
+	var sqlOpts *sql.TxOptions
+	if opts != nil {
+		sqlOpts = &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly}
+	}
+	t, err := p.db.BeginTx(ctx, sqlOpts)
+	if err != nil {
+		return nil, err
+	}
+	if opts != nil && opts.Deferrable {
+		if _, err = t.ExecContext(ctx, "SET TRANSACTION DEFERRABLE"); err != nil {
+			_ = t.Rollback()
+			return nil, err
+		}
+	}
+	cp := *p
+	cp.conn = t
+	// buffered writes would be committed outside of the transaction.
+	cp.async = nil
+	if p.cache != nil {
+		cp.txWrites = new([]string)
+	}
+	tt := &tx{&cp, t}
+	if err = tt.setTenantPath(ctx); err != nil {
+		_ = t.Rollback()
+		return nil, err
+	}
+	if err = setSessionVars(ctx, t); err != nil {
+		_ = t.Rollback()
+		return nil, err
+	}
+	return tt, nil
+}
+
+func (t *tx) Begin(context.Context, *TxOptions) (Tx, error) {
+	return nil, ErrNestedTx
+}
+
+// RunInTx runs fn in a transaction and commits it when fn succeeds. The whole
+// transaction is retried with exponential backoff when it fails with
+// serialization failure or deadlock, so fn must be safe to run again.
+// tx passed to fn implements Tx.
+func (p *provider) RunInTx(ctx context.Context, opts *TxOptions, fn func(tx skyorm.Provider) error) error {
+	backoff := p.txBackoff
+	for attempt := 1; ; attempt++ {
+		err := p.runInTx(ctx, opts, fn)
+		if err == nil || !isRetryableTxError(err) || attempt >= p.txAttempts {
+			return err
+		}
+		p.logf(LevelWarn, "TX RETRY %d: %v", attempt, err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (t *tx) RunInTx(context.Context, *TxOptions, func(tx skyorm.Provider) error) error {
+	return ErrNestedTx
+}
+
+func (p *provider) runInTx(ctx context.Context, opts *TxOptions, fn func(tx skyorm.Provider) error) error {
+	t, err := p.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err = fn(t); err != nil {
+		_ = t.Rollback()
+		return err
+	}
+	return t.Commit()
+}
+
+// isRetryableTxError reports whether err is serialization failure or deadlock.
+func isRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+func (t *tx) Commit() error {
+	err := t.tx.Commit()
+	if t.txWrites != nil {
+		for _, store := range *t.txWrites {
+			t.cache.invalidate(store)
+		}
+	}
+	return err
+}
+
+func (t *tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Close rolls back the transaction unless it was committed, the database stays open.
+func (t *tx) Close() error {
+	if err := t.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
+}
+
+func (t *tx) Savepoint(ctx context.Context, name string) error {
+	_, err := t.exec(ctx, "SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+func (t *tx) RollbackTo(ctx context.Context, name string) error {
+	_, err := t.exec(ctx, "ROLLBACK TO SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+func (t *tx) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := t.exec(ctx, "RELEASE SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// FindSpec is a store and condition of models found by FindUnion.
+type FindSpec struct {
+	Store     skyorm.Store
+	Condition skyorm.Cond
+}
+
+// FindUnion returns models matching any of the specs, combined with UNION ALL
+// when all is set or UNION dropping duplicates otherwise, in the order and
+// limited as a whole, e.g. for merged feeds. Stores of the specs have to have
+// props of the same names in the same order, models are of store of the first
+// spec. Results of unions are not cached, as they span stores.
+func (p *provider) FindUnion(ctx context.Context, all bool, specs []FindSpec, order []Order, limit, offset int) ([]skyorm.Model, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no finds to union")
+	}
+	store := specs[0].Store
+	props := buildQueryProperties(selectedProps(ctx, store), false)
+	op := " UNION "
+	if all {
+		op = " UNION ALL "
+	}
+	var (
+		n     = newN()
+		parts = make([]string, len(specs))
+		args  = make([]interface{}, 0)
+	)
+	for i, spec := range specs {
+		if buildQueryProperties(selectedProps(ctx, spec.Store), false) != props {
+			return nil, fmt.Errorf("props of %s don't match props of %s", spec.Store.Name(), store.Name())
+		}
+		columns := selectColumns(spec.Store, selectedProps(ctx, spec.Store))
+		query, v := buildWhere(p.bindCond(ctx, spec.Condition), "SELECT %s FROM %s", n, columns, p.tableAs(ctx, spec.Store.Name()))
+		parts[i] = "(" + query + ")"
+		args = append(args, v...)
+	}
+	query := strings.Join(parts, op) + buildOrder(order)
+	query += p.limit(limit, offset)
+	query, args = withCTEs(ctx, query, args)
+	l, err := p.findQuery(withOp(ctx, OpFind), store, query, args...)
+	if err != nil && !errors.Is(err, ErrRowsTruncated) {
+		return nil, err
+	}
+	return identifyAll(ctx, l), err
+}
@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/skyorm"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+var archivedUserStore = skyorm.NewStore("archived_users", 0, func() skyorm.Model {
+	return &user{}
+},
+	skyorm.NewProp("id", "int64", true),
+	skyorm.NewProp("name", "string", false),
+)
+
+func TestFindUnion(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	specs := []postgres.FindSpec{
+		{Store: userStore, Condition: skyorm.Eq(userStore.Props()[1], "a")},
+		{Store: archivedUserStore, Condition: skyorm.And(skyorm.Eq(archivedUserStore.Props()[1], "a"), skyorm.Gt(archivedUserStore.Pk(), 5))},
+	}
+	// placeholders of later parts are numbered after the ones of earlier parts.
+	mock.ExpectQuery(`^\(SELECT id, name FROM users WHERE name = \$1\) UNION ALL `+
+		`\(SELECT id, name FROM archived_users WHERE \(name = \$2 AND id > \$3\)\) ORDER BY id DESC LIMIT 10 OFFSET 5$`).
+		WithArgs("a", "a", 5).
+		WillReturnRows([]string{"id", "name"}, []interface{}{int64(7), "a"}, []interface{}{int64(6), "a"})
+	l, err := p.FindUnion(ctx, true, specs, []postgres.Order{postgres.Desc(userStore.Pk())}, 10, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(l) != 2 || l[0].(*user).ID != 7 {
+		t.Fatalf("FindUnion() = %v, want users 7 and 6", l)
+	}
+	mock.ExpectQuery(`^\(SELECT id, name FROM users WHERE name = \$1\) UNION `+
+		`\(SELECT id, name FROM archived_users WHERE \(name = \$2 AND id > \$3\)\)$`).
+		WithArgs("a", "a", 5).WillReturnRows([]string{"id", "name"})
+	if _, err = p.FindUnion(ctx, false, specs, nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.FindUnion(ctx, true, []postgres.FindSpec{{Store: userStore}, {Store: orderStore}}, nil, 0, 0); err == nil {
+		t.Fatal("union of stores with different props")
+	}
+	if _, err = p.FindUnion(ctx, true, nil, nil, 0, 0); err == nil {
+		t.Fatal("union of no finds")
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// PkValueSet is a set of values to update in the model with pk.
+type PkValueSet struct {
+	Pk     interface{}
+	Values []skyorm.Val
+}
+
+// UpdateMany applies different values to many models of the store with a single
+// UPDATE ... SET prop = CASE pk WHEN ... END WHERE pk IN (...) statement. Update
+// hooks run for every set with condition matching its pk, sets without values
+// are skipped. Sets which values don't fit into bind parameters of a statement
+// are updated by several statements, which are atomic only in a transaction.
+func (p *provider) UpdateMany(ctx context.Context, store skyorm.Store, updates []PkValueSet) error {
+	_, err := p.updateMany(ctx, store, updates)
+	return err
+}
+
+// updateMany is UpdateMany returning number of updated rows.
+func (p *provider) updateMany(ctx context.Context, store skyorm.Store, updates []PkValueSet) (int64, error) {
+	if err := checkWritable(store); err != nil {
+		return 0, err
+	}
+	h := storeHooks(store)
+	l := make([]PkValueSet, 0, len(updates))
+	for _, u := range updates {
+		if h.BeforeUpdate != nil {
+			var err error
+			if u.Values, err = h.BeforeUpdate(ctx, skyorm.Eq(store.Pk(), u.Pk), u.Values); err != nil {
+				return 0, err
+			}
+		}
+		if err := checkEnumVals(store, u.Values); err != nil {
+			return 0, err
+		}
+		if err := checkStoredVals(store, u.Values); err != nil {
+			return 0, err
+		}
+		if len(u.Values) > 0 {
+			l = append(l, u)
+		}
+	}
+	if len(l) == 0 {
+		return 0, nil
+	}
+	ctx = withOp(ctx, OpUpdateMany)
+	var total int64
+	for start := 0; start < len(l); {
+		// every set binds its pk to IN list and to case of every value, which
+		// may bind the value too.
+		end, params := start, 0
+		for ; end < len(l); end++ {
+			n := 1 + 2*len(l[end].Values)
+			if end > start && params+n > maxBindParams {
+				break
+			}
+			params += n
+		}
+		n, err := p.updateManyStatement(ctx, store, l[start:end])
+		if err != nil {
+			return total, err
+		}
+		total += n
+		start = end
+	}
+	if dryRun(ctx) {
+		return 0, nil
+	}
+	forgetStore(ctx, store)
+	p.invalidateCache(store)
+	if h.AfterUpdate != nil {
+		for _, u := range l {
+			if err := h.AfterUpdate(ctx, skyorm.Eq(store.Pk(), u.Pk), u.Values); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// updateManyStatement updates sets with a single statement and returns number
+// of updated rows.
+func (p *provider) updateManyStatement(ctx context.Context, store skyorm.Store, updates []PkValueSet) (int64, error) {
+	var (
+		pkName = quoteColumn(store.Pk().Name())
+		props  = make([]string, 0)
+		cases  = make(map[string][]string)
+		args   = make([]interface{}, 0)
+		n      = 1
+	)
+	for _, u := range updates {
+		for _, v := range u.Values {
+			name := quoteColumn(v.Prop().Name())
+			if _, ok := cases[name]; !ok {
+				props = append(props, name)
+			}
+			when := "WHEN $" + strconv.Itoa(n)
+			args = append(args, bindColumn(store.Pk(), u.Pk))
+			n++
+			expr, bound := buildValExpr(v, n)
+			if bound {
+				args = append(args, bindColumn(v.Prop(), v.Val()))
+				n++
+			}
+			cases[name] = append(cases[name], when+" THEN "+expr)
+		}
+	}
+	sets := make([]string, len(props))
+	for i, name := range props {
+		sets[i] = fmt.Sprintf("%s = CASE %s %s ELSE %s END", name, pkName, strings.Join(cases[name], " "), name)
+	}
+	pks := make([]string, len(updates))
+	for i, u := range updates {
+		pks[i] = "$" + strconv.Itoa(n)
+		args = append(args, bindColumn(store.Pk(), u.Pk))
+		n++
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s IN (%s)",
+		p.table(ctx, store.Name()),
+		strings.Join(sets, ", "),
+		pkName,
+		strings.Join(pks, ", "),
+	)
+	res, err := p.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun(ctx) {
+		return 0, nil
+	}
+	return res.RowsAffected()
+}
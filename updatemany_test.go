@@ -0,0 +1,54 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+func TestUpdateManySkipsEmptySets(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := userStore.Props()[1]
+	if err = p.UpdateMany(context.Background(), userStore, []postgres.PkValueSet{{Pk: int64(1)}}); err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectExec(`^UPDATE users SET name = CASE id WHEN \$1 THEN \$2 ELSE name END WHERE id IN \(\$3\)$`).
+		WithArgs(int64(2), "b", int64(2)).WillReturnResult(1)
+	if err = p.UpdateMany(context.Background(), userStore, []postgres.PkValueSet{
+		{Pk: int64(1)},
+		{Pk: int64(2), Values: []skyorm.Val{skyorm.NewVal(name, "b")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateManyInChunks(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := userStore.Props()[1]
+	// 3 params per set, the last one doesn't fit into the first statement.
+	updates := make([]postgres.PkValueSet, 65535/3+1)
+	for i := range updates {
+		updates[i] = postgres.PkValueSet{Pk: int64(i + 1), Values: []skyorm.Val{skyorm.NewVal(name, "a")}}
+	}
+	mock.ExpectExec(`^UPDATE users SET name = CASE id WHEN \$1 THEN \$2 .* WHERE id IN \(.*\$65535\)$`).WillReturnResult(21845)
+	mock.ExpectExec(`^UPDATE users SET name = CASE id WHEN \$1 THEN \$2 ELSE name END WHERE id IN \(\$3\)$`).
+		WithArgs(int64(len(updates)), "a", int64(len(updates))).WillReturnResult(1)
+	if err = p.UpdateMany(context.Background(), userStore, updates); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Upsert inserts models or updates the existing ones with the same pk, and
+// reports whether each model was inserted rather than updated, e.g. to send
+// welcome email only to new users. AfterInsert hooks are notified only of
+// inserted models.
+func (p *provider) Upsert(ctx context.Context, models ...skyorm.Model) ([]bool, error) {
+	return p.UpsertOn(ctx, Conflict{}, models...)
+}
+
+// UpsertOn is Upsert updating models conflicting on the target, e.g. unique
+// props, rather than on pk. Zero target is the pk. Props of the target and the
+// pk are never updated.
+func (p *provider) UpsertOn(ctx context.Context, target Conflict, models ...skyorm.Model) ([]bool, error) {
+	inserted := make([]bool, len(models))
+	for i, m := range models {
+		if err := checkWritable(m.OrmStore()); err != nil {
+			return inserted, err
+		}
+		if err := beforeInsert(ctx, m); err != nil {
+			return inserted, err
+		}
+		if err := checkEnumModel(m); err != nil {
+			return inserted, err
+		}
+		clause := " ON CONFLICT" + target.target()
+		if clause == " ON CONFLICT" {
+			clause += " (" + quoteColumn(m.OrmPkProp().Name()) + ")"
+		}
+		// xmax of inserted rows is zero, it's the locking transaction of updated ones.
+		if err := p.insert(ctx, m, clause+" DO UPDATE SET "+p.upsertSets(ctx, m, target), ", (xmax = 0)", &inserted[i]); err != nil {
+			return inserted, err
+		}
+		if dryRun(ctx) {
+			continue
+		}
+		p.invalidateCache(m.OrmStore())
+		// known model of an updated row is stale.
+		forget(ctx, m)
+		identify(ctx, m)
+		if !inserted[i] {
+			continue
+		}
+		if err := afterInsert(ctx, m); err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
+
+// upsertSets returns assignments of DO UPDATE of props of the model from
+// EXCLUDED, except for pk and props of the target.
+func (p *provider) upsertSets(ctx context.Context, m skyorm.Model, target Conflict) string {
+	skipped := map[string]bool{m.OrmPkProp().Name(): true}
+	for _, prop := range target.Props {
+		skipped[prop.Name()] = true
+	}
+	sets := make([]string, 0, len(m.OrmProps()))
+	for _, prop := range storedProps(m.OrmStore(), m.OrmProps()) {
+		if !skipped[prop.Name()] {
+			sets = append(sets, quoteColumn(prop.Name())+" = EXCLUDED."+quoteColumn(prop.Name()))
+		}
+	}
+	if len(sets) == 0 {
+		// rows are returned only when they are updated, so pk is set to itself.
+		pk := quoteColumn(m.OrmPkProp().Name())
+		sets = append(sets, pk+" = "+p.table(ctx, m.OrmStore().Name())+"."+pk)
+	}
+	return strings.Join(sets, ", ")
+}
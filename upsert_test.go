@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+	"github.com/skyorm/skyorm"
+)
+
+func TestUpsert(t *testing.T) {
+	var notified []int64
+	postgres.RegisterHooks(noteStore, postgres.StoreHooks{
+		AfterInsert: func(ctx context.Context, m skyorm.Model) error {
+			notified = append(notified, m.(*note).ID)
+			return nil
+		},
+	})
+	defer postgres.RegisterHooks(noteStore, postgres.StoreHooks{})
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, c := range []struct {
+		target postgres.Conflict
+		clause string
+	}{
+		{postgres.Conflict{}, `ON CONFLICT \(id\) DO UPDATE SET text = EXCLUDED.text, updated = EXCLUDED.updated`},
+		{postgres.Conflict{Constraint: "notes_text_key"}, `ON CONFLICT ON CONSTRAINT notes_text_key DO UPDATE SET text = EXCLUDED.text, updated = EXCLUDED.updated`},
+		{postgres.Conflict{Props: noteStore.Props()[1:2]}, `ON CONFLICT \(text\) DO UPDATE SET updated = EXCLUDED.updated`},
+		// pk is set to itself when all props are in the target.
+		{postgres.Conflict{Props: noteStore.Props()[1:]}, `ON CONFLICT \(text, updated\) DO UPDATE SET id = notes.id`},
+	} {
+		notified = nil
+		query := `^INSERT INTO notes \(id, text, updated\) VALUES \(\$1, \$2, \$3\) ` + c.clause + ` RETURNING id, \(xmax = 0\)$`
+		mock.ExpectQuery(query).WithArgs(int64(1), "a", int64(0)).
+			WillReturnRows([]string{"id", "?column?"}, []interface{}{int64(1), true})
+		mock.ExpectQuery(query).WithArgs(int64(2), "b", int64(0)).
+			WillReturnRows([]string{"id", "?column?"}, []interface{}{int64(2), false})
+		inserted, err := p.UpsertOn(ctx, c.target, &note{ID: 1, Text: "a"}, &note{ID: 2, Text: "b"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(inserted, []bool{true, false}) {
+			t.Errorf("%s: inserted %v", c.clause, inserted)
+		}
+		if !reflect.DeepEqual(notified, []int64{1}) {
+			t.Errorf("%s: AfterInsert of %v", c.clause, notified)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// VectorMetric is a pgvector distance operator.
+type VectorMetric string
+
+const (
+	// VectorL2 is the euclidean distance.
+	VectorL2 VectorMetric = "<->"
+	// VectorInnerProduct is the negative inner product.
+	VectorInnerProduct VectorMetric = "<#>"
+	// VectorCosine is the cosine distance.
+	VectorCosine VectorMetric = "<=>"
+)
+
+// Vector wraps a pointer to []float32 property stored in pgvector vector column.
+// Models return it from OrmPointers and OrmVals.
+func Vector(v *[]float32) *VectorValue {
+	return &VectorValue{v}
+}
+
+// VectorValue binds and scans []float32 in pgvector text format.
+type VectorValue struct {
+	v *[]float32
+}
+
+func (v *VectorValue) Value() (driver.Value, error) {
+	if v.v == nil || *v.v == nil {
+		return nil, nil
+	}
+	return formatVector(*v.v), nil
+}
+
+func (v *VectorValue) Scan(src interface{}) error {
+	var s string
+	switch src := src.(type) {
+	case nil:
+		*v.v = nil
+		return nil
+	case []byte:
+		s = string(src)
+	case string:
+		s = src
+	default:
+		return fmt.Errorf("can't scan %T into vector", src)
+	}
+	s = strings.Trim(s, "[]")
+	if s == "" {
+		*v.v = []float32{}
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	l := make([]float32, len(parts))
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 32)
+		if err != nil {
+			return err
+		}
+		l[i] = float32(f)
+	}
+	*v.v = l
+	return nil
+}
+
+func formatVector(l []float32) string {
+	parts := make([]string, len(l))
+	for i, f := range l {
+		parts[i] = strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// NearestNeighbors returns k models of the store which vector property is the
+// closest to embedding by metric, ordered by distance. k must be positive.
+func (p *provider) NearestNeighbors(ctx context.Context, store skyorm.Store, prop skyorm.Prop, embedding []float32, k int, metric VectorMetric) ([]skyorm.Model, error) {
+	switch metric {
+	case VectorL2, VectorInnerProduct, VectorCosine:
+	default:
+		return nil, fmt.Errorf("unsupported vector metric %q", metric)
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("invalid number of neighbors %d", k)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s %s $1::vector LIMIT %d",
+		selectColumns(store, selectedProps(ctx, store)),
+		p.table(ctx, store.Name()),
+		quoteColumn(prop.Name()),
+		metric,
+		k,
+	)
+	ctx = withOp(ctx, OpNearestNeighbors)
+	return p.findQuery(ctx, store, query, formatVector(embedding))
+}
@@ -0,0 +1,24 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestNearestNeighborsRejectsNonPositiveK(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []int{0, -1} {
+		if _, err = p.NearestNeighbors(context.Background(), userStore, userStore.Props()[1], []float32{1}, k, postgres.VectorL2); err == nil {
+			t.Fatalf("found %d neighbors", k)
+		}
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/skyorm/skyorm"
+)
+
+// View is a view of a read-only store, e.g. a denormalized read model, defined
+// alongside the store.
+type View struct {
+	// Query is the SELECT defining the view, its columns are props of the store.
+	Query string
+	// Materialized makes the view a materialized view, refreshed by RefreshMaterializedView.
+	Materialized bool
+}
+
+// createSQL returns statement creating the view of the table, or replacing
+// the plain one.
+func (v View) createSQL(table string) string {
+	if v.Materialized {
+		return fmt.Sprintf("CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS %s", table, v.Query)
+	}
+	return fmt.Sprintf("CREATE OR REPLACE VIEW %s AS %s", table, v.Query)
+}
+
+var (
+	viewsMu sync.RWMutex
+	views   = make(map[string]View)
+)
+
+// RegisterView binds the store to the view, so it's created by EnsureViews and
+// GenerateDDL instead of a table, and writes of its models fail with ErrReadOnlyStore.
+func RegisterView(store skyorm.Store, v View) {
+	viewsMu.Lock()
+	defer viewsMu.Unlock()
+	views[store.Name()] = v
+}
+
+func storeView(store skyorm.Store) (View, bool) {
+	viewsMu.RLock()
+	defer viewsMu.RUnlock()
+	v, ok := views[store.Name()]
+	return v, ok
+}
+
+// EnsureViews creates views of the stores, replacing plain views, so changed
+// queries take effect. Materialized views are created only when they don't
+// exist, as they'd lose their data, and have to be dropped to be changed.
+func (p *provider) EnsureViews(ctx context.Context, stores ...skyorm.Store) error {
+	for _, s := range stores {
+		v, ok := storeView(s)
+		if !ok {
+			continue
+		}
+		if _, err := p.exec(ctx, v.createSQL(p.table(ctx, s.Name()))); err != nil {
+			return err
+		}
+		p.invalidateCache(s)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/skyorm/skyorm"
+)
+
+// Window is a window function computed for every model found by FindWindowed.
+type Window struct {
+	// Name is the column name of the value.
+	Name string
+	// Func is the window function call, e.g. "row_number()" or "sum(amount)".
+	Func        string
+	PartitionBy []skyorm.Prop
+	OrderBy     []Order
+}
+
+// expr returns the window function call over the window.
+func (w Window) expr() string {
+	over := make([]string, 0, 2)
+	if len(w.PartitionBy) > 0 {
+		columns := make([]string, len(w.PartitionBy))
+		for i, prop := range w.PartitionBy {
+			columns[i] = quoteColumn(prop.Name())
+		}
+		over = append(over, "PARTITION BY "+strings.Join(columns, ", "))
+	}
+	if len(w.OrderBy) > 0 {
+		over = append(over, strings.TrimPrefix(buildOrder(w.OrderBy), " "))
+	}
+	return w.Func + " OVER (" + strings.Join(over, " ") + ") AS " + quoteIdent(w.Name)
+}
+
+// RowNumber returns row_number() window numbering models in partitions from 1
+// in the order.
+func RowNumber(name string, partitionBy []skyorm.Prop, order ...Order) Window {
+	return Window{Name: name, Func: "row_number()", PartitionBy: partitionBy, OrderBy: order}
+}
+
+// WindowedModel is a model found by FindWindowed with values of its windows.
+type WindowedModel struct {
+	skyorm.Model
+	// Values are values of windows by their names.
+	Values map[string]interface{}
+}
+
+// FindWindowed returns models matching condition with values of the windows
+// computed over them. Finds of more models than WithMaxRows allows are handled
+// like by Find.
+func (p *provider) FindWindowed(ctx context.Context, store skyorm.Store, condition skyorm.Cond, windows []Window, limit, offset int) ([]WindowedModel, error) {
+	if len(windows) == 0 {
+		return nil, fmt.Errorf("no windows to find %s with", store.Name())
+	}
+	columns := make([]string, len(windows))
+	for i, w := range windows {
+		columns[i] = w.expr()
+	}
+	query, args := buildWhere(p.bindCond(ctx, condition),
+		"SELECT %s, %s FROM %s",
+		nil,
+		selectColumns(store, selectedProps(ctx, store)),
+		strings.Join(columns, ", "),
+		p.tableAs(ctx, store.Name()),
+	)
+	query += p.limit(limit, offset)
+	query, args = withCTEs(ctx, query, args)
+	res, err := p.query(forRead(withOp(ctx, OpFind)), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Close()
+	}()
+	l := make([]WindowedModel, 0)
+	for res.Next() {
+		if p.maxRows > 0 && len(l) == p.maxRows {
+			if err = p.maxRowsError(store); !p.truncateRows {
+				return nil, err
+			}
+			return l, err
+		}
+		m := store.Model()
+		values := make([]interface{}, len(windows))
+		dest := p.scanPointers(selectedPointers(ctx, m))
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err = res.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if err = afterFind(ctx, m); err != nil {
+			return nil, err
+		}
+		wm := WindowedModel{Model: identify(ctx, m), Values: make(map[string]interface{}, len(windows))}
+		for i, w := range windows {
+			wm.Values[w.Name] = values[i]
+		}
+		l = append(l, wm)
+	}
+	return l, res.Err()
+}
+
+// FindFirstPerGroup returns the first model in the order of every group of
+// models matching condition with equal values of partitionBy props, e.g. the
+// latest row per group ordered by Desc of its creation time.
+func (p *provider) FindFirstPerGroup(ctx context.Context, store skyorm.Store, condition skyorm.Cond, partitionBy []skyorm.Prop, order ...Order) ([]skyorm.Model, error) {
+	w := RowNumber("orm_row_number", partitionBy, order...)
+	props := selectedProps(ctx, store)
+	query, args := buildWhere(p.bindCond(ctx, condition),
+		"SELECT %s, %s FROM %s",
+		nil,
+		selectColumns(store, props),
+		w.expr(),
+		p.tableAs(ctx, store.Name()),
+	)
+	query = "SELECT " + buildQueryProperties(props, false) + " FROM (" + query + ") ranked WHERE " + quoteIdent(w.Name) + " = 1" + p.limit(0, 0)
+	query, args = withCTEs(ctx, query, args)
+	return p.findThrough(withOp(ctx, OpFind), store, condition, query, args)
+}
@@ -0,0 +1,45 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/skyorm/postgres"
+	"github.com/skyorm/postgres/postgrestest"
+)
+
+func TestFindWindowedTruncated(t *testing.T) {
+	p, mock, err := postgrestest.NewMock(postgres.WithMaxRows(1, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	windows := []postgres.Window{postgres.RowNumber("n", nil, postgres.Desc(userStore.Props()[1]))}
+	mock.ExpectQuery(`^SELECT id, name, row_number\(\) OVER \(ORDER BY name DESC\) AS n FROM users LIMIT 2 OFFSET 1$`).
+		WillReturnRows([]string{"id", "name", "n"}, []interface{}{2, "b", 2}, []interface{}{1, "a", 3})
+	l, err := p.FindWindowed(context.Background(), userStore, nil, windows, 0, 1)
+	if !errors.Is(err, postgres.ErrRowsTruncated) {
+		t.Fatalf("FindWindowed() error = %v, want ErrRowsTruncated", err)
+	}
+	if len(l) != 1 || l[0].Model.(*user).ID != 2 {
+		t.Fatalf("found %v", l)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindOffsetWithoutLimit(t *testing.T) {
+	p, mock, err := postgrestest.NewMock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.ExpectQuery(`^SELECT id, name, row_number\(\) OVER \(\) AS n FROM users OFFSET 2$`).
+		WillReturnRows([]string{"id", "name", "n"})
+	if _, err = p.FindWindowed(context.Background(), userStore, nil, []postgres.Window{postgres.RowNumber("n", nil)}, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}